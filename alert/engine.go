@@ -0,0 +1,347 @@
+package alert
+
+import (
+	"api-gateway/measure"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxSamples bounds the readings kept per patient and data type.
+const maxSamples = 1000
+
+// sweepEvery is how often the engine forgets patients who sent no reading
+// for maxMinutes.
+const sweepEvery = time.Hour
+
+// Clock tells the engine the time of readings that don't carry one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
+
+// ManualClock only moves when told to, so tests can step through
+// sustained and rate-of-change rules.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Reading is a validated reading in its data type's canonical unit.
+type Reading struct {
+	PatientID  string
+	DataType   string
+	DeviceType string
+	Value      string
+	// At is when the reading was taken; zero means now.
+	At time.Time
+}
+
+// Alert is a rule that matched a reading.
+type Alert struct {
+	RuleID     int64     `json:"rule_id"`
+	PatientID  string    `json:"patient_id"`
+	DataType   string    `json:"data_type"`
+	DeviceType string    `json:"device_type"`
+	Value      string    `json:"value"`
+	Condition  string    `json:"condition"`
+	Severity   string    `json:"severity"`
+	Message    string    `json:"message"`
+	At         time.Time `json:"at"`
+}
+
+// Payload is the alert as the payload of an alerts topic event.
+func (a Alert) Payload() (*structpb.Struct, error) {
+	return structpb.NewStruct(map[string]any{
+		"rule_id":     strconv.FormatInt(a.RuleID, 10),
+		"patient_id":  a.PatientID,
+		"data_type":   a.DataType,
+		"device_type": a.DeviceType,
+		"value":       a.Value,
+		"condition":   a.Condition,
+		"severity":    a.Severity,
+		"message":     a.Message,
+		"at":          a.At.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+type sample struct {
+	at time.Time
+	// values holds one value, or systolic and diastolic.
+	values []float64
+}
+
+// Engine evaluates readings against the rules in a store. It remembers
+// the last day of readings per patient and data type in process memory,
+// which sustained and rate-of-change rules look back on; with several
+// gateway replicas each sees only the readings it handled.
+type Engine struct {
+	rules Store
+	clock Clock
+
+	mu      sync.Mutex
+	samples map[string][]sample
+	// fired holds when a rule last fired for a patient.
+	fired map[string]time.Time
+	swept time.Time
+}
+
+// NewEngine uses SystemClock when clock is nil.
+func NewEngine(rules Store, clock Clock) *Engine {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &Engine{
+		rules:   rules,
+		clock:   clock,
+		samples: make(map[string][]sample),
+		fired:   make(map[string]time.Time),
+	}
+}
+
+// Evaluate records the reading and returns the alerts it raises. A rule
+// that fired for the patient within its cooldown stays quiet.
+func (e *Engine) Evaluate(ctx context.Context, r Reading) ([]Alert, error) {
+	values, err := parseValues(r.DataType, r.Value)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := e.rules.List(ctx, Query{DataType: r.DataType, Patients: []string{"", r.PatientID}})
+	if err != nil {
+		return nil, err
+	}
+	rules = effective(rules)
+
+	at := r.At
+	if at.IsZero() {
+		at = e.clock.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.sweep(e.clock.Now())
+	samples := e.record(r.PatientID+"\x00"+r.DataType, sample{at: at, values: values})
+	latest := samples[len(samples)-1]
+	if !latest.at.Equal(at) {
+		// a late reading; rules judge the newest one
+		return nil, nil
+	}
+
+	var alerts []Alert
+	for _, rule := range rules {
+		detail, ok := rule.match(samples)
+		if !ok {
+			continue
+		}
+		key := strconv.FormatInt(rule.ID, 10) + "\x00" + r.PatientID
+		if last, ok := e.fired[key]; ok && at.Sub(last) < time.Duration(rule.CooldownMinutes)*time.Minute {
+			continue
+		}
+		e.fired[key] = at
+
+		alerts = append(alerts, Alert{
+			RuleID:     rule.ID,
+			PatientID:  r.PatientID,
+			DataType:   r.DataType,
+			DeviceType: r.DeviceType,
+			Value:      r.Value,
+			Condition:  rule.Condition,
+			Severity:   rule.Severity,
+			Message:    detail,
+			At:         at,
+		})
+	}
+	return alerts, nil
+}
+
+// record adds s to the readings under key, in time order, and drops the
+// ones too old for any rule to look at.
+func (e *Engine) record(key string, s sample) []sample {
+	samples := e.samples[key]
+	i := len(samples)
+	for i > 0 && samples[i-1].at.After(s.at) {
+		i--
+	}
+	samples = append(samples, sample{})
+	copy(samples[i+1:], samples[i:])
+	samples[i] = s
+
+	cutoff := samples[len(samples)-1].at.Add(-maxMinutes * time.Minute)
+	drop := 0
+	for drop < len(samples)-1 && (samples[drop].at.Before(cutoff) || len(samples)-drop > maxSamples) {
+		drop++
+	}
+	samples = append(samples[:0], samples[drop:]...)
+	e.samples[key] = samples
+	return samples
+}
+
+// sweep drops the readings of patients whose newest reading is too old for
+// any rule to look at, and firings past every cooldown. Callers hold mu.
+func (e *Engine) sweep(now time.Time) {
+	if now.Sub(e.swept) < sweepEvery {
+		return
+	}
+	e.swept = now
+
+	cutoff := now.Add(-maxMinutes * time.Minute)
+	for key, samples := range e.samples {
+		if samples[len(samples)-1].at.Before(cutoff) {
+			delete(e.samples, key)
+		}
+	}
+	for key, at := range e.fired {
+		if at.Before(cutoff) {
+			delete(e.fired, key)
+		}
+	}
+}
+
+// effective drops the general rules a patient's own rules replace.
+func effective(rules []Rule) []Rule {
+	overridden := make(map[string]bool)
+	for _, r := range rules {
+		if r.PatientID != "" {
+			overridden[r.overrideKey()] = true
+		}
+	}
+	kept := rules[:0]
+	for _, r := range rules {
+		if r.PatientID == "" && overridden[r.overrideKey()] {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// match reports whether the newest of samples matches the rule, with a
+// message saying why.
+func (r Rule) match(samples []sample) (string, bool) {
+	index := 0
+	if r.Component == Diastolic {
+		index = 1
+	}
+	value := func(s sample) float64 { return s.values[index] }
+
+	t, _ := measure.Lookup(r.DataType)
+	name := strings.ReplaceAll(r.DataType, "_", " ")
+	if r.Component != "" {
+		name = r.Component + " " + name
+	}
+	latest := samples[len(samples)-1]
+	v := value(latest)
+
+	switch r.Condition {
+	case Above, Below:
+		past := func(x float64) bool { return x > r.Threshold }
+		if r.Condition == Below {
+			past = func(x float64) bool { return x < r.Threshold }
+		}
+		if !past(v) {
+			return "", false
+		}
+		if r.ForMinutes == 0 {
+			return fmt.Sprintf("%s %s %s is %s %s %s", capitalize(name), format(v), t.Unit, r.Condition, format(r.Threshold), t.Unit), true
+		}
+		since := latest.at
+		for i := len(samples) - 2; i >= 0 && past(value(samples[i])); i-- {
+			since = samples[i].at
+		}
+		if latest.at.Sub(since) < time.Duration(r.ForMinutes)*time.Minute {
+			return "", false
+		}
+		return fmt.Sprintf("%s has been %s %s %s for %d minutes, now %s %s",
+			capitalize(name), r.Condition, format(r.Threshold), t.Unit, r.ForMinutes, format(v), t.Unit), true
+
+	case Rise, Fall:
+		from := latest.at.Add(-time.Duration(r.WindowMinutes) * time.Minute)
+		extreme := v
+		for i := len(samples) - 2; i >= 0 && !samples[i].at.Before(from); i-- {
+			x := value(samples[i])
+			if (r.Condition == Rise && x < extreme) || (r.Condition == Fall && x > extreme) {
+				extreme = x
+			}
+		}
+		change := v - extreme
+		if r.Condition == Fall {
+			change = extreme - v
+		}
+		if change < r.Threshold {
+			return "", false
+		}
+		verb := "rose"
+		if r.Condition == Fall {
+			verb = "fell"
+		}
+		return fmt.Sprintf("%s %s by %s %s within %d minutes, now %s %s",
+			capitalize(name), verb, format(change), t.Unit, r.WindowMinutes, format(v), t.Unit), true
+	}
+	return "", false
+}
+
+// parseValues reads a canonical value: a number, or systolic/diastolic.
+func parseValues(dataType, value string) ([]float64, error) {
+	parts := []string{value}
+	if t, ok := measure.Lookup(dataType); ok && t.Kind == measure.KindPressure {
+		parts = strings.Split(value, "/")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("alert: %s value %q is not systolic/diastolic", dataType, value)
+		}
+	}
+	values := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil, fmt.Errorf("alert: %s value %q is not a number", dataType, value)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,234 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestEngine(t *testing.T, rules ...Rule) (*Engine, *ManualClock, []Rule) {
+	t.Helper()
+	store := NewMemoryStore()
+	created := make([]Rule, len(rules))
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			t.Fatalf("rule %d: %v", i, err)
+		}
+		var err error
+		if created[i], err = store.Create(context.Background(), rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock := NewManualClock(start)
+	return NewEngine(store, clock), clock, created
+}
+
+// evaluate sends a reading taken now and returns the ids of the rules that
+// fired.
+func evaluate(t *testing.T, e *Engine, patientID, dataType, value string) []int64 {
+	t.Helper()
+	return evaluateAt(t, e, Reading{PatientID: patientID, DataType: dataType, Value: value})
+}
+
+func evaluateAt(t *testing.T, e *Engine, r Reading) []int64 {
+	t.Helper()
+	alerts, err := e.Evaluate(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, a := range alerts {
+		if a.PatientID != r.PatientID || a.DataType != r.DataType || a.Value != r.Value {
+			t.Errorf("alert %+v does not describe the reading %+v", a, r)
+		}
+		ids = append(ids, a.RuleID)
+	}
+	return ids
+}
+
+func fired(ids []int64, id int64) bool {
+	for _, got := range ids {
+		if got == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAboveBelow(t *testing.T) {
+	e, clock, rules := newTestEngine(t,
+		Rule{DataType: "heart_rate", Condition: Above, Threshold: 120, CooldownMinutes: 1},
+		Rule{DataType: "heart_rate", Condition: Below, Threshold: 40, CooldownMinutes: 1},
+		Rule{DataType: "blood_pressure", Condition: Above, Component: Diastolic, Threshold: 90, CooldownMinutes: 1},
+	)
+	above, below, diastolic := rules[0].ID, rules[1].ID, rules[2].ID
+
+	tests := []struct {
+		dataType, value string
+		want            []int64
+	}{
+		{"heart_rate", "72", nil},
+		{"heart_rate", "120", nil},
+		{"heart_rate", "121", []int64{above}},
+		{"heart_rate", "39.5", []int64{below}},
+		{"blood_pressure", "150/85", nil},
+		{"blood_pressure", "118/95", []int64{diastolic}},
+	}
+	for _, tt := range tests {
+		// past every cooldown, so each reading is judged on its own
+		clock.Advance(2 * time.Minute)
+		got := evaluate(t, e, "p1", tt.dataType, tt.value)
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s %s fired %v, want %v", tt.dataType, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRiseFallWithinWindow(t *testing.T) {
+	e, clock, rules := newTestEngine(t,
+		Rule{DataType: "heart_rate", Condition: Rise, Threshold: 30, WindowMinutes: 10},
+		Rule{DataType: "heart_rate", Condition: Fall, Threshold: 30, WindowMinutes: 10},
+	)
+	rise, fall := rules[0].ID, rules[1].ID
+
+	evaluate(t, e, "p1", "heart_rate", "70")
+	clock.Advance(5 * time.Minute)
+	if got := evaluate(t, e, "p1", "heart_rate", "95"); len(got) != 0 {
+		t.Errorf("rise of 25 fired %v, want nothing", got)
+	}
+	clock.Advance(4 * time.Minute)
+	if got := evaluate(t, e, "p1", "heart_rate", "100"); !fired(got, rise) {
+		t.Errorf("rise of 30 within 9 minutes fired %v, want rule %d", got, rise)
+	}
+
+	// the low reading has left the window by now
+	e2, clock2, rules2 := newTestEngine(t, Rule{DataType: "heart_rate", Condition: Rise, Threshold: 30, WindowMinutes: 10})
+	evaluate(t, e2, "p1", "heart_rate", "70")
+	clock2.Advance(11 * time.Minute)
+	if got := evaluate(t, e2, "p1", "heart_rate", "100"); fired(got, rules2[0].ID) {
+		t.Errorf("rise over 11 minutes fired %v, want nothing for a 10 minute window", got)
+	}
+
+	clock.Advance(time.Minute)
+	evaluate(t, e, "p1", "heart_rate", "110")
+	clock.Advance(3 * time.Minute)
+	if got := evaluate(t, e, "p1", "heart_rate", "75"); !fired(got, fall) {
+		t.Errorf("fall of 35 within 3 minutes fired %v, want rule %d", got, fall)
+	}
+}
+
+func TestForMinutes(t *testing.T) {
+	e, clock, rules := newTestEngine(t, Rule{DataType: "heart_rate", Condition: Above, Threshold: 100, ForMinutes: 10})
+	sustained := rules[0].ID
+
+	steps := []struct {
+		after time.Duration
+		value string
+		want  bool
+	}{
+		{0, "110", false},
+		{5 * time.Minute, "115", false},
+		// dipping below starts the count again
+		{time.Minute, "90", false},
+		{time.Minute, "112", false},
+		{9 * time.Minute, "108", false},
+		{time.Minute, "120", true},
+	}
+	for i, step := range steps {
+		clock.Advance(step.after)
+		if got := fired(evaluate(t, e, "p1", "heart_rate", step.value), sustained); got != step.want {
+			t.Errorf("step %d (%s): fired %v, want %v", i, step.value, got, step.want)
+		}
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	e, clock, rules := newTestEngine(t, Rule{DataType: "heart_rate", Condition: Above, Threshold: 120, CooldownMinutes: 15})
+	id := rules[0].ID
+
+	if got := evaluate(t, e, "p1", "heart_rate", "130"); !fired(got, id) {
+		t.Fatalf("first reading fired %v, want rule %d", got, id)
+	}
+	clock.Advance(14 * time.Minute)
+	if got := evaluate(t, e, "p1", "heart_rate", "135"); len(got) != 0 {
+		t.Errorf("within the cooldown fired %v, want nothing", got)
+	}
+	if got := evaluate(t, e, "p2", "heart_rate", "135"); !fired(got, id) {
+		t.Errorf("another patient fired %v, want rule %d; cooldowns are per patient", got, id)
+	}
+	clock.Advance(time.Minute)
+	if got := evaluate(t, e, "p1", "heart_rate", "135"); !fired(got, id) {
+		t.Errorf("after the cooldown fired %v, want rule %d", got, id)
+	}
+}
+
+func TestPatientOverride(t *testing.T) {
+	e, _, rules := newTestEngine(t,
+		Rule{DataType: "heart_rate", Condition: Above, Threshold: 120},
+		// an athlete's own limit replaces the general one
+		Rule{DataType: "heart_rate", PatientID: "athlete", Condition: Above, Threshold: 160},
+		Rule{DataType: "heart_rate", Condition: Below, Threshold: 40},
+	)
+	general, own, below := rules[0].ID, rules[1].ID, rules[2].ID
+
+	if got := evaluate(t, e, "athlete", "heart_rate", "150"); len(got) != 0 {
+		t.Errorf("athlete at 150 fired %v, want nothing", got)
+	}
+	if got := evaluate(t, e, "athlete", "heart_rate", "165"); len(got) != 1 || got[0] != own {
+		t.Errorf("athlete at 165 fired %v, want only their rule %d", got, own)
+	}
+	if got := evaluate(t, e, "athlete", "heart_rate", "35"); !fired(got, below) {
+		t.Errorf("athlete at 35 fired %v, want the general rule %d they don't override", got, below)
+	}
+	if got := evaluate(t, e, "p1", "heart_rate", "150"); len(got) != 1 || got[0] != general {
+		t.Errorf("other patient at 150 fired %v, want the general rule %d", got, general)
+	}
+}
+
+func TestLateReading(t *testing.T) {
+	e, clock, rules := newTestEngine(t,
+		Rule{DataType: "heart_rate", Condition: Above, Threshold: 120},
+		Rule{DataType: "heart_rate", Condition: Rise, Threshold: 30, WindowMinutes: 30},
+	)
+	above, rise := rules[0].ID, rules[1].ID
+
+	clock.Advance(20 * time.Minute)
+	evaluate(t, e, "p1", "heart_rate", "90")
+
+	// sent late: rules judge the newest reading, so it raises nothing
+	late := Reading{PatientID: "p1", DataType: "heart_rate", Value: "130", At: start.Add(10 * time.Minute)}
+	if got := evaluateAt(t, e, late); len(got) != 0 {
+		t.Errorf("late reading fired %v, want nothing", got)
+	}
+	late = Reading{PatientID: "p1", DataType: "heart_rate", Value: "60", At: start.Add(5 * time.Minute)}
+	evaluateAt(t, e, late)
+
+	// but it is remembered for what comes after
+	clock.Advance(5 * time.Minute)
+	got := evaluate(t, e, "p1", "heart_rate", "95")
+	if !fired(got, rise) || fired(got, above) {
+		t.Errorf("reading after the late ones fired %v, want the rise %d from 60", got, rise)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	e, clock, _ := newTestEngine(t, Rule{DataType: "heart_rate", Condition: Above, Threshold: 120})
+
+	evaluate(t, e, "gone", "heart_rate", "130")
+	clock.Advance(maxMinutes*time.Minute - time.Hour)
+	evaluate(t, e, "active", "heart_rate", "130")
+	if len(e.samples) != 2 || len(e.fired) != 2 {
+		t.Fatalf("engine holds %d patients and %d firings, want 2 of each", len(e.samples), len(e.fired))
+	}
+
+	clock.Advance(time.Hour + time.Minute)
+	evaluate(t, e, "active", "heart_rate", "80")
+	if _, ok := e.samples["gone\x00heart_rate"]; ok || len(e.samples) != 1 {
+		t.Errorf("engine still holds %d patients, want only the active one", len(e.samples))
+	}
+	if len(e.fired) != 1 {
+		t.Errorf("engine still holds %d firings, want only the active one's", len(e.fired))
+	}
+}
@@ -0,0 +1,113 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates the alert_rules table when it is missing.
+func NewPostgresStore(db *sql.DB) (Store, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_rules (
+			id               BIGSERIAL PRIMARY KEY,
+			data_type        TEXT NOT NULL,
+			patient_id       TEXT NOT NULL DEFAULT '',
+			condition        TEXT NOT NULL,
+			component        TEXT NOT NULL DEFAULT '',
+			threshold        DOUBLE PRECISION NOT NULL,
+			window_minutes   INT NOT NULL DEFAULT 0,
+			for_minutes      INT NOT NULL DEFAULT 0,
+			cooldown_minutes INT NOT NULL,
+			severity         TEXT NOT NULL,
+			created_by       TEXT NOT NULL,
+			created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS alert_rules_lookup ON alert_rules (data_type, patient_id)`)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{db: db}, nil
+}
+
+const ruleColumns = `id, data_type, patient_id, condition, component, threshold, window_minutes,
+	for_minutes, cooldown_minutes, severity, created_by, created_at, updated_at`
+
+func scanRule(row interface{ Scan(...any) error }) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.DataType, &r.PatientID, &r.Condition, &r.Component, &r.Threshold, &r.WindowMinutes,
+		&r.ForMinutes, &r.CooldownMinutes, &r.Severity, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+func (s *postgresStore) Create(ctx context.Context, rule Rule) (Rule, error) {
+	return scanRule(s.db.QueryRowContext(ctx, `
+		INSERT INTO alert_rules (data_type, patient_id, condition, component, threshold, window_minutes,
+			for_minutes, cooldown_minutes, severity, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+ruleColumns,
+		rule.DataType, rule.PatientID, rule.Condition, rule.Component, rule.Threshold, rule.WindowMinutes,
+		rule.ForMinutes, rule.CooldownMinutes, rule.Severity, rule.CreatedBy))
+}
+
+func (s *postgresStore) Get(ctx context.Context, id int64) (Rule, bool, error) {
+	rule, err := scanRule(s.db.QueryRowContext(ctx, `SELECT `+ruleColumns+` FROM alert_rules WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return Rule{}, false, nil
+	}
+	if err != nil {
+		return Rule{}, false, err
+	}
+	return rule, true, nil
+}
+
+func (s *postgresStore) List(ctx context.Context, q Query) ([]Rule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+ruleColumns+` FROM alert_rules
+		WHERE ($1 = '' OR data_type = $1) AND ($2::TEXT[] IS NULL OR patient_id = ANY($2))
+		ORDER BY id`,
+		q.DataType, pq.Array(q.Patients))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *postgresStore) Update(ctx context.Context, rule Rule) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE alert_rules SET data_type = $2, patient_id = $3, condition = $4, component = $5, threshold = $6,
+			window_minutes = $7, for_minutes = $8, cooldown_minutes = $9, severity = $10, updated_at = NOW()
+		WHERE id = $1`,
+		rule.ID, rule.DataType, rule.PatientID, rule.Condition, rule.Component, rule.Threshold,
+		rule.WindowMinutes, rule.ForMinutes, rule.CooldownMinutes, rule.Severity)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *postgresStore) Delete(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
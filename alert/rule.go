@@ -0,0 +1,259 @@
+// Package alert checks incoming vital-sign readings against configurable
+// rules: thresholds, rate of change, and thresholds held for a while.
+// Rules apply to every patient unless a patient has their own rule for the
+// same data type, condition and component, which then replaces it.
+package alert
+
+import (
+	"api-gateway/measure"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Conditions.
+const (
+	// Above matches a value over the threshold.
+	Above = "above"
+	// Below matches a value under the threshold.
+	Below = "below"
+	// Rise matches a value that rose by at least the threshold within the
+	// window.
+	Rise = "rise"
+	// Fall matches a value that fell by at least the threshold within the
+	// window.
+	Fall = "fall"
+)
+
+// Severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Blood pressure components.
+const (
+	Systolic  = "systolic"
+	Diastolic = "diastolic"
+)
+
+// DefaultCooldownMinutes is how long a rule stays quiet for a patient after
+// it fired, when the rule doesn't say.
+const DefaultCooldownMinutes = 15
+
+// maxMinutes bounds windows and durations; readings are kept that long.
+const maxMinutes = 24 * 60
+
+type Rule struct {
+	ID       int64  `json:"id"`
+	DataType string `json:"data_type"`
+	// PatientID is empty for a rule that applies to every patient.
+	PatientID string `json:"patient_id,omitempty"`
+	Condition string `json:"condition"`
+	// Component picks the systolic or diastolic value of blood pressure.
+	Component string `json:"component,omitempty"`
+	// Threshold is in the canonical unit of the data type; for rise and
+	// fall it is the change.
+	Threshold float64 `json:"threshold"`
+	// WindowMinutes is how far back rise and fall look.
+	WindowMinutes int `json:"window_minutes,omitempty"`
+	// ForMinutes makes above and below match only once the value has stayed
+	// past the threshold that long.
+	ForMinutes int `json:"for_minutes,omitempty"`
+	// CooldownMinutes is how long the rule stays quiet for a patient after
+	// it fired.
+	CooldownMinutes int       `json:"cooldown_minutes"`
+	Severity        string    `json:"severity"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// InvalidRuleError says which field of a rule is wrong.
+type InvalidRuleError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidRuleError) Error() string {
+	return fmt.Sprintf("alert: invalid rule: %s %s", e.Field, e.Reason)
+}
+
+// Validate checks the rule and fills in the component, cooldown and
+// severity when they are left out. It fails with an *InvalidRuleError.
+func (r *Rule) Validate() error {
+	invalid := func(field, reason string) error { return &InvalidRuleError{Field: field, Reason: reason} }
+
+	t, ok := measure.Lookup(r.DataType)
+	if !ok {
+		return invalid("data_type", "is not a known data type")
+	}
+	if t.Kind == measure.KindPressure {
+		if r.Component == "" {
+			r.Component = Systolic
+		}
+		if r.Component != Systolic && r.Component != Diastolic {
+			return invalid("component", "must be systolic or diastolic")
+		}
+	} else if r.Component != "" {
+		return invalid("component", "is only for blood_pressure")
+	}
+
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		return invalid("threshold", "must be a number")
+	}
+	switch r.Condition {
+	case Above, Below:
+		if r.WindowMinutes != 0 {
+			return invalid("window_minutes", "is only for rise and fall")
+		}
+		if r.ForMinutes < 0 || r.ForMinutes > maxMinutes {
+			return invalid("for_minutes", fmt.Sprintf("must be between 0 and %d", maxMinutes))
+		}
+	case Rise, Fall:
+		if r.Threshold <= 0 {
+			return invalid("threshold", "must be above 0 for rise and fall")
+		}
+		if r.WindowMinutes < 1 || r.WindowMinutes > maxMinutes {
+			return invalid("window_minutes", fmt.Sprintf("must be between 1 and %d", maxMinutes))
+		}
+		if r.ForMinutes != 0 {
+			return invalid("for_minutes", "is only for above and below")
+		}
+	default:
+		return invalid("condition", "must be one of: above, below, rise, fall")
+	}
+
+	if r.CooldownMinutes == 0 {
+		r.CooldownMinutes = DefaultCooldownMinutes
+	}
+	if r.CooldownMinutes < 0 || r.CooldownMinutes > maxMinutes {
+		return invalid("cooldown_minutes", fmt.Sprintf("must be between 1 and %d", maxMinutes))
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return invalid("severity", "must be one of: info, warning, critical")
+	}
+	return nil
+}
+
+// overrideKey is what a patient's rule shares with the general rule it
+// replaces.
+func (r Rule) overrideKey() string {
+	return r.DataType + "\x00" + r.Condition + "\x00" + r.Component
+}
+
+// Query selects rules. Zero fields match everything.
+type Query struct {
+	DataType string
+	// Patients limits the rules to those of these patients; "" stands for
+	// the rules that apply to every patient.
+	Patients []string
+}
+
+func (q Query) match(r Rule) bool {
+	if q.DataType != "" && r.DataType != q.DataType {
+		return false
+	}
+	if q.Patients == nil {
+		return true
+	}
+	for _, p := range q.Patients {
+		if r.PatientID == p {
+			return true
+		}
+	}
+	return false
+}
+
+type Store interface {
+	// Create stores a new rule and returns it with its id and times set.
+	Create(ctx context.Context, rule Rule) (Rule, error)
+	// Get reports false when there is no rule with the id.
+	Get(ctx context.Context, id int64) (Rule, bool, error)
+	// List returns the matching rules by id.
+	List(ctx context.Context, q Query) ([]Rule, error)
+	// Update replaces a rule and reports false when there is none with its
+	// id. The creator and creation time are kept.
+	Update(ctx context.Context, rule Rule) (bool, error)
+	// Delete reports false when there was no rule with the id.
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	nextID int64
+	rules  map[int64]Rule
+}
+
+// NewMemoryStore keeps rules in process memory. It is meant for tests and
+// local runs; rules are lost on restart.
+func NewMemoryStore() Store {
+	return &memoryStore{rules: make(map[int64]Rule)}
+}
+
+func (m *memoryStore) Create(ctx context.Context, rule Rule) (Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	now := time.Now()
+	rule.ID = m.nextID
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	m.rules[rule.ID] = rule
+	return rule, nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, id int64) (Rule, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule, ok := m.rules[id]
+	return rule, ok, nil
+}
+
+func (m *memoryStore) List(ctx context.Context, q Query) ([]Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rules []Rule
+	for _, r := range m.rules {
+		if q.match(r) {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, rule Rule) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.rules[rule.ID]
+	if !ok {
+		return false, nil
+	}
+	rule.CreatedBy = old.CreatedBy
+	rule.CreatedAt = old.CreatedAt
+	rule.UpdatedAt = time.Now()
+	m.rules[rule.ID] = rule
+	return true, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.rules[id]
+	delete(m.rules, id)
+	return ok, nil
+}
@@ -0,0 +1,198 @@
+// Package apierror writes every error response of the gateway in one
+// envelope and translates gRPC errors from the backend services into HTTP.
+package apierror
+
+import (
+	"api-gateway/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RequestIDKey is the gin context key holding the request id.
+	RequestIDKey = "request_id"
+	// RequestIDHeader carries the request id in and out of the gateway.
+	RequestIDHeader = "X-Request-ID"
+)
+
+type mapping struct {
+	status int
+	code   string
+}
+
+// grpcMappings follows the HTTP mapping of google.rpc.Code.
+var grpcMappings = map[codes.Code]mapping{
+	codes.Canceled:           {499, "canceled"},
+	codes.Unknown:            {http.StatusInternalServerError, "unknown"},
+	codes.InvalidArgument:    {http.StatusBadRequest, "invalid_argument"},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, "deadline_exceeded"},
+	codes.NotFound:           {http.StatusNotFound, "not_found"},
+	codes.AlreadyExists:      {http.StatusConflict, "already_exists"},
+	codes.PermissionDenied:   {http.StatusForbidden, "permission_denied"},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, "resource_exhausted"},
+	codes.FailedPrecondition: {http.StatusBadRequest, "failed_precondition"},
+	codes.Aborted:            {http.StatusConflict, "aborted"},
+	codes.OutOfRange:         {http.StatusBadRequest, "out_of_range"},
+	codes.Unimplemented:      {http.StatusNotImplemented, "unimplemented"},
+	codes.Internal:           {http.StatusInternalServerError, "internal"},
+	codes.Unavailable:        {http.StatusServiceUnavailable, "unavailable"},
+	codes.DataLoss:           {http.StatusInternalServerError, "data_loss"},
+	codes.Unauthenticated:    {http.StatusUnauthorized, "unauthenticated"},
+}
+
+// statusCodes names the errors the gateway raises itself.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "invalid_argument",
+	http.StatusUnauthorized:        "unauthenticated",
+	http.StatusForbidden:           "permission_denied",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusTooManyRequests:     "resource_exhausted",
+	http.StatusInternalServerError: "internal",
+	http.StatusNotImplemented:      "unimplemented",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "deadline_exceeded",
+}
+
+// clientMessages are the gRPC codes whose message is written for the caller
+// and may be passed on. Other messages can carry backend internals, such as
+// database errors, and are replaced by the HTTP status text.
+var clientMessages = map[codes.Code]bool{
+	codes.InvalidArgument:    true,
+	codes.FailedPrecondition: true,
+	codes.OutOfRange:         true,
+}
+
+func init() {
+	// report fields by their JSON name rather than the Go one
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" || name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// Write answers the request with an error envelope.
+func Write(c *gin.Context, httpStatus int, message string, details ...models.FieldError) {
+	c.JSON(httpStatus, envelope(c, httpStatus, codeForStatus(httpStatus), message, details))
+}
+
+// Abort is Write for middleware: the handlers after it are not run.
+func Abort(c *gin.Context, httpStatus int, message string, details ...models.FieldError) {
+	c.AbortWithStatusJSON(httpStatus, envelope(c, httpStatus, codeForStatus(httpStatus), message, details))
+}
+
+// GRPC answers the request for an error returned by a backend service.
+func GRPC(c *gin.Context, err error) {
+	httpStatus, code, message, details := FromGRPC(err)
+	c.JSON(httpStatus, envelope(c, httpStatus, code, message, details))
+}
+
+// FromGRPC translates a gRPC error into an HTTP status, an error code, a
+// message that is safe to show and the field violations it carries.
+func FromGRPC(err error) (int, string, string, []models.FieldError) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return http.StatusInternalServerError, "internal", http.StatusText(http.StatusInternalServerError), nil
+	}
+
+	m, found := grpcMappings[st.Code()]
+	if !found {
+		m = mapping{http.StatusInternalServerError, "internal"}
+	}
+
+	message := http.StatusText(m.status)
+	if clientMessages[st.Code()] && st.Message() != "" {
+		message = st.Message()
+	}
+
+	var details []models.FieldError
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range br.GetFieldViolations() {
+				details = append(details, models.FieldError{Field: violation.GetField(), Message: violation.GetDescription()})
+			}
+		}
+	}
+	return m.status, m.code, message, details
+}
+
+// Bind answers a request whose body or query failed to bind.
+func Bind(c *gin.Context, err error) {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		details := make([]models.FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			details = append(details, models.FieldError{Field: fe.Field(), Message: describe(fe)})
+		}
+		Write(c, http.StatusBadRequest, "Invalid request parameters", details...)
+		return
+	}
+
+	var syntax *json.SyntaxError
+	var mistyped *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &mistyped):
+		Write(c, http.StatusBadRequest, "Invalid request body", models.FieldError{Field: mistyped.Field, Message: "must be a " + mistyped.Type.String()})
+	case errors.As(err, &syntax):
+		Write(c, http.StatusBadRequest, "Request body is not valid JSON")
+	default:
+		Write(c, http.StatusBadRequest, "Invalid request body")
+	}
+}
+
+// RequestID returns the id the RequestID middleware gave the request.
+func RequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+func envelope(c *gin.Context, httpStatus int, code, message string, details []models.FieldError) models.ErrorResponse {
+	if message == "" {
+		message = http.StatusText(httpStatus)
+	}
+	return models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: RequestID(c),
+		Details:   details,
+	}
+}
+
+func codeForStatus(httpStatus int) string {
+	if code, ok := statusCodes[httpStatus]; ok {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(httpStatus)), " ", "_")
+}
+
+func describe(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must have length " + fe.Param()
+	}
+	return "is invalid"
+}
@@ -0,0 +1,196 @@
+package apierror
+
+import (
+	"api-gateway/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serve runs handle on a request with id req-1 and returns the response.
+func serve(t *testing.T, body string, handle gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		c.Set(RequestIDKey, "req-1")
+		c.Next()
+	}, handle)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) models.ErrorResponse {
+	t.Helper()
+	var res models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("body %q is not an error envelope: %v", rec.Body, err)
+	}
+	return res
+}
+
+func TestFromGRPC(t *testing.T) {
+	tests := []struct {
+		code    codes.Code
+		status  int
+		name    string
+		message string
+	}{
+		{codes.Canceled, 499, "canceled", ""},
+		{codes.Unknown, http.StatusInternalServerError, "unknown", "Internal Server Error"},
+		{codes.InvalidArgument, http.StatusBadRequest, "invalid_argument", "backend says"},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout, "deadline_exceeded", "Gateway Timeout"},
+		{codes.NotFound, http.StatusNotFound, "not_found", "Not Found"},
+		{codes.AlreadyExists, http.StatusConflict, "already_exists", "Conflict"},
+		{codes.PermissionDenied, http.StatusForbidden, "permission_denied", "Forbidden"},
+		{codes.ResourceExhausted, http.StatusTooManyRequests, "resource_exhausted", "Too Many Requests"},
+		{codes.FailedPrecondition, http.StatusBadRequest, "failed_precondition", "backend says"},
+		{codes.Aborted, http.StatusConflict, "aborted", "Conflict"},
+		{codes.OutOfRange, http.StatusBadRequest, "out_of_range", "backend says"},
+		{codes.Unimplemented, http.StatusNotImplemented, "unimplemented", "Not Implemented"},
+		{codes.Internal, http.StatusInternalServerError, "internal", "Internal Server Error"},
+		{codes.Unavailable, http.StatusServiceUnavailable, "unavailable", "Service Unavailable"},
+		{codes.DataLoss, http.StatusInternalServerError, "data_loss", "Internal Server Error"},
+		{codes.Unauthenticated, http.StatusUnauthorized, "unauthenticated", "Unauthorized"},
+	}
+	if len(tests) != len(grpcMappings) {
+		t.Fatalf("the table covers %d codes, the mapping %d", len(tests), len(grpcMappings))
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			httpStatus, code, message, details := FromGRPC(status.Error(tt.code, "backend says"))
+			if httpStatus != tt.status || code != tt.name {
+				t.Errorf("mapped to %d %s, want %d %s", httpStatus, code, tt.status, tt.name)
+			}
+			// only messages meant for the caller get through
+			if message != tt.message {
+				t.Errorf("message %q, want %q", message, tt.message)
+			}
+			if details != nil {
+				t.Errorf("details %v, want none", details)
+			}
+		})
+	}
+
+	for name, err := range map[string]error{
+		"not a gRPC error": errors.New("connection reset"),
+		"unmapped code":    status.Error(codes.Code(99), "odd"),
+	} {
+		if httpStatus, code, message, _ := FromGRPC(err); httpStatus != 500 || code != "internal" || message != "Internal Server Error" {
+			t.Errorf("%s: mapped to %d %s %q, want 500 internal", name, httpStatus, code, message)
+		}
+	}
+}
+
+func TestGRPCFieldViolations(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "invalid reading").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "value", Description: "must be positive"},
+			{Field: "data_type", Description: "is unknown"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, "", func(c *gin.Context) { GRPC(c, st.Err()) })
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", rec.Code)
+	}
+	want := models.ErrorResponse{
+		Code:      "invalid_argument",
+		Message:   "invalid reading",
+		RequestID: "req-1",
+		Details:   []models.FieldError{{Field: "value", Message: "must be positive"}, {Field: "data_type", Message: "is unknown"}},
+	}
+	if got := decode(t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("envelope %+v, want %+v", got, want)
+	}
+}
+
+func TestEnvelopeShape(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle gin.HandlerFunc
+		status int
+		want   string
+	}{
+		{"defaults the message", func(c *gin.Context) { Write(c, http.StatusNotFound, "") }, 404,
+			`{"code":"not_found","message":"Not Found","request_id":"req-1"}`},
+		{"status without a name", func(c *gin.Context) { Write(c, http.StatusUnprocessableEntity, "nope") }, 422,
+			`{"code":"unprocessable_entity","message":"nope","request_id":"req-1"}`},
+		{"with details", func(c *gin.Context) {
+			Write(c, http.StatusBadRequest, "bad", models.FieldError{Field: "email", Message: "is required"})
+		}, 400, `{"code":"invalid_argument","message":"bad","request_id":"req-1","details":[{"field":"email","message":"is required"}]}`},
+		{"abort", func(c *gin.Context) { Abort(c, http.StatusTooManyRequests, "slow down") }, 429,
+			`{"code":"resource_exhausted","message":"slow down","request_id":"req-1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, "", tt.handle)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type signup struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=patient doctor"`
+	Age   int    `json:"age" binding:"min=18"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+		details []models.FieldError
+	}{
+		{"validation", `{"email":"nope","role":"admin","age":3}`, "Invalid request parameters", []models.FieldError{
+			{Field: "email", Message: "must be a valid email address"},
+			{Field: "role", Message: "must be one of: patient, doctor"},
+			{Field: "age", Message: "must be at least 18"},
+		}},
+		{"missing field", `{"age":30}`, "Invalid request parameters", []models.FieldError{{Field: "email", Message: "is required"}}},
+		{"wrong type", `{"email":"a@b.c","age":"old"}`, "Invalid request body", []models.FieldError{{Field: "age", Message: "must be a int"}}},
+		{"truncated", `{"email":`, "Invalid request body", nil},
+		{"broken JSON", `{"email" "a@b.c"}`, "Request body is not valid JSON", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.body, func(c *gin.Context) {
+				var req signup
+				if err := c.ShouldBindJSON(&req); err != nil {
+					Bind(c, err)
+				}
+			})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", rec.Code)
+			}
+			got := decode(t, rec)
+			if got.Code != "invalid_argument" || got.Message != tt.message || got.RequestID != "req-1" {
+				t.Errorf("envelope %+v, want invalid_argument %q", got, tt.message)
+			}
+			if !reflect.DeepEqual(got.Details, tt.details) {
+				t.Errorf("details %+v, want %+v", got.Details, tt.details)
+			}
+		})
+	}
+}
@@ -15,14 +15,62 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/health/generate": {
+        "/api/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns policy and role changes, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Policy audit trail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size, default 50",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/casbin.AuditEntry"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/doctors": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Generates health recommendations for a user",
+                "description": "Creates a doctor account in the auth service",
                 "consumes": [
                     "application/json"
                 ],
@@ -30,171 +78,160 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "HealthCheck"
+                    "Admin"
                 ],
-                "summary": "Generate health recommendations",
+                "summary": "Register a doctor",
                 "parameters": [
                     {
-                        "description": "Request body for generating health recommendations",
+                        "description": "Registration data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.GenerateHealthRecommendationsReq"
+                            "$ref": "#/definitions/models.RegisterReq"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful operation",
+                    "201": {
+                        "description": "User created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.RegisterRes"
                         }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "Invalid request parameters",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/health/getDailyHealthSummary/{date}": {
+        "/api/admin/outbox": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves the daily health summary for a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns events waiting for, or done with, delivery to Kafka, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "HealthCheck"
+                    "Admin"
                 ],
-                "summary": "Get daily health summary",
+                "summary": "List outbox events",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Date in format YYYY-MM-DD",
-                        "name": "date",
-                        "in": "query",
-                        "required": true
+                        "description": "pending, sent or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, default 50",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Events to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/health.GetDailyHealthSummaryRes"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/outbox.Event"
+                            }
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/health/getRealtimeHealthMonitoring/{user_id}": {
-            "get": {
+        "/api/admin/outbox/replay": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves real-time health monitoring data for a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Makes every event that ran out of attempts due again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "HealthCheck"
+                    "Admin"
                 ],
-                "summary": "Get real-time health monitoring data",
+                "summary": "Replay dead outbox events",
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.GetRealtimeHealthMonitoringRes"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid request parameters",
-                        "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ReplayRes"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/health/getWeeklyHealthSummary/{start_date}/{end_date}": {
-            "get": {
+        "/api/admin/outbox/{id}/replay": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves the weekly health summary for a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Makes the event due again with a fresh attempt budget",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "HealthCheck"
+                    "Admin"
                 ],
-                "summary": "Get weekly health summary",
+                "summary": "Replay an outbox event",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Date in format YYYY-MM-DD",
-                        "name": "start_date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Date in format YYYY-MM-DD",
-                        "name": "end_date",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -202,32 +239,70 @@ const docTemplate = `{
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/health.GetWeeklyHealthSummaryRes"
+                            "$ref": "#/definitions/models.ReplayRes"
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Event not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/lifestyle/addLifestyleData": {
-            "post": {
+        "/api/admin/policies": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns every permission rule the enforcer holds",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List policies",
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PolicyRule"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Adds lifestyle data for a user",
+                "description": "Replaces the permission rules with the given set. Only the difference is applied and audited.",
                 "consumes": [
                     "application/json"
                 ],
@@ -235,17 +310,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Lifestyle"
+                    "Admin"
                 ],
-                "summary": "Add lifestyle data",
+                "summary": "Replace all policies",
                 "parameters": [
                     {
-                        "description": "Request body for adding lifestyle data",
+                        "description": "Full set of rules",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.AddLifeStyleDataReq"
+                            "$ref": "#/definitions/models.ReplacePoliciesReq"
                         }
                     }
                 ],
@@ -253,32 +328,36 @@ const docTemplate = `{
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.Success"
+                            "$ref": "#/definitions/models.ReplacePoliciesRes"
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Change would lock admins out",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/lifestyle/deleteLifestyleData/{id}": {
-            "delete": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a specific lifestyle data entry by its ID",
+                "description": "Adds a permission rule",
                 "consumes": [
                     "application/json"
                 ],
@@ -286,42 +365,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Lifestyle"
+                    "Admin"
                 ],
-                "summary": "Delete lifestyle data",
+                "summary": "Add a policy",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Data ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Rule to add",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PolicyRule"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
+                    "201": {
                         "description": "Successful operation",
                         "schema": {
                             "$ref": "#/definitions/models.Success"
                         }
                     },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Policy already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/lifestyle/getAllLifestyleData": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves lifestyle data for a user",
+                "description": "Removes a permission rule",
                 "consumes": [
                     "application/json"
                 ],
@@ -329,76 +420,94 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Lifestyle"
+                    "Admin"
+                ],
+                "summary": "Remove a policy",
+                "parameters": [
+                    {
+                        "description": "Rule to remove",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PolicyRule"
+                        }
+                    }
                 ],
-                "summary": "Get lifestyle data",
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.GetLifeStyle"
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Policy not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Change would lock admins out",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/lifestyle/getLifestyleById/{id}": {
+        "/api/admin/roles": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves lifestyle data for a specific entry by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns every role assignment. The user may be a user ID or another role.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Lifestyle"
-                ],
-                "summary": "Get lifestyle data by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Data ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "List role assignments",
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/health.GetLifeStyleDataByIdRes"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RoleAssignment"
+                            }
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/lifestyle/updateLifestyleData": {
-            "put": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates a specific lifestyle data entry",
+                "description": "Gives a user, or another role, the permissions of a role",
                 "consumes": [
                     "application/json"
                 ],
@@ -406,22 +515,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Lifestyle"
+                    "Admin"
                 ],
-                "summary": "Update lifestyle data",
+                "summary": "Assign a role",
                 "parameters": [
                     {
-                        "description": "Request body for updating lifestyle data",
+                        "description": "Role assignment",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.UpdateLifeStyleDataReq"
+                            "$ref": "#/definitions/models.RoleAssignment"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
+                    "201": {
                         "description": "Successful operation",
                         "schema": {
                             "$ref": "#/definitions/models.Success"
@@ -430,38 +539,48 @@ const docTemplate = `{
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Role already assigned",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/medicalReport/add": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Adds a medical report for a user",
+                "description": "Takes a role away from a user or another role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "MedicalReport"
+                    "Admin"
                 ],
-                "summary": "Add medical report",
+                "summary": "Remove a role assignment",
                 "parameters": [
                     {
-                        "description": "Request body for adding a medical report",
+                        "description": "Role assignment",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.AddMedicalReportReq"
+                            "$ref": "#/definitions/models.RoleAssignment"
                         }
                     }
                 ],
@@ -475,134 +594,147 @@ const docTemplate = `{
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Role assignment not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/medicalReport/delete/{id}": {
-            "delete": {
+        "/api/alerts/rules": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a specific medical report by its ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
+                "description": "Lists the rules that apply to every patient, plus the patient's own rules when patient_id is given. A patient's rule replaces the general rule with the same data type, condition and component.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "MedicalReport"
+                    "Alerts"
                 ],
-                "summary": "Delete medical report",
+                "summary": "List alert rules",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Report ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Also list this patient's rules",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only rules for this data type",
+                        "name": "data_type",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful operation",
+                        "description": "Rules, oldest first",
                         "schema": {
-                            "$ref": "#/definitions/models.Success"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/alert.Rule"
+                            }
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "$ref": "#/definitions/models.Error"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/medicalReport/get": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Retrieves all medical reports for a user",
-                "tags": [
-                    "MedicalReport"
-                ],
-                "summary": "Get medical reports",
-                "responses": {
-                    "200": {
-                        "description": "Successful operation",
+                    "404": {
+                        "description": "Patient not found",
                         "schema": {
-                            "$ref": "#/definitions/health.GetMedicalReportRes"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/medicalReport/getById/{id}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves a specific medical report by its ID",
+                "description": "Adds a rule for every patient, which only admins may do, or for one patient, which their doctors may do. above and below compare each reading with the threshold, or with for_minutes, readings over that long; rise and fall compare the change within window_minutes. Thresholds are in the data type's canonical unit (see /api/measurements).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "MedicalReport"
+                    "Alerts"
                 ],
-                "summary": "Get medical report by ID",
+                "summary": "Add an alert rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Report ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Rule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AlertRuleReq"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful operation",
+                    "201": {
+                        "description": "Created rule",
                         "schema": {
-                            "$ref": "#/definitions/health.GetMedicalReportByIdRes"
+                            "$ref": "#/definitions/alert.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid rule",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins may add rules for every patient",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Patient not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/medicalReport/update": {
+        "/api/alerts/rules/{id}": {
             "put": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates a specific medical report",
+                "description": "Replaces a rule. The caller must be allowed to manage both the rule as it is and as it will be.",
                 "consumes": [
                     "application/json"
                 ],
@@ -610,59 +742,79 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "MedicalReport"
+                    "Alerts"
                 ],
-                "summary": "Update medical report",
+                "summary": "Update an alert rule",
                 "parameters": [
                     {
-                        "description": "Request body for updating a medical report",
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rule",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.UpdateMedicalReportReq"
+                            "$ref": "#/definitions/models.AlertRuleReq"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful operation",
+                        "description": "Updated rule",
                         "schema": {
-                            "$ref": "#/definitions/models.Success"
+                            "$ref": "#/definitions/alert.Rule"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
+                        "description": "Invalid rule",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins may change rules for every patient",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/user/email/{email}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves a user’s information by their email address",
+                "description": "Deletes a rule. Deleting a patient's rule brings the general rule it replaced back into force.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "User"
+                    "Alerts"
                 ],
-                "summary": "Get user by email",
+                "summary": "Delete an alert rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User Email",
-                        "name": "email",
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -671,82 +823,107 @@ const docTemplate = `{
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/user.FilterUsers"
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins may delete rules for every patient",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/user/profile/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
+        "/api/auth/login": {
+            "post": {
+                "description": "Checks the credentials and returns an access and a refresh token issued by the gateway",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
-                "description": "Retrieves the profile information of a user by their ID",
                 "tags": [
-                    "User"
+                    "Auth"
                 ],
-                "summary": "Get user profile",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Credentials",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.GetProfileRes"
+                            "$ref": "#/definitions/models.LoginRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid email or password",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/user/updateUser/{id}": {
-            "put": {
+        "/api/auth/logout": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates the profile information of a user",
+                "description": "Revokes the access token in the Authorization header and, if given, the refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "User"
+                    "Auth"
                 ],
-                "summary": "Update user profile",
+                "summary": "Log out",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Request body for updating user profile",
+                        "description": "Refresh token to revoke",
                         "name": "body",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateProfileReq"
+                            "$ref": "#/definitions/models.LogoutReq"
                         }
                     }
                 ],
@@ -757,97 +934,113 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.Success"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request parameters",
+                    "401": {
+                        "description": "Invalid token provided",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/notifications/getAll": {
-            "get": {
+        "/api/auth/logout-all": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "it will GetAllNotifications",
+                "description": "Revokes every access and refresh token of the caller issued so far",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Notifications"
+                    "Auth"
                 ],
-                "summary": "GetAllNotifications",
+                "summary": "Sign out everywhere",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/user.GetNotificationsResponse"
+                            "$ref": "#/definitions/models.Success"
                         }
                     },
-                    "400": {
-                        "description": "Invalid data",
+                    "401": {
+                        "description": "Invalid token provided",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/notifications/new": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Swaps a refresh token for a new access/refresh pair. The old refresh token stops working",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
-                "description": "it will GetAndMarkNotificationAsRead",
                 "tags": [
-                    "Notifications"
+                    "Auth"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshReq"
+                        }
+                    }
                 ],
-                "summary": "GetAndMarkNotificationAsRead",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/user.GetAndMarkNotificationAsReadRes"
+                            "$ref": "#/definitions/models.TokensRes"
                         }
                     },
                     "400": {
-                        "description": "Invalid data",
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid refresh token",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/wearable/add": {
+        "/api/auth/register": {
             "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Adds wearable data for a user",
+                "description": "Creates a new patient account in the auth service. Doctor accounts are created by an admin",
                 "consumes": [
                     "application/json"
                 ],
@@ -855,50 +1048,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "WearableData"
+                    "Auth"
                 ],
-                "summary": "Add wearable data",
+                "summary": "Register a new user",
                 "parameters": [
                     {
-                        "description": "Request body for adding wearable data",
+                        "description": "Registration data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.AddWearableDataReq"
+                            "$ref": "#/definitions/models.RegisterReq"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful operation",
+                    "201": {
+                        "description": "User created",
                         "schema": {
-                            "$ref": "#/definitions/models.Success"
+                            "$ref": "#/definitions/models.RegisterRes"
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/wearable/delete/{id}": {
-            "delete": {
+        "/api/careTeam/grant": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a specific wearable data entry by its ID",
+                "description": "Lets the given doctor access the caller's medical reports, lifestyle and wearable data",
                 "consumes": [
                     "application/json"
                 ],
@@ -906,17 +1105,19 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "WearableData"
+                    "CareTeam"
                 ],
-                "summary": "Delete wearable data",
+                "summary": "Add a doctor to the care team",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Wearable Data ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                        "description": "Doctor to grant access to",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantCareTeamReq"
+                        }
+                    }
                 ],
                 "responses": {
                     "200": {
@@ -925,23 +1126,112 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.Success"
                         }
                     },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Doctor not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/wearable/get": {
+        "/api/careTeam/list": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves all wearable data for a user",
+                "description": "Returns the doctors the caller has granted access to and the patients who granted the caller access",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CareTeam"
+                ],
+                "summary": "List care team links",
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.CareTeam"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/careTeam/revoke/{doctor_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Takes away the doctor's access to the caller's data",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CareTeam"
+                ],
+                "summary": "Remove a doctor from the care team",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Doctor ID",
+                        "name": "doctor_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "404": {
+                        "description": "Doctor is not in the care team",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health/generate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates health recommendations for a user",
                 "consumes": [
                     "application/json"
                 ],
@@ -949,33 +1239,123 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "WearableData"
+                    "HealthCheck"
+                ],
+                "summary": "Generate health recommendations",
+                "parameters": [
+                    {
+                        "description": "Request body for generating health recommendations",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.GenerateHealthRecommendationsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted; poll the Location header for the outcome",
+                        "schema": {
+                            "$ref": "#/definitions/models.JobAccepted"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Job status URL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit or daily quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health/getDailyHealthSummary/{date}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the daily health summary for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "HealthCheck"
+                ],
+                "summary": "Get daily health summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Date in format YYYY-MM-DD",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get wearable data",
                 "responses": {
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.Warable"
+                            "$ref": "#/definitions/health.GetDailyHealthSummaryRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/wearable/getById/{id}": {
+        "/api/health/getRealtimeHealthMonitoring/{user_id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves a specific wearable data entry by its ID",
+                "description": "Retrieves real-time health monitoring data for a user",
                 "consumes": [
                     "application/json"
                 ],
@@ -983,14 +1363,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "WearableData"
+                    "HealthCheck"
                 ],
-                "summary": "Get wearable data by ID",
+                "summary": "Get real-time health monitoring data",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wearable Data ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
@@ -999,26 +1379,38 @@ const docTemplate = `{
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/health.GetWearableDataByIdRes"
+                            "$ref": "#/definitions/models.GetRealtimeHealthMonitoringRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/wearable/update/": {
-            "put": {
+        "/api/health/getWeeklyHealthSummary/{start_date}/{end_date}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates a specific wearable data entry",
+                "description": "Retrieves the weekly health summary for a user",
                 "consumes": [
                     "application/json"
                 ],
@@ -1026,17 +1418,79 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "WearableData"
+                    "HealthCheck"
                 ],
-                "summary": "Update wearable data",
+                "summary": "Get weekly health summary",
                 "parameters": [
                     {
-                        "description": "Request body for updating wearable data",
+                        "type": "string",
+                        "description": "Date in format YYYY-MM-DD",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date in format YYYY-MM-DD",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetWeeklyHealthSummaryRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health/realtime": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Records a real-time reading or recommendation for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "HealthCheck"
+                ],
+                "summary": "Add real-time health data",
+                "parameters": [
+                    {
+                        "description": "Real-time data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/health.UpdateWearableDataReq"
+                            "$ref": "#/definitions/health.AddRealTimeDataReq"
                         }
                     }
                 ],
@@ -1044,101 +1498,2248 @@ const docTemplate = `{
                     "200": {
                         "description": "Successful operation",
                         "schema": {
-                            "$ref": "#/definitions/models.Success"
+                            "$ref": "#/definitions/health.AddRealTimeDataRes"
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "health.AddLifeStyleDataReq": {
-            "type": "object",
-            "properties": {
-                "data_type": {
-                    "type": "string"
-                },
-                "data_value": {
-                    "type": "string"
+        },
+        "/api/health/realtime/{user_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the latest real-time data of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "HealthCheck"
+                ],
+                "summary": "Get real-time health data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetRealTimeDataRes"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reports whether a request answered with 202 Accepted is pending, succeeded, failed or expired without a reply. Finished jobs are purged after a while and are then not found",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Get job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/job.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lifestyle/addLifestyleData": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds lifestyle data for a user. data_type must be one of GET /api/measurements; data_value may name its unit and is stored in the canonical one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lifestyle"
+                ],
+                "summary": "Add lifestyle data",
+                "parameters": [
+                    {
+                        "description": "Request body for adding lifestyle data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.AddLifeStyleDataReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lifestyle/deleteLifestyleData/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a specific lifestyle data entry by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lifestyle"
+                ],
+                "summary": "Delete lifestyle data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Data ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "404": {
+                        "description": "Lifestyle data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lifestyle/getAllLifestyleData": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves lifestyle data for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lifestyle"
+                ],
+                "summary": "Get lifestyle data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetLifeStyle"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lifestyle/getLifestyleById/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves lifestyle data for a specific entry by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lifestyle"
+                ],
+                "summary": "Get lifestyle data by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Data ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetLifeStyleDataByIdRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Lifestyle data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lifestyle/updateLifestyleData": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates a specific lifestyle data entry. The value is checked and converted as for adding.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lifestyle"
+                ],
+                "summary": "Update lifestyle data",
+                "parameters": [
+                    {
+                        "description": "Request body for updating lifestyle data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.UpdateLifeStyleDataReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Lifestyle data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/measurements": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the data types wearable and lifestyle readings may carry, with the units each accepts and the canonical unit values are stored in. A value may name its unit, e.g. \"154 lb\"; it is stored converted and without one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Measurements"
+                ],
+                "summary": "List measurement types",
+                "responses": {
+                    "200": {
+                        "description": "Data types, sorted by name",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/measure.Type"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/medicalReport/add": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds a medical report for a user",
+                "tags": [
+                    "MedicalReport"
+                ],
+                "summary": "Add medical report",
+                "parameters": [
+                    {
+                        "description": "Request body for adding a medical report",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.AddMedicalReportReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Medical report not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/medicalReport/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a specific medical report by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MedicalReport"
+                ],
+                "summary": "Delete medical report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "404": {
+                        "description": "Medical report not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/medicalReport/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all medical reports for a user",
+                "tags": [
+                    "MedicalReport"
+                ],
+                "summary": "Get medical reports",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetMedicalReportRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/medicalReport/getById/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific medical report by its ID",
+                "tags": [
+                    "MedicalReport"
+                ],
+                "summary": "Get medical report by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetMedicalReportByIdRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Medical report not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/medicalReport/update": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates a specific medical report",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MedicalReport"
+                ],
+                "summary": "Update medical report",
+                "parameters": [
+                    {
+                        "description": "Request body for updating a medical report",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.UpdateMedicalReportReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Medical report not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notifications/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends the caller's notifications as server-sent events (\"notification\") as they happen. Reconnect with Last-Event-ID to receive what was missed.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Stream notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event data",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationEvent"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many open streams",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/email/{email}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a user’s information by their email address",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user by email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User Email",
+                        "name": "email",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/user.FilterUsers"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/profile/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the profile information of a user by their ID",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetProfileRes"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/updateUser/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates the profile information of a user",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request body for updating user profile",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateProfileReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/getAll": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "it will GetAllNotifications",
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "GetAllNotifications",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.GetNotificationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/new": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "it will GetAndMarkNotificationAsRead",
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "GetAndMarkNotificationAsRead",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.GetAndMarkNotificationAsReadRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/add": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds wearable data for a user. data_type must be one of GET /api/measurements; data_value may name its unit and is stored in the canonical one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Add wearable data",
+                "parameters": [
+                    {
+                        "description": "Request body for adding wearable data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.AddWearableDataReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted; poll the Location header for the outcome",
+                        "schema": {
+                            "$ref": "#/definitions/models.JobAccepted"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Job status URL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Accepts a JSON array or newline-delimited JSON (application/x-ndjson) of readings. Each reading is checked on its own; valid ones are queued even when others are rejected, so check the status of every item. Unlike POST /api/wearable/add no job is started and no Location is returned: accepted readings are only queued, and event_id identifies each of them in the feeds.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Add wearable data in bulk",
+                "parameters": [
+                    {
+                        "description": "Readings; user_id defaults to the caller",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/health.AddWearableDataReq"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Per-item report",
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkWearableRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Body is not a JSON array or NDJSON",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Too many readings or body too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a specific wearable data entry by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Delete wearable data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wearable Data ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "404": {
+                        "description": "Wearable data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all wearable data for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Get wearable data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Warable"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/getById/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific wearable data entry by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Get wearable data by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wearable Data ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/health.GetWearableDataByIdRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Wearable data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reads an Apple Health export.xml, a Google Fit export (Takeout data points or a Fitness API dataset) or a Fitbit export (Takeout file or Web API response) and queues its readings with normalised data types and units. Imported readings are tagged as such and are neither streamed nor checked against the alert rules. The body is streamed, so exports of any size up to the limit can be sent. Readings are queued in batches; if the body breaks off half way the batches before the error stay queued.",
+                "consumes": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Import wearable data from another app",
+                "parameters": [
+                    {
+                        "enum": [
+                            "apple_health",
+                            "google_fit",
+                            "fitbit"
+                        ],
+                        "type": "string",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "steps",
+                            "calories"
+                        ],
+                        "type": "string",
+                        "description": "What a Fitbit Takeout file of plain {dateTime, value} entries holds",
+                        "name": "data_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unit of Fitbit values that don't name one, e.g. lb for weight",
+                        "name": "unit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User the readings belong to; defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Import summary",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportWearableRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown format or malformed export",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Body too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends a patient's wearable readings (\"reading\") and the alerts they raise (\"alert\") as server-sent events as they arrive. Reconnect with Last-Event-ID to receive what was missed.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Stream wearable readings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient ID, defaults to the caller",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only these data types, comma separated",
+                        "name": "data_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only these device types, comma separated",
+                        "name": "device_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reading event data",
+                        "schema": {
+                            "$ref": "#/definitions/models.VitalReading"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many open streams",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/wearable/update/": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates a specific wearable data entry. The value is checked and converted as for adding.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WearableData"
+                ],
+                "summary": "Update wearable data",
+                "parameters": [
+                    {
+                        "description": "Request body for updating wearable data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/health.UpdateWearableDataReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Success"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Wearable data not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Answers while the gateway process is running. It checks no dependency, so a failing backend never gets the gateway restarted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Probes"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "Alive",
+                        "schema": {
+                            "$ref": "#/definitions/models.Liveness"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks the backends, databases and Kafka and reports each. The gateway is not ready, and answers 503, while a critical dependency is down.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Probes"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "Ready",
+                        "schema": {
+                            "$ref": "#/definitions/readiness.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Not ready",
+                        "schema": {
+                            "$ref": "#/definitions/readiness.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "alert.Rule": {
+            "type": "object",
+            "properties": {
+                "component": {
+                    "description": "Component picks the systolic or diastolic value of blood pressure.",
+                    "type": "string"
+                },
+                "condition": {
+                    "type": "string"
+                },
+                "cooldown_minutes": {
+                    "description": "CooldownMinutes is how long the rule stays quiet for a patient after\nit fired.",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "data_type": {
+                    "type": "string"
+                },
+                "for_minutes": {
+                    "description": "ForMinutes makes above and below match only once the value has stayed\npast the threshold that long.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "patient_id": {
+                    "description": "PatientID is empty for a rule that applies to every patient.",
+                    "type": "string"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "Threshold is in the canonical unit of the data type; for rise and\nfall it is the change.",
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "window_minutes": {
+                    "description": "WindowMinutes is how far back rise and fall look.",
+                    "type": "integer"
+                }
+            }
+        },
+        "casbin.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ptype": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "health.AddLifeStyleDataReq": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.AddMedicalReportReq": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Attachment"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "string"
+                },
+                "record_date": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.AddRealTimeDataReq": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "description_recommendation": {
+                    "type": "string"
+                },
+                "description_record": {
+                    "type": "string"
+                },
+                "device_data_type": {
+                    "type": "string"
+                },
+                "device_data_value": {
+                    "type": "string"
+                },
+                "device_type": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "recommendation_type": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.AddRealTimeDataRes": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "health.AddWearableDataReq": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "device_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Attachment": {
+            "type": "object",
+            "properties": {
+                "file_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Data": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "description_recommendation": {
+                    "type": "string"
+                },
+                "description_record": {
+                    "type": "string"
+                },
+                "device_data_type": {
+                    "type": "string"
+                },
+                "device_data_value": {
+                    "type": "string"
+                },
+                "device_type": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "recommendation_type": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.GenerateHealthRecommendationsReq": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "recommendation_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.GetDailyHealthSummaryRes": {
+            "type": "object",
+            "properties": {
+                "recommendations": {
+                    "$ref": "#/definitions/health.HealthRecommendation"
+                }
+            }
+        },
+        "health.GetLifeStyleByIdRes": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "recorded_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.GetLifeStyleDataByIdRes": {
+            "type": "object",
+            "properties": {
+                "life_style": {
+                    "$ref": "#/definitions/health.GetLifeStyleByIdRes"
+                }
+            }
+        },
+        "health.GetMedicalReportByIdRes": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Attachment"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "record_date": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.GetMedicalReportRes": {
+            "type": "object",
+            "properties": {
+                "medical_report": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.MedicalReport"
+                    }
+                }
+            }
+        },
+        "health.GetRealTimeDataRes": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/health.Data"
+                }
+            }
+        },
+        "health.GetWearableDataByIdRes": {
+            "type": "object",
+            "properties": {
+                "get_warable_id": {
+                    "$ref": "#/definitions/health.WearableById"
+                }
+            }
+        },
+        "health.GetWeeklyHealthSummaryRes": {
+            "type": "object",
+            "properties": {
+                "recommendations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.HealthRecommendation"
+                    }
+                }
+            }
+        },
+        "health.HealthRecommendation": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "recommendation_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.MedicalReport": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "record_date": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.UpdateLifeStyleDataReq": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.UpdateMedicalReportReq": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Attachment"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.UpdateWearableDataReq": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "device_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.WearableById": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "device_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "recorded_timestamp": {
+                    "type": "string"
                 },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
-        "health.AddMedicalReportReq": {
+        "job.Job": {
             "type": "object",
             "properties": {
-                "attachments": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID is the event id of the event that started the job.",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "UserID is the caller who started the job; only they, and roles with\ncross-user access, may see it.",
+                    "type": "string"
+                }
+            }
+        },
+        "measure.Type": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "example": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "description": "Min and Max bound a value in the canonical unit. For blood pressure\nthey bound the systolic value; the diastolic one must be lower.",
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit is the canonical unit values are stored in.",
+                    "type": "string"
+                },
+                "units": {
+                    "description": "Units are the units accepted on input, the canonical one first. A\nvalue without a unit is taken to be in the canonical one.",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/health.Attachment"
+                        "$ref": "#/definitions/measure.Unit"
+                    }
+                }
+            }
+        },
+        "measure.Unit": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "symbol": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AlertRuleReq": {
+            "type": "object",
+            "required": [
+                "condition",
+                "data_type"
+            ],
+            "properties": {
+                "component": {
+                    "type": "string"
+                },
+                "condition": {
+                    "type": "string",
+                    "example": "above"
+                },
+                "cooldown_minutes": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "data_type": {
+                    "type": "string",
+                    "example": "heart_rate"
+                },
+                "for_minutes": {
+                    "description": "ForMinutes makes above and below wait until the value has stayed\npast the threshold that long.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "patient_id": {
+                    "description": "PatientID is left out for a rule that applies to every patient.",
+                    "type": "string"
+                },
+                "severity": {
+                    "type": "string",
+                    "example": "critical"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 150
+                },
+                "window_minutes": {
+                    "description": "WindowMinutes is how far back rise and fall look.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BulkItemResult": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "event_id": {
+                    "description": "EventID is the id of the queued event. Bulk readings start no job,\nso there is no status to poll.",
+                    "type": "string"
+                },
+                "index": {
+                    "description": "Index is the item's position in the upload, from 0.",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status is \"accepted\" or \"rejected\".",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BulkWearableRes": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BulkItemResult"
+                    }
+                },
+                "received": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CareTeam": {
+            "type": "object",
+            "properties": {
+                "doctors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "patients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
                     }
                 },
+                "message": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetLifeStyle": {
+            "type": "object",
+            "properties": {
+                "data_type": {
+                    "type": "string"
+                },
+                "data_value": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "recorded_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetProfileRes": {
+            "type": "object",
+            "properties": {
+                "date_of_birth": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetRealtimeHealthMonitoringRes": {
+            "type": "object",
+            "properties": {
                 "description": {
                     "type": "string"
                 },
-                "doctor_id": {
+                "first_name": {
                     "type": "string"
                 },
-                "record_date": {
+                "last_name": {
                     "type": "string"
                 },
-                "record_type": {
+                "priority": {
+                    "type": "integer"
+                },
+                "recommendation_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GrantCareTeamReq": {
+            "type": "object",
+            "required": [
+                "doctor_id"
+            ],
+            "properties": {
+                "doctor_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportError": {
+            "type": "object",
+            "properties": {
+                "message": {
                     "type": "string"
                 },
-                "user_id": {
+                "record": {
+                    "description": "Record is the record's position in the export, from 0.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportWearableRes": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Errors lists the first invalid records.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportError"
+                    }
+                },
+                "format": {
                     "type": "string"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Skipped counts records of types the gateway doesn't track.",
+                    "type": "integer"
                 }
             }
         },
-        "health.AddWearableDataReq": {
+        "models.JobAccepted": {
             "type": "object",
             "properties": {
-                "data_type": {
+                "job_id": {
                     "type": "string"
                 },
-                "data_value": {
+                "location": {
                     "type": "string"
                 },
-                "device_type": {
+                "status": {
                     "type": "string"
-                },
-                "user_id": {
+                }
+            }
+        },
+        "models.Liveness": {
+            "type": "object",
+            "properties": {
+                "status": {
                     "type": "string"
                 }
             }
         },
-        "health.Attachment": {
+        "models.LoginReq": {
             "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
             "properties": {
-                "file_url": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
                     "type": "string"
                 }
             }
         },
-        "health.GenerateHealthRecommendationsReq": {
+        "models.LoginRes": {
             "type": "object",
             "properties": {
-                "description": {
+                "access_token": {
                     "type": "string"
                 },
-                "priority": {
-                    "type": "integer"
+                "first_name": {
+                    "type": "string"
                 },
-                "recommendation_type": {
+                "last_name": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "role": {
                     "type": "string"
                 },
                 "user_id": {
@@ -1146,211 +3747,183 @@ const docTemplate = `{
                 }
             }
         },
-        "health.GetDailyHealthSummaryRes": {
+        "models.LogoutReq": {
             "type": "object",
             "properties": {
-                "recommendations": {
-                    "$ref": "#/definitions/health.HealthRecommendation"
+                "refresh_token": {
+                    "type": "string"
                 }
             }
         },
-        "health.GetLifeStyleByIdRes": {
+        "models.NotificationEvent": {
             "type": "object",
             "properties": {
-                "data_type": {
+                "created_at": {
                     "type": "string"
                 },
-                "data_value": {
+                "message": {
                     "type": "string"
-                },
-                "id": {
+                }
+            }
+        },
+        "models.PolicyRule": {
+            "type": "object",
+            "required": [
+                "action",
+                "object",
+                "subject"
+            ],
+            "properties": {
+                "action": {
                     "type": "string"
                 },
-                "recorded_date": {
+                "object": {
                     "type": "string"
                 },
-                "user_id": {
+                "subject": {
                     "type": "string"
                 }
             }
         },
-        "health.GetLifeStyleDataByIdRes": {
+        "models.RefreshReq": {
             "type": "object",
+            "required": [
+                "refresh_token"
+            ],
             "properties": {
-                "life_style": {
-                    "$ref": "#/definitions/health.GetLifeStyleByIdRes"
+                "refresh_token": {
+                    "type": "string"
                 }
             }
         },
-        "health.GetMedicalReportByIdRes": {
+        "models.RegisterReq": {
             "type": "object",
+            "required": [
+                "date_of_birth",
+                "email",
+                "first_name",
+                "gender",
+                "last_name",
+                "password"
+            ],
             "properties": {
-                "attachments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/health.Attachment"
-                    }
-                },
-                "description": {
-                    "type": "string"
-                },
-                "doctor_id": {
+                "date_of_birth": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
-                "id": {
+                "first_name": {
                     "type": "string"
                 },
-                "record_date": {
+                "gender": {
                     "type": "string"
                 },
-                "record_type": {
+                "last_name": {
                     "type": "string"
                 },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                }
+            }
+        },
+        "models.RegisterRes": {
+            "type": "object",
+            "properties": {
                 "user_id": {
                     "type": "string"
                 }
             }
         },
-        "health.GetMedicalReportRes": {
+        "models.ReplacePoliciesReq": {
             "type": "object",
+            "required": [
+                "policies"
+            ],
             "properties": {
-                "medical_report": {
+                "policies": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/health.MedicalReport"
+                        "$ref": "#/definitions/models.PolicyRule"
                     }
                 }
             }
         },
-        "health.GetWearableDataByIdRes": {
+        "models.ReplacePoliciesRes": {
             "type": "object",
             "properties": {
-                "get_warable_id": {
-                    "$ref": "#/definitions/health.WearableById"
+                "added": {
+                    "type": "integer"
+                },
+                "removed": {
+                    "type": "integer"
                 }
             }
         },
-        "health.GetWeeklyHealthSummaryRes": {
+        "models.ReplayRes": {
             "type": "object",
             "properties": {
-                "recommendations": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/health.HealthRecommendation"
-                    }
+                "replayed": {
+                    "type": "integer"
                 }
             }
         },
-        "health.HealthRecommendation": {
+        "models.RoleAssignment": {
             "type": "object",
+            "required": [
+                "role",
+                "user"
+            ],
             "properties": {
-                "description": {
-                    "type": "string"
-                },
-                "first_name": {
-                    "type": "string"
-                },
-                "last_name": {
+                "role": {
                     "type": "string"
                 },
-                "priority": {
-                    "type": "integer"
-                },
-                "recommendation_type": {
+                "user": {
                     "type": "string"
                 }
             }
         },
-        "health.MedicalReport": {
+        "models.Success": {
             "type": "object",
             "properties": {
-                "attachments": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "description": {
-                    "type": "string"
-                },
-                "doctor_id": {
-                    "type": "string"
-                },
-                "first_name": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "last_name": {
-                    "type": "string"
-                },
-                "record_date": {
-                    "type": "string"
-                },
-                "record_type": {
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "health.UpdateLifeStyleDataReq": {
+        "models.TokensRes": {
             "type": "object",
             "properties": {
-                "data_type": {
-                    "type": "string"
-                },
-                "data_value": {
+                "access_token": {
                     "type": "string"
                 },
-                "id": {
+                "refresh_token": {
                     "type": "string"
                 }
             }
         },
-        "health.UpdateMedicalReportReq": {
+        "models.UpdateProfileReq": {
             "type": "object",
             "properties": {
-                "attachments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/health.Attachment"
-                    }
-                },
-                "description": {
-                    "type": "string"
-                },
-                "doctor_id": {
-                    "type": "string"
-                },
-                "id": {
+                "date_of_birth": {
                     "type": "string"
                 },
-                "record_type": {
-                    "type": "string"
-                }
-            }
-        },
-        "health.UpdateWearableDataReq": {
-            "type": "object",
-            "properties": {
-                "data_type": {
+                "email": {
                     "type": "string"
                 },
-                "data_value": {
+                "first_name": {
                     "type": "string"
                 },
-                "device_type": {
+                "gender": {
                     "type": "string"
                 },
-                "id": {
+                "last_name": {
                     "type": "string"
                 }
             }
         },
-        "health.WearableById": {
+        "models.VitalReading": {
             "type": "object",
             "properties": {
                 "data_type": {
@@ -1362,10 +3935,7 @@ const docTemplate = `{
                 "device_type": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "string"
-                },
-                "recorded_timestamp": {
+                "recorded_at": {
                     "type": "string"
                 },
                 "user_id": {
@@ -1373,15 +3943,7 @@ const docTemplate = `{
                 }
             }
         },
-        "models.Error": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.GetLifeStyle": {
+        "models.Warable": {
             "type": "object",
             "properties": {
                 "data_type": {
@@ -1390,107 +3952,94 @@ const docTemplate = `{
                 "data_value": {
                     "type": "string"
                 },
+                "device_type": {
+                    "type": "string"
+                },
                 "first_name": {
                     "type": "string"
                 },
                 "last_name": {
                     "type": "string"
                 },
-                "recorded_date": {
+                "recorded_timestamp": {
                     "type": "string"
                 }
             }
         },
-        "models.GetProfileRes": {
+        "outbox.Event": {
             "type": "object",
             "properties": {
-                "date_of_birth": {
-                    "type": "string"
+                "attempts": {
+                    "type": "integer"
                 },
-                "email": {
+                "created_at": {
                     "type": "string"
                 },
-                "first_name": {
-                    "type": "string"
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "gender": {
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "last_name": {
+                "key": {
                     "type": "string"
                 },
-                "role": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.GetRealtimeHealthMonitoringRes": {
-            "type": "object",
-            "properties": {
-                "description": {
+                "last_error": {
                     "type": "string"
                 },
-                "first_name": {
+                "next_attempt_at": {
                     "type": "string"
                 },
-                "last_name": {
+                "sent_at": {
                     "type": "string"
                 },
-                "priority": {
-                    "type": "integer"
-                },
-                "recommendation_type": {
+                "status": {
                     "type": "string"
-                }
-            }
-        },
-        "models.Success": {
-            "type": "object",
-            "properties": {
-                "message": {
+                },
+                "topic": {
                     "type": "string"
                 }
             }
         },
-        "models.UpdateProfileReq": {
+        "readiness.Report": {
             "type": "object",
             "properties": {
-                "date_of_birth": {
-                    "type": "string"
-                },
-                "email": {
-                    "type": "string"
-                },
-                "first_name": {
-                    "type": "string"
-                },
-                "gender": {
-                    "type": "string"
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/readiness.Result"
+                    }
                 },
-                "last_name": {
+                "status": {
                     "type": "string"
                 }
             }
         },
-        "models.Warable": {
+        "readiness.Result": {
             "type": "object",
             "properties": {
-                "data_type": {
-                    "type": "string"
+                "critical": {
+                    "type": "boolean"
                 },
-                "data_value": {
-                    "type": "string"
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "device_type": {
+                "error": {
                     "type": "string"
                 },
-                "first_name": {
-                    "type": "string"
+                "latency_ms": {
+                    "type": "integer"
                 },
-                "last_name": {
+                "name": {
                     "type": "string"
                 },
-                "recorded_timestamp": {
+                "status": {
                     "type": "string"
                 }
             }
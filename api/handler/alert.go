@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"api-gateway/alert"
+	"api-gateway/api/apierror"
+	"api-gateway/genproto/user"
+	"api-gateway/models"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAlertRules godoc
+// @Security ApiKeyAuth
+// @Summary List alert rules
+// @Description Lists the rules that apply to every patient, plus the patient's own rules when patient_id is given. A patient's rule replaces the general rule with the same data type, condition and component.
+// @Tags Alerts
+// @Produce json
+// @Param patient_id query string false "Also list this patient's rules"
+// @Param data_type query string false "Only rules for this data type"
+// @Success 200 {array} alert.Rule "Rules, oldest first"
+// @Failure 404 {object} models.ErrorResponse "Patient not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/alerts/rules [get]
+func (h *Handler) GetAlertRules(ctx *gin.Context) {
+	query := alert.Query{DataType: ctx.Query("data_type"), Patients: []string{""}}
+	if patientID := ctx.Query("patient_id"); patientID != "" {
+		if !h.authorizeOwner(ctx, patientID) {
+			return
+		}
+		query.Patients = append(query.Patients, patientID)
+	}
+
+	rules, err := h.Alerts.Rules.List(ctx.Request.Context(), query)
+	if err != nil {
+		h.Logger.Error("Error listing alert rules", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if rules == nil {
+		rules = []alert.Rule{}
+	}
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// AddAlertRule godoc
+// @Security ApiKeyAuth
+// @Summary Add an alert rule
+// @Description Adds a rule for every patient, which only admins may do, or for one patient, which their doctors may do. above and below compare each reading with the threshold, or with for_minutes, readings over that long; rise and fall compare the change within window_minutes. Thresholds are in the data type's canonical unit (see /api/measurements).
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param body body models.AlertRuleReq true "Rule"
+// @Success 201 {object} alert.Rule "Created rule"
+// @Failure 400 {object} models.ErrorResponse "Invalid rule"
+// @Failure 403 {object} models.ErrorResponse "Only admins may add rules for every patient"
+// @Failure 404 {object} models.ErrorResponse "Patient not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/alerts/rules [post]
+func (h *Handler) AddAlertRule(ctx *gin.Context) {
+	var req models.AlertRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+	rule, ok := h.ruleFromRequest(ctx, req)
+	if !ok {
+		return
+	}
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+	rule.CreatedBy = principal.UserID
+
+	rule, err := h.Alerts.Rules.Create(ctx.Request.Context(), rule)
+	if err != nil {
+		h.Logger.Error("Error creating alert rule", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	h.Logger.Info("Alert rule added", "rule_id", rule.ID, "patient_id", rule.PatientID, "by", rule.CreatedBy)
+	ctx.JSON(http.StatusCreated, rule)
+}
+
+// UpdateAlertRule godoc
+// @Security ApiKeyAuth
+// @Summary Update an alert rule
+// @Description Replaces a rule. The caller must be allowed to manage both the rule as it is and as it will be.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param body body models.AlertRuleReq true "Rule"
+// @Success 200 {object} alert.Rule "Updated rule"
+// @Failure 400 {object} models.ErrorResponse "Invalid rule"
+// @Failure 403 {object} models.ErrorResponse "Only admins may change rules for every patient"
+// @Failure 404 {object} models.ErrorResponse "Rule not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/alerts/rules/{id} [put]
+func (h *Handler) UpdateAlertRule(ctx *gin.Context) {
+	existing, ok := h.loadAlertRule(ctx)
+	if !ok {
+		return
+	}
+	var req models.AlertRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+	rule, ok := h.ruleFromRequest(ctx, req)
+	if !ok {
+		return
+	}
+	rule.ID = existing.ID
+
+	updated, err := h.Alerts.Rules.Update(ctx.Request.Context(), rule)
+	if err == nil && updated {
+		rule, updated, err = h.Alerts.Rules.Get(ctx.Request.Context(), rule.ID)
+	}
+	if err != nil {
+		h.Logger.Error("Error updating alert rule", "rule_id", rule.ID, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !updated {
+		apierror.Write(ctx, http.StatusNotFound, "Rule not found")
+		return
+	}
+	h.Logger.Info("Alert rule updated", "rule_id", rule.ID, "patient_id", rule.PatientID)
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DeleteAlertRule godoc
+// @Security ApiKeyAuth
+// @Summary Delete an alert rule
+// @Description Deletes a rule. Deleting a patient's rule brings the general rule it replaced back into force.
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 403 {object} models.ErrorResponse "Only admins may delete rules for every patient"
+// @Failure 404 {object} models.ErrorResponse "Rule not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/alerts/rules/{id} [delete]
+func (h *Handler) DeleteAlertRule(ctx *gin.Context) {
+	rule, ok := h.loadAlertRule(ctx)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.Alerts.Rules.Delete(ctx.Request.Context(), rule.ID)
+	if err != nil {
+		h.Logger.Error("Error deleting alert rule", "rule_id", rule.ID, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !deleted {
+		apierror.Write(ctx, http.StatusNotFound, "Rule not found")
+		return
+	}
+	h.Logger.Info("Alert rule deleted", "rule_id", rule.ID, "patient_id", rule.PatientID)
+	ctx.JSON(http.StatusOK, models.Success{Message: "Alert rule deleted successfully"})
+}
+
+// loadAlertRule loads the rule named by the id path parameter and checks
+// that the caller may manage it.
+func (h *Handler) loadAlertRule(ctx *gin.Context) (alert.Rule, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Write(ctx, http.StatusBadRequest, "Invalid rule id")
+		return alert.Rule{}, false
+	}
+	rule, found, err := h.Alerts.Rules.Get(ctx.Request.Context(), id)
+	if err != nil {
+		h.Logger.Error("Error loading alert rule", "rule_id", id, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return alert.Rule{}, false
+	}
+	if !found {
+		apierror.Write(ctx, http.StatusNotFound, "Rule not found")
+		return alert.Rule{}, false
+	}
+	if !h.authorizeRuleScope(ctx, rule.PatientID) {
+		return alert.Rule{}, false
+	}
+	return rule, true
+}
+
+// ruleFromRequest validates a rule and checks that the caller may manage
+// it. It answers errors itself and reports false then.
+func (h *Handler) ruleFromRequest(ctx *gin.Context, req models.AlertRuleReq) (alert.Rule, bool) {
+	rule := alert.Rule{
+		DataType:        req.DataType,
+		PatientID:       req.PatientID,
+		Condition:       req.Condition,
+		Component:       req.Component,
+		Threshold:       req.Threshold,
+		WindowMinutes:   req.WindowMinutes,
+		ForMinutes:      req.ForMinutes,
+		CooldownMinutes: req.CooldownMinutes,
+		Severity:        req.Severity,
+	}
+	if err := rule.Validate(); err != nil {
+		var invalid *alert.InvalidRuleError
+		if errors.As(err, &invalid) {
+			apierror.Write(ctx, http.StatusBadRequest, "Invalid rule", models.FieldError{Field: invalid.Field, Message: invalid.Reason})
+			return alert.Rule{}, false
+		}
+		apierror.Write(ctx, http.StatusBadRequest, "Invalid rule")
+		return alert.Rule{}, false
+	}
+	if !h.authorizeRuleScope(ctx, rule.PatientID) {
+		return alert.Rule{}, false
+	}
+	return rule, true
+}
+
+// authorizeRuleScope checks that the caller may manage rules of the
+// patient: their care team may, while rules for every patient take
+// cross-user access.
+func (h *Handler) authorizeRuleScope(ctx *gin.Context, patientID string) bool {
+	if patientID != "" {
+		return h.authorizeOwner(ctx, patientID)
+	}
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return false
+	}
+	allowed, err := h.canAccessUser(principal.Role, principal.UserID, "", ctx.FullPath())
+	if err != nil {
+		h.Logger.Error("Error enforcing ownership policy", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return false
+	}
+	if !allowed {
+		apierror.Write(ctx, http.StatusForbidden, "Only admins may manage rules for every patient")
+		return false
+	}
+	return true
+}
+
+// raiseAlerts checks an accepted reading against the alert rules, notifies
+// the patient of every match and publishes it to the alerts topic. The
+// reading is already queued, so failures are logged rather than answered.
+func (h *Handler) raiseAlerts(ctx *gin.Context, reading alert.Reading) {
+	if h.Alerts.Engine == nil {
+		return
+	}
+	alerts, err := h.Alerts.Engine.Evaluate(ctx.Request.Context(), reading)
+	if err != nil {
+		h.Logger.Error("Error evaluating alert rules", "patient_id", reading.PatientID, "data_type", reading.DataType, "error", err)
+		return
+	}
+
+	for _, a := range alerts {
+		h.Logger.Warn("Alert raised", "rule_id", a.RuleID, "patient_id", a.PatientID, "severity", a.Severity, "message", a.Message)
+
+		_, err := h.User.CreateNotifications(ctx.Request.Context(), &user.CreateNotificationsReq{
+			UserId:  a.PatientID,
+			Message: "[" + a.Severity + "] " + a.Message,
+		})
+		if err != nil {
+			h.Logger.Error("Error creating alert notification", "rule_id", a.RuleID, "patient_id", a.PatientID, "error", err)
+		}
+
+		if err := h.publishAlert(ctx, a); err != nil {
+			h.Logger.Error("Error publishing alert", "rule_id", a.RuleID, "patient_id", a.PatientID, "error", err)
+		}
+	}
+}
+
+// publishAlert queues the alert for the alerts topic.
+func (h *Handler) publishAlert(ctx *gin.Context, a alert.Alert) error {
+	payload, err := a.Payload()
+	if err != nil {
+		return err
+	}
+	meta := h.eventMeta(ctx, a.PatientID, "")
+	meta.OccurredAt = a.At
+	msg, err := h.Events.Encode("alerts", payload, meta)
+	if err != nil {
+		return err
+	}
+	_, err = h.Outbox.Enqueue(ctx.Request.Context(), msg)
+	return err
+}
@@ -1,14 +1,300 @@
 package handler
 
 import (
+	"api-gateway/api/apierror"
+	middleware "api-gateway/api/middlerware"
+	tokenn "api-gateway/api/token"
 	"api-gateway/genproto/user"
 	"api-gateway/models"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// Register godoc
+// @Summary Register a new user
+// @Description Creates a new patient account in the auth service. Doctor accounts are created by an admin
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.RegisterReq true "Registration data"
+// @Success 201 {object} models.RegisterRes "User created"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 409 {object} models.ErrorResponse "User already exists"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/auth/register [post]
+func (h *Handler) Register(ctx *gin.Context) {
+	h.register(ctx, "patient")
+}
+
+// RegisterDoctor godoc
+// @Security ApiKeyAuth
+// @Summary Register a doctor
+// @Description Creates a doctor account in the auth service
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.RegisterReq true "Registration data"
+// @Success 201 {object} models.RegisterRes "User created"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 409 {object} models.ErrorResponse "User already exists"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/doctors [post]
+func (h *Handler) RegisterDoctor(ctx *gin.Context) {
+	h.register(ctx, "doctor")
+}
+
+// register creates an account with the role; the role never comes from the
+// request body.
+func (h *Handler) register(ctx *gin.Context, role string) {
+	var req models.RegisterReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	resp, err := h.User.Register(ctx, &user.RegisterReq{
+		Email:       req.Email,
+		Password:    req.Password,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		DateOfBirth: req.DateOfBirth,
+		Gender:      req.Gender,
+		Role:        role,
+	})
+	if err != nil {
+		h.Logger.Error("Error registering user: ", "error", err)
+		if status.Code(err) == codes.AlreadyExists {
+			apierror.Write(ctx, http.StatusConflict, "User already exists")
+			return
+		}
+		apierror.GRPC(ctx, err)
+		return
+	}
+
+	if principal, ok := middleware.GetPrincipal(ctx); ok {
+		h.Logger.Info("Account registered by admin", "user_id", resp.UserId, "role", role, "admin_id", principal.UserID)
+	}
+	ctx.JSON(http.StatusCreated, models.RegisterRes{UserId: resp.UserId})
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Checks the credentials and returns an access and a refresh token issued by the gateway
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.LoginReq true "Credentials"
+// @Success 200 {object} models.LoginRes "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 401 {object} models.ErrorResponse "Invalid email or password"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/auth/login [post]
+func (h *Handler) Login(ctx *gin.Context) {
+	var req models.LoginReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	resp, err := h.User.Login(ctx, &user.LoginReq{Email: req.Email, Password: req.Password})
+	if err != nil {
+		h.Logger.Error("Error logging in: ", "error", err)
+		switch status.Code(err) {
+		case codes.NotFound, codes.Unauthenticated, codes.InvalidArgument, codes.PermissionDenied:
+			apierror.Write(ctx, http.StatusUnauthorized, "Invalid email or password")
+		default:
+			apierror.GRPC(ctx, err)
+		}
+		return
+	}
+
+	// the gateway issues its own typed pair, so only tokens it issued can
+	// later be refreshed
+	tokens, err := h.Tokens.GenerateTokens(resp.UserId, resp.Role)
+	if err != nil {
+		h.Logger.Error("Error generating tokens: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.LoginRes{
+		UserId:       resp.UserId,
+		FirstName:    resp.FirstName,
+		LastName:     resp.LastName,
+		Role:         resp.Role,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// RefreshToken godoc
+// @Summary Refresh tokens
+// @Description Swaps a refresh token for a new access/refresh pair. The old refresh token stops working
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.RefreshReq true "Refresh token"
+// @Success 200 {object} models.TokensRes "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 401 {object} models.ErrorResponse "Invalid refresh token"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/auth/refresh [post]
+func (h *Handler) RefreshToken(ctx *gin.Context) {
+	var req models.RefreshReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	claims, err := tokenn.ExtractRefreshClaim(h.Verifier, req.RefreshToken)
+	if err != nil {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	principal, err := tokenn.PrincipalFromClaims(*claims, req.RefreshToken)
+	if err != nil {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	revoked, err := tokenn.IsTokenRevoked(ctx, h.Revoked, *claims, req.RefreshToken, h.Tokens.MaxTTL())
+	if err != nil {
+		h.Logger.Error("Error checking token revocation: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if revoked {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	// Revoking first makes rotation atomic: of two concurrent requests with
+	// the same refresh token only one gets a new pair.
+	expiresAt := tokenn.ExpiresAt(*claims, time.Now().Add(h.Tokens.RefreshTTL))
+	fresh, err := h.Revoked.Revoke(ctx, principal.TokenID, expiresAt)
+	if err != nil {
+		h.Logger.Error("Error revoking refresh token: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !fresh {
+		h.Logger.Warn("Refresh token reused", "user_id", principal.UserID)
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	tokens, err := h.Tokens.GenerateTokens(principal.UserID, principal.Role)
+	if err != nil {
+		h.Logger.Error("Error generating tokens: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.TokensRes{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
+
+// Logout godoc
+// @Security ApiKeyAuth
+// @Summary Log out
+// @Description Revokes the access token in the Authorization header and, if given, the refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.LogoutReq false "Refresh token to revoke"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 401 {object} models.ErrorResponse "Invalid token provided"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/auth/logout [post]
+func (h *Handler) Logout(ctx *gin.Context) {
+	accessToken := ctx.GetHeader("Authorization")
+	claims, err := tokenn.ExtractAccessClaim(h.Verifier, accessToken)
+	if err != nil {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid token provided")
+		return
+	}
+
+	var req models.LogoutReq
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.Logger.Error("Error binding JSON: ", "error", err)
+			apierror.Bind(ctx, err)
+			return
+		}
+	}
+
+	expiresAt := tokenn.ExpiresAt(*claims, time.Now().Add(h.Tokens.AccessTTL))
+	if _, err := h.Revoked.Revoke(ctx, tokenn.TokenID(*claims, accessToken), expiresAt); err != nil {
+		h.Logger.Error("Error revoking access token: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	if req.RefreshToken != "" {
+		refreshClaims, err := tokenn.ExtractRefreshClaim(h.Verifier, req.RefreshToken)
+		if err == nil && (*refreshClaims)["user_id"] == (*claims)["user_id"] {
+			expiresAt := tokenn.ExpiresAt(*refreshClaims, time.Now().Add(h.Tokens.RefreshTTL))
+			if _, err := h.Revoked.Revoke(ctx, tokenn.TokenID(*refreshClaims, req.RefreshToken), expiresAt); err != nil {
+				h.Logger.Error("Error revoking refresh token: ", "error", err)
+				apierror.Write(ctx, http.StatusInternalServerError, "")
+				return
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, models.Success{Message: "Logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Security ApiKeyAuth
+// @Summary Sign out everywhere
+// @Description Revokes every access and refresh token of the caller issued so far
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 401 {object} models.ErrorResponse "Invalid token provided"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/auth/logout-all [post]
+func (h *Handler) LogoutAll(ctx *gin.Context) {
+	accessToken := ctx.GetHeader("Authorization")
+	claims, err := tokenn.ExtractAccessClaim(h.Verifier, accessToken)
+	if err != nil {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid token provided")
+		return
+	}
+	principal, err := tokenn.PrincipalFromClaims(*claims, accessToken)
+	if err != nil {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid token provided")
+		return
+	}
+
+	revoked, err := tokenn.IsTokenRevoked(ctx, h.Revoked, *claims, accessToken, h.Tokens.MaxTTL())
+	if err != nil {
+		h.Logger.Error("Error checking token revocation: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if revoked {
+		apierror.Write(ctx, http.StatusUnauthorized, "Invalid token provided")
+		return
+	}
+
+	now := time.Now()
+	if err := h.Revoked.RevokeUser(ctx, principal.UserID, now, now.Add(h.Tokens.MaxTTL())); err != nil {
+		h.Logger.Error("Error revoking user tokens: ", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.Success{Message: "Signed out from all sessions"})
+}
+
 // GetUserProfile godoc
 // @Security ApiKeyAuth
 // @Summary Get user profile
@@ -16,21 +302,19 @@ import (
 // @Tags User
 // @Param id path string true "User ID"
 // @Success 200 {object} models.GetProfileRes "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/user/profile/{id} [get]
 func (h *Handler) GetUserProfile(ctx *gin.Context) {
-    userID, exists := ctx.Get("user_id")
-    if !exists {
-        ctx.JSON(http.StatusUnauthorized, models.Error{Message: "User ID not found in token"})
-        return
-    }
+	id := ctx.Param("id")
+	if !h.authorizeOwner(ctx, id) {
+		return
+	}
 
-    // Convert to string
-    id := userID.(string)
     resp, err := h.User.GetUserProfile(ctx, &user.GetProfileReq{UserId: id})
     if err != nil {
         h.Logger.Error("Error getting user profile: ", "error", err)
-        ctx.JSON(500, models.Error{Message: "Internal server error"})
+        apierror.GRPC(ctx, err)
         return
     }
 
@@ -47,16 +331,20 @@ func (h *Handler) GetUserProfile(ctx *gin.Context) {
 // @Param id path string true "User ID"
 // @Param body body models.UpdateProfileReq true "Request body for updating user profile"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/user/updateUser/{id} [put]
 func (h *Handler) UpdateUser(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !h.authorizeOwner(ctx, id) {
+		return
+	}
 	var userUpdate models.UpdateProfileReq
 
 	if err := ctx.ShouldBindJSON(&userUpdate); err!= nil {
         h.Logger.Error("Error binding JSON: ", "error", err)
-        ctx.JSON(400, models.Error{Message: "Invalid request parameters"})
+        apierror.Bind(ctx, err)
         return
     }
 
@@ -70,7 +358,7 @@ func (h *Handler) UpdateUser(ctx *gin.Context) {
 	})
 	if err!= nil {
         h.Logger.Error("Error updating user: ", "error", err)
-        ctx.JSON(500, models.Error{Message: "Internal server error"})
+        apierror.GRPC(ctx, err)
         return
     }
 
@@ -85,21 +373,21 @@ func (h *Handler) UpdateUser(ctx *gin.Context) {
 // @Tags User
 // @Param email path string true "User Email"
 // @Success 200 {object} user.FilterUsers "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/user/email/{email} [get]
 func (h *Handler) GetUserByEmail(ctx *gin.Context) {
 	email := ctx.Param("email")
-	fmt.Println(email)
     resp, err := h.User.GetUSerByEmail(ctx, &user.GetUSerByEmailReq{
         Email: email,
     })
-	fmt.Println(err)
-	
     if err!= nil {
-        h.Logger.Error("Error getting user by email: ", "error", err)
-        ctx.JSON(500, models.Error{Message: "Internal server error"})
+        h.lookupFailed(ctx, err)
         return
     }
+	if !h.authorizeOwner(ctx, resp.UserId) {
+		return
+	}
 
     ctx.JSON(http.StatusOK, resp)
 }
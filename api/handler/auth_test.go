@@ -0,0 +1,137 @@
+package handler
+
+import (
+	tokenn "api-gateway/api/token"
+	"api-gateway/genproto/user"
+	"api-gateway/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// loginUsers logs every caller in as u1, handing out untyped tokens the way
+// the auth service does, and records the roles it registers.
+type loginUsers struct {
+	fakeUsers
+	registered []string
+}
+
+func (f *loginUsers) Register(ctx context.Context, in *user.RegisterReq, opts ...grpc.CallOption) (*user.RegisterRes, error) {
+	f.registered = append(f.registered, in.Role)
+	return &user.RegisterRes{UserId: "new"}, nil
+}
+
+func (f *loginUsers) Login(ctx context.Context, in *user.LoginReq, opts ...grpc.CallOption) (*user.LoginRes, error) {
+	return &user.LoginRes{UserId: "u1", Role: "patient", Accestoken: "auth-service-access", Refreshtoken: "auth-service-refresh"}, nil
+}
+
+func authRouter(env *gatewayEnv) *gin.Engine {
+	env.handler.User = &loginUsers{}
+	router := gin.New()
+	router.POST("/api/auth/register", env.handler.Register)
+	router.POST("/api/auth/login", env.handler.Login)
+	router.POST("/api/auth/refresh", env.handler.RefreshToken)
+	return router
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginIssuesGatewayTokens(t *testing.T) {
+	env := newGatewayEnv(t)
+	router := authRouter(env)
+
+	rec := postJSON(router, "/api/auth/login", `{"email":"a@b.c","password":"secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body)
+	}
+	var res models.LoginRes
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokenn.ExtractAccessClaim(env.handler.Verifier, res.AccessToken); err != nil {
+		t.Errorf("access token from login: %v", err)
+	}
+
+	rec = postJSON(router, "/api/auth/refresh", `{"refresh_token":"`+res.RefreshToken+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("refresh with the login refresh token: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRefreshRejectsOtherTokens(t *testing.T) {
+	env := newGatewayEnv(t)
+	router := authRouter(env)
+	pair, err := env.tokens.GenerateTokens("u1", "patient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u1", "role": "patient"}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"access token": pair.AccessToken, "untyped token": untyped} {
+		rec := postJSON(router, "/api/auth/refresh", `{"refresh_token":"`+token+`"}`)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", name, rec.Code)
+		}
+	}
+}
+
+func TestRefreshTokenCantCallAPI(t *testing.T) {
+	env := newGatewayEnv(t)
+	pair, err := env.tokens.GenerateTokens("u1", "patient")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/any", nil)
+	req.Header.Set("Authorization", pair.RefreshToken)
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", rec.Code)
+	}
+}
+
+const registration = `{"email":"a@b.c","password":"secret123","first_name":"A","last_name":"B","date_of_birth":"1990-01-02","gender":"f","role":"doctor"}`
+
+func TestRegisterIsAlwaysPatient(t *testing.T) {
+	env := newGatewayEnv(t)
+	router := authRouter(env)
+
+	rec := postJSON(router, "/api/auth/register", registration)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := env.handler.User.(*loginUsers).registered; len(got) != 1 || got[0] != "patient" {
+		t.Errorf("registered %v, want a patient whatever the body says", got)
+	}
+}
+
+func TestRegisterDoctor(t *testing.T) {
+	env := newGatewayEnv(t)
+	authRouter(env)
+	env.router.POST("/api/admin/doctors", env.handler.RegisterDoctor)
+
+	rec := env.do(t, http.MethodPost, "/api/admin/doctors", "admin1", strings.Replace(registration, `,"role":"doctor"`, "", 1))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := env.handler.User.(*loginUsers).registered; len(got) != 1 || got[0] != "doctor" {
+		t.Errorf("registered %v, want a doctor", got)
+	}
+}
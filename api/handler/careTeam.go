@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/genproto/user"
+	"api-gateway/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GrantCareTeam godoc
+// @Security ApiKeyAuth
+// @Summary Add a doctor to the care team
+// @Description Lets the given doctor access the caller's medical reports, lifestyle and wearable data
+// @Tags CareTeam
+// @Accept json
+// @Produce json
+// @Param body body models.GrantCareTeamReq true "Doctor to grant access to"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Doctor not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/careTeam/grant [post]
+func (h *Handler) GrantCareTeam(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.GrantCareTeamReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+	if req.DoctorId == principal.UserID {
+		apierror.Write(ctx, http.StatusBadRequest, "You can't add yourself to your care team")
+		return
+	}
+
+	doctor, err := h.User.GetUserById(ctx, &user.UserId{UserId: req.DoctorId})
+	if err != nil {
+		h.lookupFailed(ctx, err)
+		return
+	}
+	if doctor.Role != "doctor" {
+		apierror.Write(ctx, http.StatusNotFound, "Not found")
+		return
+	}
+
+	added, err := h.Enforcer.AddNamedGroupingPolicy(careTeamPtype, req.DoctorId, principal.UserID)
+	if err != nil {
+		h.Logger.Error("Error adding care team link", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	// granting again changes nothing, so only a new link is audited
+	if added {
+		h.audit(ctx, "add", careTeamPtype, []string{req.DoctorId, principal.UserID})
+	}
+
+	h.Logger.Info("Care team access granted", "patient_id", principal.UserID, "doctor_id", req.DoctorId)
+	ctx.JSON(http.StatusOK, models.Success{Message: "Doctor added to care team"})
+}
+
+// GetCareTeam godoc
+// @Security ApiKeyAuth
+// @Summary List care team links
+// @Description Returns the doctors the caller has granted access to and the patients who granted the caller access
+// @Tags CareTeam
+// @Produce json
+// @Success 200 {object} models.CareTeam "Successful operation"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/careTeam/list [get]
+func (h *Handler) GetCareTeam(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+
+	team := models.CareTeam{Doctors: []string{}, Patients: []string{}}
+
+	doctors, err := h.Enforcer.GetFilteredNamedGroupingPolicy(careTeamPtype, 1, principal.UserID)
+	if err != nil {
+		h.Logger.Error("Error listing care team", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	for _, link := range doctors {
+		team.Doctors = append(team.Doctors, link[0])
+	}
+
+	patients, err := h.Enforcer.GetFilteredNamedGroupingPolicy(careTeamPtype, 0, principal.UserID)
+	if err != nil {
+		h.Logger.Error("Error listing care team", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	for _, link := range patients {
+		team.Patients = append(team.Patients, link[1])
+	}
+
+	ctx.JSON(http.StatusOK, team)
+}
+
+// RevokeCareTeam godoc
+// @Security ApiKeyAuth
+// @Summary Remove a doctor from the care team
+// @Description Takes away the doctor's access to the caller's data
+// @Tags CareTeam
+// @Produce json
+// @Param doctor_id path string true "Doctor ID"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 404 {object} models.ErrorResponse "Doctor is not in the care team"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/careTeam/revoke/{doctor_id} [delete]
+func (h *Handler) RevokeCareTeam(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+	doctorID := ctx.Param("doctor_id")
+
+	removed, err := h.Enforcer.RemoveNamedGroupingPolicy(careTeamPtype, doctorID, principal.UserID)
+	if err != nil {
+		h.Logger.Error("Error removing care team link", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !removed {
+		apierror.Write(ctx, http.StatusNotFound, "Not found")
+		return
+	}
+	h.audit(ctx, "remove", careTeamPtype, []string{doctorID, principal.UserID})
+
+	h.Logger.Info("Care team access revoked", "patient_id", principal.UserID, "doctor_id", doctorID)
+	ctx.JSON(http.StatusOK, models.Success{Message: "Doctor removed from care team"})
+}
@@ -1,10 +1,29 @@
 package handler
 
 import (
+	"api-gateway/alert"
+	"api-gateway/api/apierror"
+	"api-gateway/api/stream"
+	middleware "api-gateway/api/middlerware"
+	"api-gateway/api/ratelimit"
+	"api-gateway/api/readiness"
+	tokenn "api-gateway/api/token"
+	policy "api-gateway/casbin"
 	"api-gateway/genproto/health"
 	"api-gateway/genproto/user"
+	"api-gateway/kafka/event"
+	"api-gateway/kafka/job"
+	"api-gateway/kafka/outbox"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
 	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
 )
 
 type Handler struct {
@@ -15,16 +34,137 @@ type Handler struct {
 	Wearable health.WearableClient
 	Logger *slog.Logger
 	Enforcer *casbin.Enforcer
+	Tokens *tokenn.Issuer
+	Verifier tokenn.Verifier
+	Revoked tokenn.RevocationStore
+	Audit policy.AuditLog
+	Producer kafka.ProducerIkafka
+	Outbox outbox.Store
+	Events event.Encoder
+	Jobs job.Store
+	// ReplyTopic is where backend services report finished jobs.
+	ReplyTopic string
+	Streams Streams
+	Bulk BulkLimits
+	Alerts Alerts
+	// Limiter is nil when requests are not rate limited.
+	Limiter *ratelimit.Limiter
+	Readiness *readiness.Checker
+}
+
+// Streams are the live feeds served as server-sent events.
+type Streams struct {
+	Notifications *stream.Hub
+	// Vitals is keyed by patient, so its connection limit is per patient.
+	Vitals *stream.Hub
+	// Heartbeat is how often an idle stream sends a keep-alive comment.
+	Heartbeat time.Duration
 }
 
-func NewHandler(user user.UsersClient, healthClient health.HealthCheckClient, lifeStyleClient health.LifeStyleClient, medicalRecordClient health.MedicalRecordClient, wearableClient health.WearableClient, logger *slog.Logger, Enforcer *casbin.Enforcer) *Handler {
-	return &Handler{
-        User:         user,
-        Health:  healthClient,
-        Lifestyle: lifeStyleClient,
-        Mecdical: medicalRecordClient,
-        Wearable: wearableClient,
-        Logger:        logger,
-		Enforcer: Enforcer,
-    }
-}
\ No newline at end of file
+// Alerts holds the alert rules and the engine that checks readings
+// against them.
+type Alerts struct {
+	Rules  alert.Store
+	Engine *alert.Engine
+}
+
+// principal returns the authenticated caller of the request. It answers 401
+// itself when there is none, so handlers only need to return.
+func (h *Handler) principal(ctx *gin.Context) (*tokenn.Principal, bool) {
+	p, ok := middleware.GetPrincipal(ctx)
+	if !ok {
+		h.Logger.Error("Principal not found in context", "path", ctx.FullPath())
+		apierror.Write(ctx, http.StatusUnauthorized, "User ID not found in token")
+		return nil, false
+	}
+	return p, true
+}
+
+// enqueueFollowUp wraps payload in the topic's event envelope and writes it
+// to the outbox, keyed so one user's events stay in order. It is for events
+// about a change a backend already made, so a failure is logged rather than
+// answered: failing the request would get the change made again by a retry.
+func (h *Handler) enqueueFollowUp(ctx *gin.Context, topic, key string, payload proto.Message) {
+	msg, err := h.Events.Encode(topic, payload, h.eventMeta(ctx, key, ""))
+	if err == nil {
+		_, err = h.Outbox.Enqueue(ctx.Request.Context(), msg)
+	}
+	if err != nil {
+		h.Logger.Error("Error writing follow-up event to outbox", "topic", topic, "key", key, "error", err)
+	}
+}
+
+// startJob wraps the event in its envelope, writes it to the outbox and
+// tracks it as a job of the caller. It answers errors itself. Answer with
+// jobAccepted once the rest of the request is done.
+func (h *Handler) startJob(ctx *gin.Context, topic, key string, payload proto.Message) (string, bool) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return "", false
+	}
+	msg, ok := h.encodeEvent(ctx, topic, payload, h.eventMeta(ctx, key, h.ReplyTopic))
+	if !ok {
+		return "", false
+	}
+
+	id := msg.Headers[event.HeaderID]
+	err := h.Jobs.Create(ctx.Request.Context(), job.Job{ID: id, Type: msg.Headers[event.HeaderType], UserID: principal.UserID})
+	if err != nil {
+		h.Logger.Error("Error creating job", "topic", topic, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return "", false
+	}
+	if !h.enqueue(ctx, msg) {
+		if _, err := h.Jobs.Complete(ctx.Request.Context(), id, job.StatusFailed, "event could not be queued"); err != nil {
+			h.Logger.Error("Error failing job", "job_id", id, "error", err)
+		}
+		return "", false
+	}
+	return id, true
+}
+
+// jobAccepted answers 202 Accepted with the job's status URL.
+func (h *Handler) jobAccepted(ctx *gin.Context, id string) {
+	location := "/api/jobs/" + id
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusAccepted, models.JobAccepted{JobID: id, Status: job.StatusPending, Location: location})
+}
+
+// eventMeta describes an event raised by the request.
+func (h *Handler) eventMeta(ctx *gin.Context, key, replyTo string) event.Meta {
+	return event.Meta{
+		Key:       key,
+		RequestID: apierror.RequestID(ctx),
+		TraceID:   traceID(ctx.GetHeader("traceparent")),
+		ReplyTo:   replyTo,
+	}
+}
+
+func (h *Handler) encodeEvent(ctx *gin.Context, topic string, payload proto.Message, meta event.Meta) (kafka.Message, bool) {
+	msg, err := h.Events.Encode(topic, payload, meta)
+	if err != nil {
+		h.Logger.Error("Error encoding event", "topic", topic, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return kafka.Message{}, false
+	}
+	return msg, true
+}
+
+func (h *Handler) enqueue(ctx *gin.Context, msg kafka.Message) bool {
+	if _, err := h.Outbox.Enqueue(ctx.Request.Context(), msg); err != nil {
+		h.Logger.Error("Error writing event to outbox", "topic", msg.Topic, "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return false
+	}
+	return true
+}
+
+// traceID takes the trace id out of a W3C traceparent header
+// ("00-<trace id>-<span id>-<flags>"), or returns "" when there is none.
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	return parts[1]
+}
@@ -1,11 +1,9 @@
 package handler
 
 import (
+	"api-gateway/api/apierror"
 	"api-gateway/genproto/health"
 	"api-gateway/genproto/user"
-	kafka "api-gateway/kafka/producer"
-	"api-gateway/models"
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -20,54 +18,40 @@ import (
 // @Accept json
 // @Produce json
 // @Param body body health.GenerateHealthRecommendationsReq true "Request body for generating health recommendations"
-// @Success 200 {object} map[string]string "Successful operation"
-// @Failure 400 {object} map[string]string "Invalid data"
-// @Failure 500 {object} map[string]string "Server error"
+// @Success 202 {object} models.JobAccepted "Accepted; poll the Location header for the outcome"
+// @Header 202 {string} Location "Job status URL"
+// @Failure 400 {object} models.ErrorResponse "Invalid data"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 429 {object} models.ErrorResponse "Rate limit or daily quota exceeded"
+// @Failure 500 {object} models.ErrorResponse "Server error"
 // @Router /api/health/generate [post]
 func (h *Handler) GenerateHealthRecommendations(c *gin.Context) {
 	h.Logger.Info("GenerateHealthRecommendations called")
 
 	var req health.GenerateHealthRecommendationsReq
-	if err := c.BindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		h.Logger.Error("Invalid request body", "error", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		apierror.Bind(c, err)
 		return
 	}
-
-	writerKafka, err := kafka.NewKafkaProducerInit([]string{"kafka:9092"})
-	if err != nil {
-		h.Logger.Error("Error initializing Kafka producer", "error", err)
-		c.JSON(http.StatusInternalServerError, err.Error())
+	if !h.authorizeOwner(c, req.UserId) {
 		return
 	}
-	defer writerKafka.Close()
 
-	msgBytes, err := json.Marshal(&req)
-	if err != nil {
-		h.Logger.Error("Error marshaling request to JSON", "error", err)
-		c.JSON(http.StatusInternalServerError, err.Error())
+	id, ok := h.startJob(c, "health", req.UserId, &req)
+	if !ok {
 		return
 	}
+	message := fmt.Sprintf("Generated health recommendations for user %s Description : %s, Priority : %d, RecommendationType : %s, ", req.UserId, req.Description, req.Priority, req.RecommendationType)
 
-	err = writerKafka.Producermessage("health", msgBytes)
-	if err != nil {
-		h.Logger.Error("Error producing Kafka message", "error", err)
-		c.JSON(http.StatusInternalServerError, err.Error())
-		return
+	if _, err := h.User.CreateNotifications(c, &user.CreateNotificationsReq{UserId: req.UserId, Message: message}); err != nil {
+		// the job is queued and will run; failing now would only get it
+		// started again by a retry
+		h.Logger.Error("Error creating notifications", "job_id", id, "error", err)
 	}
-	message := fmt.Sprintf("Generated health recommendations for user %s Description : %s, Priority : %d, RecommendationType : %s, ", req.UserId, req.Description, req.Priority, req.RecommendationType)
 
-	fmt.Println(req.UserId)
-	resp, err := h.User.CreateNotifications(c, &user.CreateNotificationsReq{UserId: req.UserId, Message: message})
-	if err!= nil {
-        h.Logger.Error("Error creating notifications: ", "error", err)
-        c.JSON(http.StatusInternalServerError, err.Error())
-        return
-    }
-	fmt.Println(resp.Id)
-
-	h.Logger.Info("GenerateHealthRecommendations finished successfully")
-	c.JSON(http.StatusOK, models.Success{Message: "Health recommendations generated successfully ",})
+	h.Logger.Info("GenerateHealthRecommendations accepted", "job_id", id)
+	h.jobAccepted(c, id)
 }
 
 // GetRealtimeHealthMonitoring godoc
@@ -77,25 +61,22 @@ func (h *Handler) GenerateHealthRecommendations(c *gin.Context) {
 // @Tags HealthCheck
 // @Accept       json
 // @Produce      json
+// @Param user_id path string true "User ID"
 // @Success 200 {object} models.GetRealtimeHealthMonitoringRes "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/health/getRealtimeHealthMonitoring/{user_id} [get]
 func (h *Handler) GetRealtimeHealthMonitoring(ctx *gin.Context) {
-	userId, exists := ctx.Get("user_id")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		ctx.JSON(http.StatusBadRequest, models.Error{Message: "User ID not found in context"})
+	id := ctx.Param("user_id")
+	if !h.authorizeOwner(ctx, id) {
 		return
 	}
-	id := userId.(string)
-	fmt.Println(id)
-
 
 	resp, err := h.Health.GetRealtimeHealthMonitoring(ctx, &health.GetRealtimeHealthMonitoringReq{UserId: id})
 	if err != nil {
 		h.Logger.Error("Error getting user profile: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -110,28 +91,16 @@ func (h *Handler) GetRealtimeHealthMonitoring(ctx *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param date query string true "Date in format YYYY-MM-DD"
+// @Param user_id query string false "Patient ID, defaults to the caller"
 // @Success 200 {object} health.GetDailyHealthSummaryRes "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/health/getDailyHealthSummary/{date} [get]
 func (h *Handler) GetDailyHealthSummary(ctx *gin.Context) {
-	userId, exists := ctx.Get("user_id")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		ctx.JSON(http.StatusBadRequest, models.Error{Message: "User ID not found in context"})
+	id, ok := h.targetUser(ctx)
+	if !ok {
 		return
 	}
-	id := userId.(string)
-	
-	// user, err := h.User.GetUserById(ctx, &user.UserId{UserId: id})
-	// fmt.Println(id)
-	// fmt.Println("Qara")
-	// fmt.Println(user)
-	// if err != nil {
-	// 	h.Logger.Error("Error getting user profile", "error", err)
-	// 	ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-	// 	return
-	// }
 
 	date := ctx.Query("date")
 
@@ -141,7 +110,7 @@ func (h *Handler) GetDailyHealthSummary(ctx *gin.Context) {
 	})
 	if err != nil {
 		h.Logger.Error("Error getting daily health summary", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -157,24 +126,21 @@ func (h *Handler) GetDailyHealthSummary(ctx *gin.Context) {
 // @Produce      json
 // @Param start_date query string true "Date in format YYYY-MM-DD"
 // @Param end_date query string true "Date in format YYYY-MM-DD"
+// @Param user_id query string false "Patient ID, defaults to the caller"
 // @Success 200 {object} health.GetWeeklyHealthSummaryRes "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/health/getWeeklyHealthSummary/{start_date}/{end_date} [get]
 func (h *Handler) GetWeeklyHealthSummary(ctx *gin.Context) {
-	userId, exists := ctx.Get("user_id")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		ctx.JSON(http.StatusBadRequest, models.Error{Message: "User ID not found in context"})
+	id, ok := h.targetUser(ctx)
+	if !ok {
 		return
 	}
-	id := userId.(string)
-	fmt.Println(id)
 
 	user, err := h.User.GetUserById(ctx, &user.UserId{UserId: id})
 	if err != nil {
 		h.Logger.Error("Error getting user profile: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -190,7 +156,75 @@ func (h *Handler) GetWeeklyHealthSummary(ctx *gin.Context) {
 		EndDate:   enddate})
 	if err != nil {
 		h.Logger.Error("Error getting user profile: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// AddRealTimeData godoc
+// @Security ApiKeyAuth
+// @Summary Add real-time health data
+// @Description Records a real-time reading or recommendation for a user
+// @Tags HealthCheck
+// @Accept json
+// @Produce json
+// @Param body body health.AddRealTimeDataReq true "Real-time data"
+// @Success 200 {object} health.AddRealTimeDataRes "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/health/realtime [post]
+func (h *Handler) AddRealTimeData(ctx *gin.Context) {
+	var req health.AddRealTimeDataReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+	if req.UserId == "" {
+		principal, ok := h.principal(ctx)
+		if !ok {
+			return
+		}
+		req.UserId = principal.UserID
+	}
+	if !h.authorizeOwner(ctx, req.UserId) {
+		return
+	}
+
+	resp, err := h.Health.AddRealTimeData(ctx, &req)
+	if err != nil {
+		h.Logger.Error("Error adding real-time data", "error", err)
+		apierror.GRPC(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetRealTimeData godoc
+// @Security ApiKeyAuth
+// @Summary Get real-time health data
+// @Description Retrieves the latest real-time data of a user
+// @Tags HealthCheck
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} health.GetRealTimeDataRes "Successful operation"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/health/realtime/{user_id} [get]
+func (h *Handler) GetRealTimeData(ctx *gin.Context) {
+	id := ctx.Param("user_id")
+	if !h.authorizeOwner(ctx, id) {
+		return
+	}
+
+	resp, err := h.Health.GetRealTimeData(ctx, &health.GetRealTimeDataReq{UserId: id})
+	if err != nil {
+		h.Logger.Error("Error getting real-time data", "error", err)
+		apierror.GRPC(ctx, err)
 		return
 	}
 
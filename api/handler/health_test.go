@@ -0,0 +1,275 @@
+package handler
+
+import (
+	middleware "api-gateway/api/middlerware"
+	tokenn "api-gateway/api/token"
+	"api-gateway/genproto/user"
+	"api-gateway/kafka/consumer"
+	"api-gateway/kafka/event"
+	"api-gateway/kafka/job"
+	"api-gateway/kafka/outbox"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testReplyTopic = "job-reply"
+
+// fakeUsers answers CreateNotifications with err and counts the calls.
+type fakeUsers struct {
+	user.UsersClient
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (f *fakeUsers) CreateNotifications(ctx context.Context, in *user.CreateNotificationsReq, opts ...grpc.CallOption) (*user.CreateNotificationsRes, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &user.CreateNotificationsRes{}, nil
+}
+
+// gatewayEnv is the gateway with its job pipeline in memory: the outbox is
+// relayed to a MemoryBroker and replies settle jobs in the memory store.
+type gatewayEnv struct {
+	router  *gin.Engine
+	handler *Handler
+	users   *fakeUsers
+	events  outbox.Store
+	jobs    job.Store
+	broker  *consumer.MemoryBroker
+	relay   *outbox.Relay
+	tokens  *tokenn.Issuer
+}
+
+func newGatewayEnv(t *testing.T) *gatewayEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tokens, err := tokenn.NewIssuer(tokenn.IssuerConfig{AccessTTL: time.Hour, RefreshTTL: time.Hour, Algorithm: "HS256", HMACSecret: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := tokenn.NewVerifier(tokenn.VerifierConfig{Algorithms: []string{"HS256"}, HMACSecret: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := casbin.NewEnforcer("../../casbin/model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &gatewayEnv{
+		users:  &fakeUsers{},
+		events: outbox.NewMemoryStore(),
+		jobs:   job.NewMemoryStore(),
+		broker: consumer.NewMemoryBroker(),
+		tokens: tokens,
+	}
+	env.relay = outbox.NewRelay(env.events, env.broker, logger, outbox.RelayConfig{})
+	t.Cleanup(func() { env.broker.Close() })
+
+	revoked := tokenn.NewMemoryStore()
+	h := &Handler{
+		User:       env.users,
+		Tokens:     tokens,
+		Verifier:   verifier,
+		Revoked:    revoked,
+		Logger:     logger,
+		Enforcer:   enforcer,
+		Outbox:     env.events,
+		Events:     event.Encoder{Producer: "api-gateway", Encoding: event.EncodingJSON},
+		Jobs:       env.jobs,
+		ReplyTopic: testReplyTopic,
+	}
+	env.handler = h
+	env.router = gin.New()
+	env.router.Use(middleware.CheckMiddleware(verifier, revoked, tokens.MaxTTL()))
+	env.router.POST("/api/health/generate", h.GenerateHealthRecommendations)
+	env.router.GET("/api/jobs/:id", h.GetJob)
+	return env
+}
+
+// consume runs handle on every message of topic until the test ends.
+func (env *gatewayEnv) consume(t *testing.T, topic string, handle consumer.Handler) {
+	t.Helper()
+	c := env.broker.Consumer(topic)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Consume(ctx, handle)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		c.Close()
+	})
+}
+
+func (env *gatewayEnv) do(t *testing.T, method, path, userID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	pair, err := env.tokens.GenerateTokens(userID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", pair.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (env *gatewayEnv) generate(t *testing.T, userID string) models.JobAccepted {
+	t.Helper()
+	rec := env.do(t, http.MethodPost, "/api/health/generate", userID, `{"user_id":"`+userID+`","recommendation_type":"diet","priority":1}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("generate: status %d, want 202: %s", rec.Code, rec.Body)
+	}
+	var accepted models.JobAccepted
+	if err := json.Unmarshal(rec.Body.Bytes(), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.JobID == "" || accepted.Status != job.StatusPending {
+		t.Fatalf("generate: %+v, want a pending job", accepted)
+	}
+	if got := rec.Header().Get("Location"); got != "/api/jobs/"+accepted.JobID || accepted.Location != got {
+		t.Errorf("Location %q, body location %q, want /api/jobs/%s", got, accepted.Location, accepted.JobID)
+	}
+	return accepted
+}
+
+func (env *gatewayEnv) jobStatus(t *testing.T, location, userID string) job.Job {
+	t.Helper()
+	rec := env.do(t, http.MethodGet, location, userID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("job status: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var j job.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestGenerateHealthRecommendationsJob(t *testing.T) {
+	for _, outcome := range []string{job.StatusSucceeded, job.StatusFailed} {
+		t.Run(outcome, func(t *testing.T) {
+			env := newGatewayEnv(t)
+			env.consume(t, testReplyTopic, job.ReplyHandler(env.jobs, slog.New(slog.NewTextHandler(io.Discard, nil))))
+			// the backend reports every job it gets as done with outcome
+			env.consume(t, "health", func(ctx context.Context, msg kafka.Message) error {
+				reply, _ := json.Marshal(job.Reply{JobID: msg.Headers[event.HeaderID], Status: outcome})
+				return env.broker.Publish(ctx, kafka.Message{Topic: msg.Headers[event.HeaderReplyTo], Value: reply})
+			})
+
+			accepted := env.generate(t, "u1")
+			if j := env.jobStatus(t, accepted.Location, "u1"); j.Status != job.StatusPending || j.Type != "health.recommendation.requested" {
+				t.Fatalf("before the reply: %+v, want a pending health job", j)
+			}
+
+			if err := env.relay.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				j := env.jobStatus(t, accepted.Location, "u1")
+				if j.Status == outcome {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("job still %s, want %s", j.Status, outcome)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestGenerateHealthRecommendationsNotificationFails(t *testing.T) {
+	env := newGatewayEnv(t)
+	env.users.err = status.Error(codes.Unavailable, "user service down")
+
+	accepted := env.generate(t, "u1")
+	if env.users.calls != 1 {
+		t.Errorf("notification calls %d, want 1", env.users.calls)
+	}
+	if j := env.jobStatus(t, accepted.Location, "u1"); j.Status != job.StatusPending {
+		t.Errorf("job %s, want pending", j.Status)
+	}
+	queued, err := env.events.List(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].Headers[event.HeaderID] != accepted.JobID {
+		t.Errorf("outbox holds %d events, want the job's event", len(queued))
+	}
+}
+
+func TestGetJobOfAnotherUser(t *testing.T) {
+	env := newGatewayEnv(t)
+	accepted := env.generate(t, "u1")
+
+	if rec := env.do(t, http.MethodGet, accepted.Location, "u2", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("other user: status %d, want 404", rec.Code)
+	}
+	if rec := env.do(t, http.MethodGet, "/api/jobs/unknown", "u1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job: status %d, want 404", rec.Code)
+	}
+}
+
+func TestGenerateHealthRecommendationsOfAnotherUser(t *testing.T) {
+	env := newGatewayEnv(t)
+
+	rec := env.do(t, http.MethodPost, "/api/health/generate", "u2", `{"user_id":"u1"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+	queued, err := env.events.List(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 0 || env.users.calls != 0 {
+		t.Errorf("queued %d events and sent %d notifications, want none", len(queued), env.users.calls)
+	}
+}
+
+func TestGenerateHealthRecommendationsInvalidBody(t *testing.T) {
+	env := newGatewayEnv(t)
+
+	rec := env.do(t, http.MethodPost, "/api/health/generate", "u1", `{"user_id":`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("Content-Type %q, want JSON", got)
+	}
+	var res models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("body %q is not an error response: %v", rec.Body, err)
+	}
+	if res.Code == "" || res.Message == "" {
+		t.Errorf("error response %+v, want a code and a message", res)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJob godoc
+// @Security ApiKeyAuth
+// @Summary Get job status
+// @Description Reports whether a request answered with 202 Accepted is pending, succeeded, failed or expired without a reply. Finished jobs are purged after a while and are then not found
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} job.Job "Successful operation"
+// @Failure 404 {object} models.ErrorResponse "Job not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/jobs/{id} [get]
+func (h *Handler) GetJob(ctx *gin.Context) {
+	j, found, err := h.Jobs.Get(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		h.Logger.Error("Error getting job", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !found {
+		apierror.Write(ctx, http.StatusNotFound, "Job not found")
+		return
+	}
+	if !h.authorizeOwner(ctx, j.UserID) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, j)
+}
@@ -1,11 +1,10 @@
 package handler
 
 import (
-	tokenn "api-gateway/api/token"
+	"api-gateway/api/apierror"
 	"api-gateway/genproto/health"
 	"api-gateway/genproto/user"
 	"api-gateway/models"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,36 +13,38 @@ import (
 // AddLifeStyleData godoc
 // @Security ApiKeyAuth
 // @Summary Add lifestyle data
-// @Description Adds lifestyle data for a user
+// @Description Adds lifestyle data for a user. data_type must be one of GET /api/measurements; data_value may name its unit and is stored in the canonical one.
 // @Tags Lifestyle
 // @Accept       json
 // @Produce      json
 // @Param body body health.AddLifeStyleDataReq true "Request body for adding lifestyle data"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/lifestyle/addLifestyleData [post]
 func (h *Handler) AddLifeStyleData(ctx *gin.Context) {
-	accessToken := ctx.GetHeader("Authorization")
-	id, _, err := tokenn.GetUserInfoFromAccessToken(accessToken)
-	if err != nil {
-		fmt.Println("ok")
-		h.Logger.Error(err.Error())
-		ctx.JSON(http.StatusBadRequest, models.Error{Message: "Invalid access token"})
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
 	}
+	id := principal.UserID
 
 	var life health.AddLifeStyleDataReq
 
 	if err := ctx.ShouldBindJSON(&life); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: err.Error()})
+		apierror.Bind(ctx, err)
+		return
+	}
+	if errs := normalizeMeasurement(life.DataType, &life.DataValue); len(errs) > 0 {
+		measurementInvalid(ctx, errs)
 		return
 	}
 
 	resp, err := h.Lifestyle.AddLifeStyleData(ctx, &health.AddLifeStyleDataReq{UserId: id, DataType: life.DataType, DataValue: life.DataValue})
 	if err != nil {
 		h.Logger.Error("Error Adding user life Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -58,37 +59,30 @@ func (h *Handler) AddLifeStyleData(ctx *gin.Context) {
 // @Tags Lifestyle
 // @Accept       json
 // @Produce      json
+// @Param user_id query string false "Patient ID, defaults to the caller"
 // @Success 200 {object} models.GetLifeStyle "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/lifestyle/getAllLifestyleData [get]
 func (h *Handler) GetLifeStyleData(ctx *gin.Context) {
-	userID, exists := ctx.Get("user_id")
-    if !exists {
-		ctx.JSON(http.StatusUnauthorized, models.Error{Message: "User ID not found in token"})
-        return
-    }
-	
-	fmt.Println("Ok", userID)
-    id := userID.(string)
+	id, ok := h.targetUser(ctx)
+	if !ok {
+		return
+	}
 	user, err := h.User.GetUserById(ctx, &user.UserId{UserId: id})
 	if err!= nil {
         h.Logger.Error("Error getting user profile: ", "error", err)
-        ctx.JSON(500, models.Error{Message: err.Error()})
+        apierror.GRPC(ctx, err)
         return
     }
-	fmt.Println(user)
 
 	resp, err := h.Lifestyle.GetLifeStyleData(ctx, &health.GetLifeStyleDataReq{
 		UserId: id,
 		FirstName: user.FirstName,
 	    LastName: user.LastName,
     })
-
-	fmt.Println(resp)
-
 	if err != nil {
 		h.Logger.Error("Error Get user life Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -104,19 +98,21 @@ func (h *Handler) GetLifeStyleData(ctx *gin.Context) {
 // @Produce      json
 // @Param id path string true "Data ID"
 // @Success 200 {object} health.GetLifeStyleDataByIdRes "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Lifestyle data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/lifestyle/getLifestyleById/{id} [get]
 func (h *Handler) GetLifeStyleDataById(ctx *gin.Context) {
 	id := ctx.Param("id")
-	fmt.Println("sadhfuyieudsc", id)
 
 	resp, err := h.Lifestyle.GetLifeStyleDataById(ctx, &health.GetLifeStyleDataByIdReq{Id: id})
 	if err != nil {
 		h.Logger.Error("Error Get user life Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		h.lookupFailed(ctx, err)
+		return
+	}
+	if !h.authorizeOwner(ctx, resp.GetLifeStyle().GetUserId()) {
 		return
 	}
-	fmt.Println(resp)
 
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -125,28 +121,36 @@ func (h *Handler) GetLifeStyleDataById(ctx *gin.Context) {
 // UpdateLifeStyleData godoc
 // @Security ApiKeyAuth
 // @Summary Update lifestyle data
-// @Description Updates a specific lifestyle data entry
+// @Description Updates a specific lifestyle data entry. The value is checked and converted as for adding.
 // @Tags Lifestyle
 // @Accept       json
 // @Produce      json
 // @Param body body health.UpdateLifeStyleDataReq true "Request body for updating lifestyle data"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Lifestyle data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/lifestyle/updateLifestyleData [put]
 func (h *Handler) UpdateLifeStyleData(ctx *gin.Context) {
 	var update health.UpdateLifeStyleDataReq
 
 	if err := ctx.ShouldBindJSON(&update); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: err.Error()})
+		apierror.Bind(ctx, err)
+		return
+	}
+	if errs := normalizeMeasurement(update.DataType, &update.DataValue); len(errs) > 0 {
+		measurementInvalid(ctx, errs)
+		return
+	}
+	if !h.authorizeLifeStyleRecord(ctx, update.Id) {
 		return
 	}
 
 	_, err := h.Lifestyle.UpdateLifeStyleData(ctx, &health.UpdateLifeStyleDataReq{Id: update.Id, DataType: update.DataType, DataValue: update.DataValue})
 	if err != nil {
 		h.Logger.Error("Error Updating user life Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -163,15 +167,19 @@ func (h *Handler) UpdateLifeStyleData(ctx *gin.Context) {
 // @Produce      json
 // @Param id path string true "Data ID"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Lifestyle data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/lifestyle/deleteLifestyleData/{id} [delete]
 func (h *Handler) DeleteLifeStyleData(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !h.authorizeLifeStyleRecord(ctx, id) {
+		return
+	}
 
     _, err := h.Lifestyle.DeleteLifeStyleData(ctx, &health.DeleteLifeStyleDataReq{Id: id})
     if err!= nil {
         h.Logger.Error("Error deleting user life Style: ", "error", err)
-        ctx.JSON(500, models.Error{Message: err.Error()})
+        apierror.GRPC(ctx, err)
         return
     }
 
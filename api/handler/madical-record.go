@@ -1,11 +1,10 @@
 package handler
 
 import (
+	"api-gateway/api/apierror"
 	"api-gateway/genproto/health"
 	"api-gateway/genproto/user"
-	kafka "api-gateway/kafka/producer"
 	"api-gateway/models"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -20,47 +19,31 @@ import (
 // @Tags MedicalReport
 // @Param body body health.AddMedicalReportReq true "Request body for adding a medical report"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Medical report not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/medicalReport/add [post]
 func (h *Handler) AddMedicalReport(ctx *gin.Context) {
 	var record health.AddMedicalReportReq
 
 	if err := ctx.ShouldBindJSON(&record); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request parameters"})
+		apierror.Bind(ctx, err)
+		return
+	}
+	if !h.authorizeOwner(ctx, record.UserId) {
 		return
 	}
 
 	resp, err := h.Mecdical.AddMedicalReport(ctx, &health.AddMedicalReportReq{UserId: record.UserId, RecordType: record.RecordType, RecordDate: record.RecordDate, Description: record.Description, DoctorId: record.DoctorId, Attachments: record.Attachments})
 	if err != nil {
 		h.Logger.Error("Error Adding user medical record: ", "error", err)
-		ctx.JSON(500, err.Error())
+		apierror.GRPC(ctx, err)
 		return
 	}
 
 	kajkareq := user.CreateNotificationsReq{UserId: record.UserId, Message: fmt.Sprintf("You have added a new medical report for %s", time.Now().String())}
-	writerKafka, err := kafka.NewKafkaProducerInit([]string{"kafka:9092"})
-	if err != nil {
-		h.Logger.Error("Error initializing Kafka producer", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-		return
-	}
-	defer writerKafka.Close()
-
-	msgBytes, err := json.Marshal(&kajkareq)
-	if err != nil {
-		h.Logger.Error("Error marshaling request to JSON", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-		return
-	}
-
-	err = writerKafka.WriteToNotification("notification", msgBytes)
-	if err != nil {
-		h.Logger.Error("Error producing Kafka message", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-		return
-	}
+	h.enqueueFollowUp(ctx, "notification", kajkareq.UserId, &kajkareq)
 
 	h.Logger.Info("Medical report finished successfully")
 
@@ -72,27 +55,22 @@ func (h *Handler) AddMedicalReport(ctx *gin.Context) {
 // @Summary Get medical reports
 // @Description Retrieves all medical reports for a user
 // @Tags MedicalReport
+// @Param user_id query string false "Patient ID, defaults to the caller"
 // @Success 200 {object} health.GetMedicalReportRes "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/medicalReport/get [get]
 func (h *Handler) GetMedicalReport(ctx *gin.Context) {
-	fmt.Println("Helllllllllllllllllllllllllllllllllllllllllllllollllllllllllllllllllllllllllllllllllllll")
-	userId, exists := ctx.Get("user_id")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, models.Error{Message: "User ID not found in token"})
+	id, ok := h.targetUser(ctx)
+	if !ok {
 		return
 	}
-	id := userId.(string)
-	fmt.Println("Helllllllllllllllllllllllllllllllllllllllllllllollllllllllllllllllllllllllllllllllllllll")
-	fmt.Println(id)
 
 	user, err := h.User.GetUserById(ctx, &user.UserId{UserId: id})
 	if err != nil {
 		h.Logger.Error("Error getting user profile: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
-	fmt.Println(user)
 
 	resp, err := h.Mecdical.GetMedicalReport(ctx, &health.GetMedicalReportReq{
 		UserId:    id,
@@ -101,7 +79,7 @@ func (h *Handler) GetMedicalReport(ctx *gin.Context) {
 	})
 	if err != nil {
 		h.Logger.Error("Error Get Medical record Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -115,7 +93,8 @@ func (h *Handler) GetMedicalReport(ctx *gin.Context) {
 // @Tags MedicalReport
 // @Param id path string true "Report ID"
 // @Success 200 {object} health.GetMedicalReportByIdRes "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Medical report not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/medicalReport/getById/{id} [get]
 func (h *Handler) GetMedicalReportById(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -123,7 +102,10 @@ func (h *Handler) GetMedicalReportById(ctx *gin.Context) {
 	resp, err := h.Mecdical.GetMedicalReportById(ctx, &health.GetMedicalReportByIdReq{Id: id})
 	if err != nil {
 		h.Logger.Error("Error Get user life Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		h.lookupFailed(ctx, err)
+		return
+	}
+	if !h.authorizeOwner(ctx, resp.GetUserId()) {
 		return
 	}
 
@@ -139,22 +121,26 @@ func (h *Handler) GetMedicalReportById(ctx *gin.Context) {
 // @Produce      json
 // @Param body body health.UpdateMedicalReportReq true "Request body for updating a medical report"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Medical report not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/medicalReport/update [put]
 func (h *Handler) UpdateMedicalReport(ctx *gin.Context) {
 	var record health.UpdateMedicalReportReq
 
 	if err := ctx.ShouldBindJSON(&record); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: err.Error()})
+		apierror.Bind(ctx, err)
+		return
+	}
+	if !h.authorizeMedicalRecord(ctx, record.Id) {
 		return
 	}
 
 	_, err := h.Mecdical.UpdateMedicalReport(ctx, &health.UpdateMedicalReportReq{Id: record.Id, RecordType: record.RecordType, Description: record.Description, DoctorId: record.DoctorId, Attachments: record.Attachments})
 	if err != nil {
 		h.Logger.Error("Error Updating user medical record: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -170,15 +156,19 @@ func (h *Handler) UpdateMedicalReport(ctx *gin.Context) {
 // @Produce      json
 // @Param id path string true "Report ID"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Medical report not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/medicalReport/delete/{id} [delete]
 func (h *Handler) DeleteMedicalReport(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !h.authorizeMedicalRecord(ctx, id) {
+		return
+	}
 
 	_, err := h.Mecdical.DeleteMedicalReport(ctx, &health.DeleteMedicalReportReq{Id: id})
 	if err != nil {
 		h.Logger.Error("Error deleting user medical record: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"api-gateway/genproto/health"
+	"api-gateway/kafka/outbox"
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// fakeMedical saves every report it is given.
+type fakeMedical struct {
+	health.MedicalRecordClient
+	saved int
+}
+
+func (f *fakeMedical) AddMedicalReport(ctx context.Context, in *health.AddMedicalReportReq, opts ...grpc.CallOption) (*health.AddMedicalReportRes, error) {
+	f.saved++
+	return &health.AddMedicalReportRes{Id: "r1"}, nil
+}
+
+// brokenOutbox fails every write.
+type brokenOutbox struct {
+	outbox.Store
+}
+
+func (brokenOutbox) Enqueue(ctx context.Context, msg kafka.Message) (int64, error) {
+	return 0, errors.New("database is down")
+}
+
+func TestAddMedicalReport(t *testing.T) {
+	for _, broken := range []bool{false, true} {
+		name := "outbox up"
+		if broken {
+			name = "outbox down"
+		}
+		t.Run(name, func(t *testing.T) {
+			env := newGatewayEnv(t)
+			medical := &fakeMedical{}
+			env.handler.Mecdical = medical
+			if broken {
+				env.handler.Outbox = brokenOutbox{env.events}
+			}
+			env.router.POST("/api/medicalReport/add", env.handler.AddMedicalReport)
+
+			rec := env.do(t, http.MethodPost, "/api/medicalReport/add", "u1", `{"user_id":"u1","record_type":"lab"}`)
+			// the report is saved either way, so a retry must not be invited
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body)
+			}
+			var id string
+			if err := json.Unmarshal(rec.Body.Bytes(), &id); err != nil || id != "r1" {
+				t.Errorf("body %s, want the report id", rec.Body)
+			}
+			if medical.saved != 1 {
+				t.Errorf("saved %d reports, want 1", medical.saved)
+			}
+
+			queued, err := env.events.List(context.Background(), "", 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 1
+			if broken {
+				want = 0
+			}
+			if len(queued) != want || (want == 1 && queued[0].Topic != "notification") {
+				t.Errorf("outbox holds %d events, want %d notification", len(queued), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/measure"
+	"api-gateway/models"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMeasurements godoc
+// @Security ApiKeyAuth
+// @Summary List measurement types
+// @Description Lists the data types wearable and lifestyle readings may carry, with the units each accepts and the canonical unit values are stored in. A value may name its unit, e.g. "154 lb"; it is stored converted and without one.
+// @Tags Measurements
+// @Produce json
+// @Success 200 {array} measure.Type "Data types, sorted by name"
+// @Router /api/measurements [get]
+func (h *Handler) GetMeasurements(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, measure.Types())
+}
+
+// normalizeMeasurement checks a data type and value against the measure
+// registry and rewrites the value in the type's canonical unit.
+func normalizeMeasurement(dataType string, value *string) []models.FieldError {
+	var errs []models.FieldError
+	if dataType == "" {
+		errs = append(errs, models.FieldError{Field: "data_type", Message: "is required"})
+	}
+	if *value == "" {
+		errs = append(errs, models.FieldError{Field: "data_value", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	normalized, err := measure.Normalize(dataType, *value)
+	var invalid *measure.ValueError
+	switch {
+	case errors.Is(err, measure.ErrUnknownType):
+		return []models.FieldError{{Field: "data_type", Message: "must be one of: " + strings.Join(measure.Names(), ", ")}}
+	case errors.As(err, &invalid):
+		return []models.FieldError{{Field: "data_value", Message: invalid.Reason}}
+	case err != nil:
+		return []models.FieldError{{Field: "data_value", Message: err.Error()}}
+	}
+	*value = normalized
+	return nil
+}
+
+// measurementInvalid answers a request whose reading failed
+// normalizeMeasurement or validateReading.
+func measurementInvalid(ctx *gin.Context, errs []models.FieldError) {
+	apierror.Write(ctx, http.StatusBadRequest, "Invalid measurement", errs...)
+}
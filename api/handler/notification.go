@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"api-gateway/api/apierror"
+	"api-gateway/api/stream"
 	"api-gateway/genproto/user"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,26 +16,23 @@ import (
 // @Description it will GetAllNotifications
 // @Tags Notifications
 // @Success 200 {object} user.GetNotificationsResponse
-// @Failure 400 {object} string "Invalid data"
-// @Failure 500 {object} string "Server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid data"
+// @Failure 500 {object} models.ErrorResponse "Server error"
 // @Router /api/v1/notifications/getAll [get]
 func (h *Handler) GetAllNotifications(c *gin.Context) {
 	h.Logger.Info("GetAllNotifications called")
 	req := user.GetNotificationsReq{}
 
-	userId, exists := c.Get("userId")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID not found in context"})
+	principal, ok := h.principal(c)
+	if !ok {
 		return
 	}
-
-	id := userId.(string)
+	id := principal.UserID
 	req.UserId = id
 	res, err := h.User.GetAllNotifications(c, &req)
 	if err != nil {
 		h.Logger.Error(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Server error"})
+		apierror.GRPC(c, err)
 		return
 	}
 	h.Logger.Info("GetAllNotifications finished successfully")
@@ -45,29 +45,61 @@ func (h *Handler) GetAllNotifications(c *gin.Context) {
 // @Description it will GetAndMarkNotificationAsRead
 // @Tags Notifications
 // @Success 200 {object} user.GetAndMarkNotificationAsReadRes
-// @Failure 400 {object} string "Invalid data"
-// @Failure 500 {object} string "Server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid data"
+// @Failure 500 {object} models.ErrorResponse "Server error"
 // @Router /api/v1/notifications/new [get]
 func (h *Handler) GetAndMarkNotificationAsRead(c *gin.Context) {
 	h.Logger.Info("GetAndMarkNotificationAsRead called")
 
 	req := user.GetAndMarkNotificationAsReadReq{}
 
-	userId, exists := c.Get("userId")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID not found in context"})
+	principal, ok := h.principal(c)
+	if !ok {
 		return
 	}
-	id := userId.(string)
+	id := principal.UserID
 
 	req.UserId = id
 	res, err := h.User.GetAndMarkNotificationAsRead(c, &req)
 	if err != nil {
 		h.Logger.Error(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Server error"})
+		apierror.GRPC(c, err)
 		return
 	}
 	h.Logger.Info("GetAndMarkNotificationAsRead finished successfully")
 	c.JSON(200, res)
 }
+
+// StreamNotifications godoc
+// @Security ApiKeyAuth
+// @Summary Stream notifications
+// @Description Sends the caller's notifications as server-sent events ("notification") as they happen. Reconnect with Last-Event-ID to receive what was missed.
+// @Tags Notifications
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {object} models.NotificationEvent "Event data"
+// @Failure 429 {object} models.ErrorResponse "Too many open streams"
+// @Router /api/notifications/stream [get]
+func (h *Handler) StreamNotifications(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+
+	sub, missed, err := h.Streams.Notifications.Subscribe(principal.UserID, ctx.GetHeader("Last-Event-ID"), nil)
+	if errors.Is(err, stream.ErrTooManyConnections) {
+		h.Logger.Warn("Notification stream limit reached", "user_id", principal.UserID)
+		apierror.Write(ctx, http.StatusTooManyRequests, "Too many open notification streams")
+		return
+	}
+	if err != nil {
+		// the hub closes when the gateway shuts down
+		apierror.Write(ctx, http.StatusServiceUnavailable, "Stream unavailable, reconnect")
+		return
+	}
+	defer sub.Close()
+
+	h.Logger.Info("Notification stream opened", "user_id", principal.UserID, "missed", len(missed))
+	stream.ServeSSE(ctx, sub, missed, h.Streams.Heartbeat)
+	h.Logger.Info("Notification stream closed", "user_id", principal.UserID)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/kafka/outbox"
+	"api-gateway/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOutboxEvents godoc
+// @Security ApiKeyAuth
+// @Summary List outbox events
+// @Description Returns events waiting for, or done with, delivery to Kafka, newest first
+// @Tags Admin
+// @Produce json
+// @Param status query string false "pending, sent or dead"
+// @Param limit query int false "Page size, default 50"
+// @Param offset query int false "Events to skip"
+// @Success 200 {array} outbox.Event "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/outbox [get]
+func (h *Handler) GetOutboxEvents(ctx *gin.Context) {
+	status := ctx.Query("status")
+	switch status {
+	case "", outbox.StatusPending, outbox.StatusSent, outbox.StatusDead:
+	default:
+		apierror.Write(ctx, http.StatusBadRequest, "Invalid request parameters",
+			models.FieldError{Field: "status", Message: "must be one of: pending, sent, dead"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, err := h.Outbox.List(ctx, status, limit, offset)
+	if err != nil {
+		h.Logger.Error("Error listing outbox events", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// ReplayOutboxEvent godoc
+// @Security ApiKeyAuth
+// @Summary Replay an outbox event
+// @Description Makes the event due again with a fresh attempt budget
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.ReplayRes "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Event not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/outbox/{id}/replay [post]
+func (h *Handler) ReplayOutboxEvent(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Write(ctx, http.StatusBadRequest, "Invalid request parameters",
+			models.FieldError{Field: "id", Message: "must be a number"})
+		return
+	}
+
+	found, err := h.Outbox.Replay(ctx, id)
+	if err != nil {
+		h.Logger.Error("Error replaying outbox event", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !found {
+		apierror.Write(ctx, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	h.Logger.Info("Outbox event replayed", "id", id)
+	ctx.JSON(http.StatusOK, models.ReplayRes{Replayed: 1})
+}
+
+// ReplayDeadOutboxEvents godoc
+// @Security ApiKeyAuth
+// @Summary Replay dead outbox events
+// @Description Makes every event that ran out of attempts due again
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} models.ReplayRes "Successful operation"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/outbox/replay [post]
+func (h *Handler) ReplayDeadOutboxEvents(ctx *gin.Context) {
+	n, err := h.Outbox.ReplayDead(ctx)
+	if err != nil {
+		h.Logger.Error("Error replaying outbox events", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	h.Logger.Info("Dead outbox events replayed", "count", n)
+	ctx.JSON(http.StatusOK, models.ReplayRes{Replayed: n})
+}
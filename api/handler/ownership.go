@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/genproto/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// actCrossUser is the Casbin action that lets a role work with records
+	// owned by any other user on the matching routes,
+	// e.g. {"admin", "/api/*", "cross_user"}.
+	actCrossUser = "cross_user"
+	// actCareTeam lets a role work with records of the patients who added the
+	// caller to their care team, e.g. {"doctor", "/api/wearable/*", "care_team"}.
+	actCareTeam = "care_team"
+	// careTeamPtype holds the doctor -> patient links.
+	careTeamPtype = "g2"
+)
+
+// authorizeOwner checks that the caller may read or change data owned by
+// ownerID: either it is their own, their role has cross-user access on the
+// current route, or the owner added the caller to their care team. Denials
+// are answered with 404 rather than 403 so that record IDs of other users
+// can't be probed.
+func (h *Handler) authorizeOwner(ctx *gin.Context, ownerID string) bool {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return false
+	}
+	if ownerID != "" && ownerID == principal.UserID {
+		return true
+	}
+
+	allowed, err := h.canAccessUser(principal.Role, principal.UserID, ownerID, ctx.FullPath())
+	if err != nil {
+		h.Logger.Error("Error enforcing ownership policy", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return false
+	}
+	if !allowed {
+		h.Logger.Warn("Cross-user access denied", "user_id", principal.UserID, "owner_id", ownerID, "path", ctx.FullPath())
+		apierror.Write(ctx, http.StatusNotFound, "Not found")
+		return false
+	}
+	return true
+}
+
+func (h *Handler) canAccessUser(role, callerID, ownerID, path string) (bool, error) {
+	allowed, err := h.Enforcer.Enforce(role, path, actCrossUser)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	if ownerID == "" {
+		return false, nil
+	}
+
+	allowed, err = h.Enforcer.Enforce(role, path, actCareTeam)
+	if err != nil || !allowed {
+		return false, err
+	}
+	return h.Enforcer.HasNamedGroupingPolicy(careTeamPtype, callerID, ownerID)
+}
+
+// targetUser returns whose data a list endpoint works on: the caller, or the
+// user in the user_id query parameter when the caller may access their data.
+func (h *Handler) targetUser(ctx *gin.Context) (string, bool) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return "", false
+	}
+
+	id := ctx.Query("user_id")
+	if id == "" || id == principal.UserID {
+		return principal.UserID, true
+	}
+	if !h.authorizeOwner(ctx, id) {
+		return "", false
+	}
+	return id, true
+}
+
+// lookupFailed answers a failed record lookup done before an ownership check.
+func (h *Handler) lookupFailed(ctx *gin.Context, err error) {
+	h.Logger.Error("Error looking up record owner", "error", err)
+	if status.Code(err) == codes.NotFound {
+		apierror.Write(ctx, http.StatusNotFound, "Not found")
+		return
+	}
+	apierror.GRPC(ctx, err)
+}
+
+// The authorize*Record helpers load a record before it is changed or deleted
+// and check that the caller may touch it.
+
+func (h *Handler) authorizeLifeStyleRecord(ctx *gin.Context, id string) bool {
+	resp, err := h.Lifestyle.GetLifeStyleDataById(ctx, &health.GetLifeStyleDataByIdReq{Id: id})
+	if err != nil {
+		h.lookupFailed(ctx, err)
+		return false
+	}
+	return h.authorizeOwner(ctx, resp.GetLifeStyle().GetUserId())
+}
+
+func (h *Handler) authorizeMedicalRecord(ctx *gin.Context, id string) bool {
+	resp, err := h.Mecdical.GetMedicalReportById(ctx, &health.GetMedicalReportByIdReq{Id: id})
+	if err != nil {
+		h.lookupFailed(ctx, err)
+		return false
+	}
+	return h.authorizeOwner(ctx, resp.GetUserId())
+}
+
+func (h *Handler) authorizeWearableRecord(ctx *gin.Context, id string) bool {
+	resp, err := h.Wearable.GetWearableDataById(ctx, &health.GetWearableDataByIdReq{Id: id})
+	if err != nil {
+		h.lookupFailed(ctx, err)
+		return false
+	}
+	return h.authorizeOwner(ctx, resp.GetGetWarableId().GetUserId())
+}
@@ -0,0 +1,402 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	middleware "api-gateway/api/middlerware"
+	policy "api-gateway/casbin"
+	"api-gateway/models"
+	"net/http"
+	"strconv"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	policyAdminPath = "/api/admin/policies"
+	rolePtype       = "g"
+)
+
+// GetPolicies godoc
+// @Security ApiKeyAuth
+// @Summary List policies
+// @Description Returns every permission rule the enforcer holds
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.PolicyRule "Successful operation"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/policies [get]
+func (h *Handler) GetPolicies(ctx *gin.Context) {
+	rules, err := h.Enforcer.GetPolicy()
+	if err != nil {
+		h.Logger.Error("Error listing policies", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	policies := make([]models.PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		policies = append(policies, toPolicyRule(rule))
+	}
+	ctx.JSON(http.StatusOK, policies)
+}
+
+// AddPolicy godoc
+// @Security ApiKeyAuth
+// @Summary Add a policy
+// @Description Adds a permission rule
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.PolicyRule true "Rule to add"
+// @Success 201 {object} models.Success "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 409 {object} models.ErrorResponse "Policy already exists"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/policies [post]
+func (h *Handler) AddPolicy(ctx *gin.Context) {
+	var req models.PolicyRule
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	added, err := h.Enforcer.AddPolicy(req.Subject, req.Object, req.Action)
+	if err != nil {
+		h.Logger.Error("Error adding policy", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !added {
+		apierror.Write(ctx, http.StatusConflict, "Policy already exists")
+		return
+	}
+
+	h.audit(ctx, "add", "p", fromPolicyRule(req))
+	ctx.JSON(http.StatusCreated, models.Success{Message: "Policy added"})
+}
+
+// RemovePolicy godoc
+// @Security ApiKeyAuth
+// @Summary Remove a policy
+// @Description Removes a permission rule
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.PolicyRule true "Rule to remove"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Policy not found"
+// @Failure 409 {object} models.ErrorResponse "Change would lock admins out"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/policies [delete]
+func (h *Handler) RemovePolicy(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.PolicyRule
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	current, err := h.Enforcer.GetPolicy()
+	if err != nil {
+		h.Logger.Error("Error listing policies", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	removed := fromPolicyRule(req)
+	remaining := make([][]string, 0, len(current))
+	for _, rule := range current {
+		if ruleKey(rule) != ruleKey(removed) {
+			remaining = append(remaining, rule)
+		}
+	}
+	if !keepsPolicyAdmin(principal.Role, remaining) {
+		apierror.Write(ctx, http.StatusConflict, "Change would remove your access to policy administration")
+		return
+	}
+
+	ok, err = h.Enforcer.RemovePolicy(req.Subject, req.Object, req.Action)
+	if err != nil {
+		h.Logger.Error("Error removing policy", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !ok {
+		apierror.Write(ctx, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	h.audit(ctx, "remove", "p", removed)
+	ctx.JSON(http.StatusOK, models.Success{Message: "Policy removed"})
+}
+
+// ReplacePolicies godoc
+// @Security ApiKeyAuth
+// @Summary Replace all policies
+// @Description Replaces the permission rules with the given set. Only the difference is applied and audited.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.ReplacePoliciesReq true "Full set of rules"
+// @Success 200 {object} models.ReplacePoliciesRes "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 409 {object} models.ErrorResponse "Change would lock admins out"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/policies [put]
+func (h *Handler) ReplacePolicies(ctx *gin.Context) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.ReplacePoliciesReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	wanted := make([][]string, 0, len(req.Policies))
+	wantedKeys := map[string]bool{}
+	for _, p := range req.Policies {
+		rule := fromPolicyRule(p)
+		if wantedKeys[ruleKey(rule)] {
+			continue
+		}
+		wantedKeys[ruleKey(rule)] = true
+		wanted = append(wanted, rule)
+	}
+	if !keepsPolicyAdmin(principal.Role, wanted) {
+		apierror.Write(ctx, http.StatusConflict, "Change would remove your access to policy administration")
+		return
+	}
+
+	current, err := h.Enforcer.GetPolicy()
+	if err != nil {
+		h.Logger.Error("Error listing policies", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	currentKeys := map[string]bool{}
+	var toRemove [][]string
+	for _, rule := range current {
+		currentKeys[ruleKey(rule)] = true
+		if !wantedKeys[ruleKey(rule)] {
+			toRemove = append(toRemove, rule)
+		}
+	}
+	var toAdd [][]string
+	for _, rule := range wanted {
+		if !currentKeys[ruleKey(rule)] {
+			toAdd = append(toAdd, rule)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		if _, err := h.Enforcer.AddPolicies(toAdd); err != nil {
+			h.Logger.Error("Error adding policies", "error", err)
+			apierror.Write(ctx, http.StatusInternalServerError, "")
+			return
+		}
+		for _, rule := range toAdd {
+			h.audit(ctx, "add", "p", rule)
+		}
+	}
+	if len(toRemove) > 0 {
+		if _, err := h.Enforcer.RemovePolicies(toRemove); err != nil {
+			h.Logger.Error("Error removing policies", "error", err)
+			apierror.Write(ctx, http.StatusInternalServerError, "")
+			return
+		}
+		for _, rule := range toRemove {
+			h.audit(ctx, "remove", "p", rule)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, models.ReplacePoliciesRes{Added: len(toAdd), Removed: len(toRemove)})
+}
+
+// GetRoles godoc
+// @Security ApiKeyAuth
+// @Summary List role assignments
+// @Description Returns every role assignment. The user may be a user ID or another role.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.RoleAssignment "Successful operation"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/roles [get]
+func (h *Handler) GetRoles(ctx *gin.Context) {
+	rules, err := h.Enforcer.GetNamedGroupingPolicy(rolePtype)
+	if err != nil {
+		h.Logger.Error("Error listing role assignments", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+
+	roles := make([]models.RoleAssignment, 0, len(rules))
+	for _, rule := range rules {
+		roles = append(roles, models.RoleAssignment{User: rule[0], Role: rule[1]})
+	}
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// AddRole godoc
+// @Security ApiKeyAuth
+// @Summary Assign a role
+// @Description Gives a user, or another role, the permissions of a role
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.RoleAssignment true "Role assignment"
+// @Success 201 {object} models.Success "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 409 {object} models.ErrorResponse "Role already assigned"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/roles [post]
+func (h *Handler) AddRole(ctx *gin.Context) {
+	var req models.RoleAssignment
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	added, err := h.Enforcer.AddNamedGroupingPolicy(rolePtype, req.User, req.Role)
+	if err != nil {
+		h.Logger.Error("Error assigning role", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !added {
+		apierror.Write(ctx, http.StatusConflict, "Role already assigned")
+		return
+	}
+
+	h.audit(ctx, "add", rolePtype, []string{req.User, req.Role})
+	ctx.JSON(http.StatusCreated, models.Success{Message: "Role assigned"})
+}
+
+// RemoveRole godoc
+// @Security ApiKeyAuth
+// @Summary Remove a role assignment
+// @Description Takes a role away from a user or another role
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body models.RoleAssignment true "Role assignment"
+// @Success 200 {object} models.Success "Successful operation"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Role assignment not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/roles [delete]
+func (h *Handler) RemoveRole(ctx *gin.Context) {
+	var req models.RoleAssignment
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.Logger.Error("Error binding JSON: ", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	removed, err := h.Enforcer.RemoveNamedGroupingPolicy(rolePtype, req.User, req.Role)
+	if err != nil {
+		h.Logger.Error("Error removing role assignment", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	if !removed {
+		apierror.Write(ctx, http.StatusNotFound, "Role assignment not found")
+		return
+	}
+
+	h.audit(ctx, "remove", rolePtype, []string{req.User, req.Role})
+	ctx.JSON(http.StatusOK, models.Success{Message: "Role assignment removed"})
+}
+
+// GetPolicyAudit godoc
+// @Security ApiKeyAuth
+// @Summary Policy audit trail
+// @Description Returns policy and role changes, newest first
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Page size, default 50"
+// @Param offset query int false "Entries to skip"
+// @Success 200 {array} casbin.AuditEntry "Successful operation"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/admin/audit [get]
+func (h *Handler) GetPolicyAudit(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	entries, err := h.Audit.List(ctx, limit, offset)
+	if err != nil {
+		h.Logger.Error("Error listing policy audit", "error", err)
+		apierror.Write(ctx, http.StatusInternalServerError, "")
+		return
+	}
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// audit records a policy change. The change itself already happened, so a
+// failure here is logged rather than returned to the caller.
+func (h *Handler) audit(ctx *gin.Context, action, ptype string, rule []string) {
+	actor := "unknown"
+	if principal, ok := middleware.GetPrincipal(ctx); ok {
+		actor = principal.UserID
+	}
+
+	h.Logger.Info("Policy changed", "actor", actor, "action", action, "ptype", ptype, "rule", rule)
+	err := h.Audit.Record(ctx, policy.AuditEntry{Actor: actor, Action: action, Ptype: ptype, Rule: rule})
+	if err != nil {
+		h.Logger.Error("Error writing policy audit", "error", err)
+	}
+}
+
+// keepsPolicyAdmin reports whether role can still replace policies once rules
+// are in force, so a bulk change can't lock every admin out.
+func keepsPolicyAdmin(role string, rules [][]string) bool {
+	for _, rule := range rules {
+		if len(rule) >= 3 && rule[0] == role && rule[2] == http.MethodPut && util.KeyMatch(policyAdminPath, rule[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func toPolicyRule(rule []string) models.PolicyRule {
+	p := models.PolicyRule{}
+	if len(rule) > 0 {
+		p.Subject = rule[0]
+	}
+	if len(rule) > 1 {
+		p.Object = rule[1]
+	}
+	if len(rule) > 2 {
+		p.Action = rule[2]
+	}
+	return p
+}
+
+func fromPolicyRule(p models.PolicyRule) []string {
+	return []string{p.Subject, p.Object, p.Action}
+}
+
+func ruleKey(rule []string) string {
+	key := ""
+	for _, v := range rule {
+		key += v + "\x00"
+	}
+	return key
+}
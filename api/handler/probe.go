@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"api-gateway/api/readiness"
+	"api-gateway/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz godoc
+// @Summary Liveness
+// @Description Answers while the gateway process is running. It checks no dependency, so a failing backend never gets the gateway restarted.
+// @Tags Probes
+// @Produce json
+// @Success 200 {object} models.Liveness "Alive"
+// @Router /healthz [get]
+func (h *Handler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.Liveness{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness
+// @Description Checks the backends, databases and Kafka and reports each. The gateway is not ready, and answers 503, while a critical dependency is down.
+// @Tags Probes
+// @Produce json
+// @Success 200 {object} readiness.Report "Ready"
+// @Failure 503 {object} readiness.Report "Not ready"
+// @Router /readyz [get]
+func (h *Handler) Readyz(ctx *gin.Context) {
+	if h.Readiness == nil {
+		ctx.JSON(http.StatusOK, readiness.Report{Status: readiness.StatusReady, Checks: []readiness.Result{}})
+		return
+	}
+
+	report := h.Readiness.Check(ctx.Request.Context())
+	if !report.Ready() {
+		h.Logger.Warn("Gateway not ready", "checks", report.Checks)
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
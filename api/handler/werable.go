@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"api-gateway/alert"
+	"api-gateway/api/apierror"
+	"api-gateway/api/stream"
 	"api-gateway/genproto/health"
-	kafka "api-gateway/kafka/producer"
 	"api-gateway/models"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,57 +16,45 @@ import (
 // AddWearableData godoc
 // @Security ApiKeyAuth
 // @Summary Add wearable data
-// @Description Adds wearable data for a user
+// @Description Adds wearable data for a user. data_type must be one of GET /api/measurements; data_value may name its unit and is stored in the canonical one.
 // @Tags WearableData
 // @Accept       json
 // @Produce      json
 // @Param body body health.AddWearableDataReq true "Request body for adding wearable data"
-// @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Success 202 {object} models.JobAccepted "Accepted; poll the Location header for the outcome"
+// @Header 202 {string} Location "Job status URL"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/wearable/add [post]
 func (h *Handler) AddWearableData(ctx *gin.Context) {
 	var warable health.AddWearableDataReq
-	userId, exists := ctx.Get("user_id")
-	if !exists {
-		h.Logger.Error("User ID not found in context")
-		ctx.JSON(http.StatusBadRequest, models.Error{Message: "User ID not found in token"})
+	principal, ok := h.principal(ctx)
+	if !ok {
 		return
 	}
-	id := userId.(string)
+	id := principal.UserID
 
 	if err := ctx.ShouldBindJSON(&warable); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: err.Error()})
+		apierror.Bind(ctx, err)
 		return
 	}
 
 	warable.UserId = id
-
-	writerKafka, err := kafka.NewKafkaProducerInit([]string{"kafka:9092"})
-	if err != nil {
-		h.Logger.Error("Error initializing Kafka producer", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	if errs := validateReading(&warable); len(errs) > 0 {
+		measurementInvalid(ctx, errs)
 		return
 	}
-	defer writerKafka.Close()
 
-	msgBytes, err := json.Marshal(&warable)
-	if err != nil {
-		h.Logger.Error("Error marshaling request to JSON", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	jobID, ok := h.startJob(ctx, "werable", warable.UserId, &warable)
+	if !ok {
 		return
 	}
 
-	err = writerKafka.Producermessage("werable", msgBytes)
-	if err != nil {
-		h.Logger.Error("Error producing Kafka message", "error", err)
-		ctx.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
-		return
-	}
+	h.raiseAlerts(ctx, alert.Reading{PatientID: warable.UserId, DataType: warable.DataType, DeviceType: warable.DeviceType, Value: warable.DataValue})
 
-	h.Logger.Info("GenerateHealthRecommendations finished successfully")
-	ctx.JSON(http.StatusOK, models.Success{Message: "Recommendations generated successfully"})
+	h.Logger.Info("AddWearableData accepted", "job_id", jobID)
+	h.jobAccepted(ctx, jobID)
 }
 
 // GetWearableData godoc
@@ -74,32 +64,20 @@ func (h *Handler) AddWearableData(ctx *gin.Context) {
 // @Tags WearableData
 // @Accept       json
 // @Produce      json
+// @Param user_id query string false "Patient ID, defaults to the caller"
 // @Success 200 {object} models.Warable "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/wearable/get [get]
 func (h *Handler) GetWearableData(ctx *gin.Context) {
-	userID, exists := ctx.Get("user_id")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, models.Error{Message: "User ID not found in token"})
+	id, ok := h.targetUser(ctx)
+	if !ok {
 		return
 	}
 
-	id := userID.(string)
-	fmt.Println(id)
-
-	// user, err := h.User.GetUserById(ctx, &user.UserId{UserId: id})
-	// if err != nil {
-	// 	fmt.Println(err, "------------------------")
-	// 	h.Logger.Error("Error getting user profile: ", "error", err)
-	// 	ctx.JSON(500, models.Error{Message: err.Error()})
-	// 	return
-	// }
-	// fmt.Println(user.FirstName, user.LastName)
 	resp, err := h.Wearable.GetWearableData(ctx, &health.GetWearableDataReq{UserId: id})
 	if err != nil {
-		fmt.Println(err, "+++++++++++++++++++++++++++++++++++")
 		h.Logger.Error("Error Get Medical record Style: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -115,16 +93,19 @@ func (h *Handler) GetWearableData(ctx *gin.Context) {
 // @Produce      json
 // @Param id path string true "Wearable Data ID"
 // @Success 200 {object} health.GetWearableDataByIdRes "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Wearable data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/wearable/getById/{id} [get]
 func (h *Handler) GetWearableDataById(ctx *gin.Context) {
 	id := ctx.Param("id")
-	fmt.Println(id)
 
 	resp, err := h.Wearable.GetWearableDataById(ctx, &health.GetWearableDataByIdReq{Id: id})
 	if err != nil {
 		h.Logger.Error("Error Get user Wearable data: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		h.lookupFailed(ctx, err)
+		return
+	}
+	if !h.authorizeOwner(ctx, resp.GetGetWarableId().GetUserId()) {
 		return
 	}
 
@@ -134,28 +115,36 @@ func (h *Handler) GetWearableDataById(ctx *gin.Context) {
 // UpdateWearableData godoc
 // @Security ApiKeyAuth
 // @Summary Update wearable data
-// @Description Updates a specific wearable data entry
+// @Description Updates a specific wearable data entry. The value is checked and converted as for adding.
 // @Tags WearableData
 // @Accept       json
 // @Produce      json
 // @Param body body health.UpdateWearableDataReq true "Request body for updating wearable data"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 400 {object} models.Error "Invalid request parameters"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 400 {object} models.ErrorResponse "Invalid request parameters"
+// @Failure 404 {object} models.ErrorResponse "Wearable data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/wearable/update/ [put]
 func (h *Handler) UpdateWearableData(ctx *gin.Context) {
 	var warable health.UpdateWearableDataReq
 
 	if err := ctx.ShouldBindJSON(&warable); err != nil {
 		h.Logger.Error("Error binding JSON: ", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request parameters"})
+		apierror.Bind(ctx, err)
+		return
+	}
+	if errs := normalizeMeasurement(warable.DataType, &warable.DataValue); len(errs) > 0 {
+		measurementInvalid(ctx, errs)
+		return
+	}
+	if !h.authorizeWearableRecord(ctx, warable.Id) {
 		return
 	}
 
 	_, err := h.Wearable.UpdateWearableData(ctx, &health.UpdateWearableDataReq{Id: warable.Id, DeviceType: warable.DeviceType, DataType: warable.DataType, DataValue: warable.DataValue})
 	if err != nil {
 		h.Logger.Error("Error Updating user Wearable data: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
@@ -171,17 +160,73 @@ func (h *Handler) UpdateWearableData(ctx *gin.Context) {
 // @Produce      json
 // @Param id path string true "Wearable Data ID"
 // @Success 200 {object} models.Success "Successful operation"
-// @Failure 500 {object} models.Error "Internal server error"
+// @Failure 404 {object} models.ErrorResponse "Wearable data not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/wearable/delete/{id} [delete]
 func (h *Handler) DeleteWearableData(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !h.authorizeWearableRecord(ctx, id) {
+		return
+	}
 
 	_, err := h.Wearable.DeleteWearableData(ctx, &health.DeleteWearableDataReq{Id: id})
 	if err != nil {
 		h.Logger.Error("Error deleting user Wearable data: ", "error", err)
-		ctx.JSON(500, models.Error{Message: err.Error()})
+		apierror.GRPC(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, models.Success{Message: "Wearable data deleted successfully"})
 }
+
+// StreamVitals godoc
+// @Security ApiKeyAuth
+// @Summary Stream wearable readings
+// @Description Sends a patient's wearable readings ("reading") and the alerts they raise ("alert") as server-sent events as they arrive. Reconnect with Last-Event-ID to receive what was missed.
+// @Tags WearableData
+// @Produce text/event-stream
+// @Param user_id query string false "Patient ID, defaults to the caller"
+// @Param data_type query string false "Only these data types, comma separated"
+// @Param device_type query string false "Only these device types, comma separated"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {object} models.VitalReading "Reading event data"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 429 {object} models.ErrorResponse "Too many open streams"
+// @Router /api/wearable/stream [get]
+func (h *Handler) StreamVitals(ctx *gin.Context) {
+	id, ok := h.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	filter := stream.Filter{
+		stream.AttrDataType:   splitQuery(ctx.Query("data_type")),
+		stream.AttrDeviceType: splitQuery(ctx.Query("device_type")),
+	}
+	sub, missed, err := h.Streams.Vitals.Subscribe(id, ctx.GetHeader("Last-Event-ID"), filter)
+	if errors.Is(err, stream.ErrTooManyConnections) {
+		h.Logger.Warn("Vitals stream limit reached", "user_id", id)
+		apierror.Write(ctx, http.StatusTooManyRequests, "Too many open streams for this patient")
+		return
+	}
+	if err != nil {
+		// the hub closes when the gateway shuts down
+		apierror.Write(ctx, http.StatusServiceUnavailable, "Stream unavailable, reconnect")
+		return
+	}
+	defer sub.Close()
+
+	h.Logger.Info("Vitals stream opened", "user_id", id, "missed", len(missed))
+	stream.ServeSSE(ctx, sub, missed, h.Streams.Heartbeat)
+	h.Logger.Info("Vitals stream closed", "user_id", id)
+}
+
+func splitQuery(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
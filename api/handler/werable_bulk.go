@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"api-gateway/alert"
+	"api-gateway/api/apierror"
+	"api-gateway/genproto/health"
+	"api-gateway/kafka/event"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	itemAccepted = "accepted"
+	itemRejected = "rejected"
+)
+
+var errTooManyItems = errors.New("too many items")
+
+// BulkLimits bound one bulk upload. Imports are read in batches of
+// MaxItems and may be up to ImportMaxBytes.
+type BulkLimits struct {
+	MaxItems       int
+	MaxBytes       int64
+	ImportMaxBytes int64
+	// ImportTimeout replaces the server's read and write timeouts for an
+	// import.
+	ImportTimeout time.Duration
+}
+
+// AddWearableDataBulk godoc
+// @Security ApiKeyAuth
+// @Summary Add wearable data in bulk
+// @Description Accepts a JSON array or newline-delimited JSON (application/x-ndjson) of readings. Each reading is checked on its own; valid ones are queued even when others are rejected, so check the status of every item. Unlike POST /api/wearable/add no job is started and no Location is returned: accepted readings are only queued, and event_id identifies each of them in the feeds.
+// @Tags WearableData
+// @Accept json
+// @Accept application/x-ndjson
+// @Produce json
+// @Param body body []health.AddWearableDataReq true "Readings; user_id defaults to the caller"
+// @Success 202 {object} models.BulkWearableRes "Per-item report"
+// @Failure 400 {object} models.ErrorResponse "Body is not a JSON array or NDJSON"
+// @Failure 413 {object} models.ErrorResponse "Too many readings or body too large"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/wearable/bulk [post]
+func (h *Handler) AddWearableDataBulk(ctx *gin.Context) {
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.Bulk.MaxBytes)
+
+	var readings []*health.AddWearableDataReq
+	var decodeErrs []error
+	err := decodeReadings(body, h.Bulk.MaxItems, func(req *health.AddWearableDataReq, err error) {
+		readings = append(readings, req)
+		decodeErrs = append(decodeErrs, err)
+	})
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		apierror.Write(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("Body is larger than %d bytes", h.Bulk.MaxBytes))
+		return
+	case errors.Is(err, errTooManyItems):
+		apierror.Write(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("At most %d readings per request", h.Bulk.MaxItems))
+		return
+	case err != nil:
+		h.Logger.Error("Error reading bulk body", "error", err)
+		apierror.Bind(ctx, err)
+		return
+	}
+
+	res, ok := h.ingestWearable(ctx, readings, decodeErrs)
+	if !ok {
+		return
+	}
+	h.Logger.Info("Bulk wearable data accepted", "accepted", res.Accepted, "rejected", res.Rejected)
+	ctx.JSON(http.StatusAccepted, res)
+}
+
+// ingestWearable checks every reading and queues the valid ones in one
+// outbox write, keyed by user so each user's readings stay in order, then
+// checks them against the alert rules.
+// decodeErrs, when set, lines up with readings and rejects the items that
+// could not be read. It answers errors itself and reports false then.
+func (h *Handler) ingestWearable(ctx *gin.Context, readings []*health.AddWearableDataReq, decodeErrs []error) (models.BulkWearableRes, bool) {
+	principal, ok := h.principal(ctx)
+	if !ok {
+		return models.BulkWearableRes{}, false
+	}
+
+	res := models.BulkWearableRes{Received: len(readings), Items: make([]models.BulkItemResult, len(readings))}
+	allowed := map[string]bool{principal.UserID: true}
+	var msgs []kafka.Message
+	var queued []int
+
+	for i, req := range readings {
+		item := &res.Items[i]
+		item.Index = i
+		item.Status = itemRejected
+
+		if decodeErrs != nil && decodeErrs[i] != nil {
+			item.Errors = readingDecodeErrors(decodeErrs[i])
+			continue
+		}
+		if req.UserId == "" {
+			req.UserId = principal.UserID
+		}
+		if _, seen := allowed[req.UserId]; !seen {
+			may, err := h.canAccessUser(principal.Role, principal.UserID, req.UserId, ctx.FullPath())
+			if err != nil {
+				h.Logger.Error("Error enforcing ownership policy", "error", err)
+				apierror.Write(ctx, http.StatusInternalServerError, "")
+				return models.BulkWearableRes{}, false
+			}
+			allowed[req.UserId] = may
+		}
+		if !allowed[req.UserId] {
+			item.Errors = []models.FieldError{{Field: "user_id", Message: "user not found"}}
+			continue
+		}
+		if item.Errors = validateReading(req); len(item.Errors) > 0 {
+			continue
+		}
+
+		msg, ok := h.encodeEvent(ctx, "werable", req, h.eventMeta(ctx, req.UserId, ""))
+		if !ok {
+			return models.BulkWearableRes{}, false
+		}
+		item.EventID = msg.Headers[event.HeaderID]
+		msgs = append(msgs, msg)
+		queued = append(queued, i)
+	}
+
+	if len(msgs) > 0 {
+		if _, err := h.Outbox.EnqueueBatch(ctx.Request.Context(), msgs); err != nil {
+			h.Logger.Error("Error writing events to outbox", "count", len(msgs), "error", err)
+			apierror.Write(ctx, http.StatusInternalServerError, "")
+			return models.BulkWearableRes{}, false
+		}
+	}
+	for _, i := range queued {
+		res.Items[i].Status = itemAccepted
+		req := readings[i]
+		h.raiseAlerts(ctx, alert.Reading{PatientID: req.UserId, DataType: req.DataType, DeviceType: req.DeviceType, Value: req.DataValue})
+	}
+	res.Accepted = len(queued)
+	res.Rejected = res.Received - res.Accepted
+	return res, true
+}
+
+// validateReading returns what is wrong with a reading and puts its value
+// in the canonical unit of its data type.
+func validateReading(req *health.AddWearableDataReq) []models.FieldError {
+	var errs []models.FieldError
+	if req.DeviceType == "" {
+		errs = append(errs, models.FieldError{Field: "device_type", Message: "is required"})
+	}
+	return append(errs, normalizeMeasurement(req.DataType, &req.DataValue)...)
+}
+
+func readingDecodeErrors(err error) []models.FieldError {
+	var mistyped *json.UnmarshalTypeError
+	if errors.As(err, &mistyped) && mistyped.Field != "" {
+		return []models.FieldError{{Field: mistyped.Field, Message: "must be a " + mistyped.Type.String()}}
+	}
+	return []models.FieldError{{Message: "item is not a valid JSON object"}}
+}
+
+// decodeReadings calls each for every reading in body, which is either a
+// JSON array or newline-delimited JSON. A reading that doesn't fit the
+// schema is passed on with its error. A broken NDJSON line only spoils that
+// line; broken JSON in an array ends the whole body, as nothing after it
+// can be read.
+func decodeReadings(body io.Reader, maxItems int, each func(*health.AddWearableDataReq, error)) error {
+	r := bufio.NewReader(body)
+	first, err := firstByte(r)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	next := func() error {
+		if count++; count > maxItems {
+			return errTooManyItems
+		}
+		return nil
+	}
+
+	if first == '[' {
+		dec := json.NewDecoder(r)
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		for dec.More() {
+			if err := next(); err != nil {
+				return err
+			}
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			req := &health.AddWearableDataReq{}
+			each(req, json.Unmarshal(raw, req))
+		}
+		_, err := dec.Token()
+		return err
+	}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := next(); err != nil {
+				return err
+			}
+			req := &health.AddWearableDataReq{}
+			each(req, json.Unmarshal(line, req))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// firstByte peeks at the first byte that isn't white space.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"api-gateway/genproto/health"
+	"api-gateway/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeReadings(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// types are the data types read, "!" for an item that failed
+		types []string
+		err   error
+	}{
+		{"empty", "  \n", nil, nil},
+		{"array", ` [{"data_type":"heart_rate"},{"data_type":"steps"}]`, []string{"heart_rate", "steps"}, nil},
+		{"ndjson", "{\"data_type\":\"heart_rate\"}\n\n{\"data_type\":\"steps\"}", []string{"heart_rate", "steps"}, nil},
+		{"mistyped array item", `[{"data_type":1},{"data_type":"steps"}]`, []string{"!", "steps"}, nil},
+		{"broken ndjson line", "{\"data_type\":\"heart_rate\"}\n{\"data_type\n{\"data_type\":\"steps\"}\n", []string{"heart_rate", "!", "steps"}, nil},
+		{"too many items", `[{},{},{},{}]`, []string{"", "", ""}, errTooManyItems},
+		{"too many lines", "{}\n{}\n{}\n{}\n", []string{"", "", ""}, errTooManyItems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []string
+			err := decodeReadings(strings.NewReader(tt.body), 3, func(req *health.AddWearableDataReq, err error) {
+				if err != nil {
+					types = append(types, "!")
+					return
+				}
+				types = append(types, req.DataType)
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+			if strings.Join(types, ",") != strings.Join(tt.types, ",") {
+				t.Errorf("read %v, want %v", types, tt.types)
+			}
+		})
+	}
+
+	// nothing after broken JSON in an array can be read
+	if err := decodeReadings(strings.NewReader(`[{"data_type":"steps"} {`), 3, func(*health.AddWearableDataReq, error) {}); err == nil {
+		t.Error("broken array was read, want an error")
+	}
+}
+
+func TestAddWearableDataBulk(t *testing.T) {
+	env := newGatewayEnv(t)
+	env.handler.Bulk = BulkLimits{MaxItems: 10, MaxBytes: 1 << 20}
+	env.router.POST("/api/wearable/bulk", env.handler.AddWearableDataBulk)
+
+	body := `[
+		{"device_type":"watch","data_type":"heart_rate","data_value":"72"},
+		{"device_type":"watch","data_type":"mood","data_value":"good"},
+		{"data_type":"heart_rate","data_value":"72"},
+		{"user_id":"u2","device_type":"watch","data_type":"heart_rate","data_value":"72"},
+		{"device_type":"watch","data_type":"heart_rate","data_value":72},
+		{"user_id":"u1","device_type":"watch","data_type":"steps","data_value":"1000"}
+	]`
+	rec := env.do(t, http.MethodPost, "/api/wearable/bulk", "u1", body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d, want 202: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Location"); got != "" {
+		t.Errorf("Location %q, want none; bulk readings start no job", got)
+	}
+	var res models.BulkWearableRes
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Received != 6 || res.Accepted != 2 || res.Rejected != 4 {
+		t.Errorf("received %d, accepted %d, rejected %d, want 6, 2, 4", res.Received, res.Accepted, res.Rejected)
+	}
+
+	want := []struct {
+		status, field string
+	}{
+		{itemAccepted, ""},
+		{itemRejected, "data_type"},
+		{itemRejected, "device_type"},
+		// another patient's data is refused like a missing one
+		{itemRejected, "user_id"},
+		{itemRejected, "data_value"},
+		{itemAccepted, ""},
+	}
+	for i, item := range res.Items {
+		if item.Index != i || item.Status != want[i].status {
+			t.Errorf("item %d: %+v, want %s", i, item, want[i].status)
+			continue
+		}
+		if item.Status == itemAccepted && (item.EventID == "" || len(item.Errors) > 0) {
+			t.Errorf("item %d: accepted without an event id or with errors: %+v", i, item)
+		}
+		if item.Status == itemRejected && (len(item.Errors) == 0 || item.Errors[0].Field != want[i].field || item.EventID != "") {
+			t.Errorf("item %d: errors %+v, want one about %s", i, item.Errors, want[i].field)
+		}
+	}
+
+	queued, err := env.events.List(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("outbox holds %d events, want the 2 accepted readings", len(queued))
+	}
+	if queued[0].Topic != "werable" {
+		t.Errorf("queued on %s, want werable", queued[0].Topic)
+	}
+}
+
+func TestAddWearableDataBulkLimits(t *testing.T) {
+	env := newGatewayEnv(t)
+	env.handler.Bulk = BulkLimits{MaxItems: 2, MaxBytes: 200}
+	env.router.POST("/api/wearable/bulk", env.handler.AddWearableDataBulk)
+
+	for name, body := range map[string]string{
+		"too many items": "{}\n{}\n{}\n",
+		"too large":      `[{"device_type":"` + strings.Repeat("x", 300) + `"}]`,
+	} {
+		if rec := env.do(t, http.MethodPost, "/api/wearable/bulk", "u1", body); rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status %d, want 413", name, rec.Code)
+		}
+	}
+	if rec := env.do(t, http.MethodPost, "/api/wearable/bulk", "u1", `[{"device_type":"watch"} {`); rec.Code != http.StatusBadRequest {
+		t.Errorf("broken array: status %d, want 400", rec.Code)
+	}
+}
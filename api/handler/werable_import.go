@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/genproto/health"
+	"api-gateway/importer"
+	"api-gateway/kafka/event"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportErrors caps the invalid records listed in an import report.
+const maxImportErrors = 20
+
+// errImportAborted stops an import whose response is already written.
+var errImportAborted = errors.New("import aborted")
+
+// ImportWearableData godoc
+// @Security ApiKeyAuth
+// @Summary Import wearable data from another app
+// @Description Reads an Apple Health export.xml, a Google Fit export (Takeout data points or a Fitness API dataset) or a Fitbit export (Takeout file or Web API response) and queues its readings with normalised data types and units. Imported readings are tagged as such and are neither streamed nor checked against the alert rules. The body is streamed, so exports of any size up to the limit can be sent. Readings are queued in batches; if the body breaks off half way the batches before the error stay queued.
+// @Tags WearableData
+// @Accept xml
+// @Accept json
+// @Produce json
+// @Param format query string true "Export format" Enums(apple_health, google_fit, fitbit)
+// @Param data_type query string false "What a Fitbit Takeout file of plain {dateTime, value} entries holds" Enums(steps, calories)
+// @Param unit query string false "Unit of Fitbit values that don't name one, e.g. lb for weight"
+// @Param user_id query string false "User the readings belong to; defaults to the caller"
+// @Success 202 {object} models.ImportWearableRes "Import summary"
+// @Failure 400 {object} models.ErrorResponse "Unknown format or malformed export"
+// @Failure 413 {object} models.ErrorResponse "Body too large"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/wearable/import [post]
+func (h *Handler) ImportWearableData(ctx *gin.Context) {
+	format := ctx.Query("format")
+	if format == "" {
+		apierror.Write(ctx, http.StatusBadRequest, "format is required; one of: "+strings.Join(importer.Formats, ", "))
+		return
+	}
+	userID, ok := h.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	res := models.ImportWearableRes{Format: format}
+	var batch []kafka.Message
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		if _, err := h.Outbox.EnqueueBatch(ctx.Request.Context(), batch); err != nil {
+			h.Logger.Error("Error writing events to outbox", "count", len(batch), "error", err)
+			apierror.Write(ctx, http.StatusInternalServerError, "")
+			return false
+		}
+		res.Imported += len(batch)
+		batch = batch[:0]
+		return true
+	}
+
+	record := 0
+	invalid := func(err error) {
+		res.Invalid++
+		if len(res.Errors) < maxImportErrors {
+			res.Errors = append(res.Errors, models.ImportError{Record: record, Message: err.Error()})
+		}
+	}
+
+	if h.Bulk.ImportTimeout > 0 {
+		// a large export takes longer to upload than the server allows
+		deadline := time.Now().Add(h.Bulk.ImportTimeout)
+		rc := http.NewResponseController(ctx.Writer)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+	}
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.Bulk.ImportMaxBytes)
+	opts := importer.Options{DataType: ctx.Query("data_type"), Unit: ctx.Query("unit")}
+	err := importer.Read(body, format, opts, func(r importer.Result) error {
+		defer func() { record++ }()
+		switch {
+		case r.Skipped:
+			res.Skipped++
+			return nil
+		case r.Invalid != nil:
+			invalid(r.Invalid)
+			return nil
+		}
+
+		req := &health.AddWearableDataReq{
+			UserId:     userID,
+			DeviceType: r.Record.DeviceType,
+			DataType:   r.Record.DataType,
+			DataValue:  r.Record.Value,
+		}
+		if errs := validateReading(req); len(errs) > 0 {
+			invalid(fmt.Errorf("%s %s", errs[0].Field, errs[0].Message))
+			return nil
+		}
+		meta := h.eventMeta(ctx, userID, "")
+		meta.OccurredAt = r.Record.At
+		// past readings; the stream and the alert rules are for live ones
+		meta.Source = event.SourceImport
+		msg, ok := h.encodeEvent(ctx, "werable", req, meta)
+		if !ok {
+			return errImportAborted
+		}
+		if batch = append(batch, msg); len(batch) >= h.Bulk.MaxItems && !flush() {
+			return errImportAborted
+		}
+		return nil
+	})
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, errImportAborted):
+		return
+	case errors.Is(err, importer.ErrUnknownFormat):
+		apierror.Write(ctx, http.StatusBadRequest, "format must be one of: "+strings.Join(importer.Formats, ", "))
+		return
+	case errors.Is(err, importer.ErrInvalidOption):
+		apierror.Write(ctx, http.StatusBadRequest, strings.TrimPrefix(err.Error(), importer.ErrInvalidOption.Error()+": "))
+		return
+	}
+	if !flush() {
+		return
+	}
+	switch {
+	case errors.As(err, &tooLarge):
+		apierror.Write(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("Body is larger than %d bytes; %d readings were imported before the limit", h.Bulk.ImportMaxBytes, res.Imported))
+		return
+	case err != nil:
+		h.Logger.Error("Error reading import", "format", format, "error", err)
+		apierror.Write(ctx, http.StatusBadRequest, fmt.Sprintf("Malformed %s export after %d records: %v; %d readings were imported before the error", format, record, err, res.Imported))
+		return
+	}
+
+	h.Logger.Info("Wearable data imported", "format", format, "user_id", userID, "imported", res.Imported, "skipped", res.Skipped, "invalid", res.Invalid)
+	ctx.JSON(http.StatusAccepted, res)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"api-gateway/alert"
+	"api-gateway/api/stream"
+	"api-gateway/kafka/event"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestImportWearableDataIsNotLive(t *testing.T) {
+	env := newGatewayEnv(t)
+	env.handler.Bulk = BulkLimits{MaxItems: 10, ImportMaxBytes: 1 << 20}
+	rules := alert.NewMemoryStore()
+	if _, err := rules.Create(context.Background(), alert.Rule{DataType: "heart_rate", Condition: alert.Above, Threshold: 100}); err != nil {
+		t.Fatal(err)
+	}
+	env.handler.Alerts = Alerts{Rules: rules, Engine: alert.NewEngine(rules, alert.SystemClock)}
+	env.router.POST("/api/wearable/import", env.handler.ImportWearableData)
+
+	body := `[{"dateTime": "06/01/24 08:00:00", "value": {"bpm": 150, "confidence": 3}}]`
+	rec := env.do(t, http.MethodPost, "/api/wearable/import?format=fitbit", "u1", body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d, want 202: %s", rec.Code, rec.Body)
+	}
+
+	queued, err := env.events.List(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].Topic != "werable" {
+		t.Fatalf("outbox holds %+v, want the one reading and no alert", queued)
+	}
+	if got := queued[0].Headers[event.HeaderSource]; got != event.SourceImport {
+		t.Errorf("source header %q, want %q", got, event.SourceImport)
+	}
+	if env.users.calls != 0 {
+		t.Errorf("sent %d alert notifications for a reading from the past", env.users.calls)
+	}
+
+	// the vitals stream leaves it out
+	hub := stream.NewHub(stream.HubConfig{History: 10})
+	sub, _, err := hub.Subscribe("u1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	feed := stream.Vitals(hub, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := feed(context.Background(), queued[0].Message()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("imported reading was streamed: %s", e.Data)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
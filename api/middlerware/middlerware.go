@@ -1,51 +1,78 @@
 package middleware
 
 import (
+	"api-gateway/api/apierror"
 	tokenn "api-gateway/api/token"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 )
 
+const principalKey = "principal"
+
 type casbinPermission struct {
 	enforcer *casbin.Enforcer
 }
 
-func Check(c *gin.Context) {
+// CheckMiddleware validates the access token, rejects tokens that were
+// revoked by logout or by signing out everywhere, and attaches the caller's
+// Principal to the request. maxTTL is the longest a token lives.
+func CheckMiddleware(verifier tokenn.Verifier, store tokenn.RevocationStore, maxTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken := c.GetHeader("Authorization")
+		if accessToken == "" {
+			apierror.Abort(c, http.StatusUnauthorized, "Authorization is required")
+			return
+		}
 
-	accessToken := c.GetHeader("Authorization")
-	if accessToken == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"error": "Authorization is required",
-		})
-		return
+		claims, err := tokenn.ExtractAccessClaim(verifier, accessToken)
+		if err != nil {
+			apierror.Abort(c, http.StatusUnauthorized, "Invalid token provided")
+			return
+		}
+
+		principal, err := tokenn.PrincipalFromClaims(*claims, accessToken)
+		if err != nil {
+			apierror.Abort(c, http.StatusUnauthorized, "Invalid token provided")
+			return
+		}
+
+		revoked, err := tokenn.IsTokenRevoked(c, store, *claims, accessToken, maxTTL)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if revoked {
+			apierror.Abort(c, http.StatusUnauthorized, "Token has been revoked")
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Request = c.Request.WithContext(tokenn.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
 	}
+}
 
-	_, err := tokenn.ValidateAccessToken(accessToken)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid token provided",
-		})
-		return
+// GetPrincipal returns the caller attached by CheckMiddleware.
+func GetPrincipal(c *gin.Context) (*tokenn.Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
 	}
-	c.Next()
+	principal, ok := value.(*tokenn.Principal)
+	return principal, ok && principal != nil
 }
 
 func (casb *casbinPermission) GetRole(c *gin.Context) (string, int) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
+	principal, ok := GetPrincipal(c)
+	if !ok {
 		return "unauthorized", http.StatusUnauthorized
 	}
-	userId, role, err := tokenn.GetUserInfoFromAccessToken(token)
-	if err != nil {
-		return "error while reding role", 500
-	}
-	fmt.Println(userId, role)
-	c.Set("user_id", userId)
-	return role, 0
+	return principal.Role, 0
 }
 
 func (casb *casbinPermission) CheckPermission(c *gin.Context) (bool, error) {
@@ -57,12 +84,14 @@ func (casb *casbinPermission) CheckPermission(c *gin.Context) (bool, error) {
 	obj := c.FullPath()
 	log.Println(sub, obj, act)
 	ok, err := casb.enforcer.Enforce(sub, obj, act)
+	if err == nil && !ok {
+		// role assignments (g) may also name a single user
+		if principal, found := GetPrincipal(c); found {
+			ok, err = casb.enforcer.Enforce(principal.UserID, obj, act)
+		}
+	}
 	if err != nil {
-		fmt.Println("Error enforcing policy", "error", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"Error": fmt.Sprintf("Internal server error: %v", err),
-		})
-		c.Abort()
+		log.Println("Error enforcing policy", "error", err.Error())
 		return false, err
 	}
 	return ok, nil
@@ -78,14 +107,13 @@ func CheckPermissionMiddleware(enf *casbin.Enforcer) gin.HandlerFunc {
 		result, err := casbHandler.CheckPermission(c)
 
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			c.Error(err)
+			apierror.Abort(c, http.StatusInternalServerError, "")
 			return
 		}
 
 		if !result {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"message": "Forbidden",
-			})
+			apierror.Abort(c, http.StatusForbidden, "Forbidden")
 			return
 		}
 
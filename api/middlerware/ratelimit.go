@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"api-gateway/api/apierror"
+	"api-gateway/api/ratelimit"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit refuses requests over the caller's rate limit or daily quota
+// with 429 and a Retry-After header. The caller is the authenticated user
+// once CheckMiddleware ran, and the client IP before; X-Forwarded-For only
+// counts from the router's trusted proxies. Rate limit state goes out in
+// X-RateLimit-* headers, quota state in X-Quota-* ones.
+//
+// A nil limiter lets every request through, and so does a failing store,
+// which is logged: the limiter should not take the gateway down with it.
+func RateLimit(limiter *ratelimit.Limiter, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		subject, role := "ip:"+c.ClientIP(), ""
+		if principal, ok := GetPrincipal(c); ok {
+			subject, role = "user:"+principal.UserID, principal.Role
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), subject, role, c.FullPath())
+		if err != nil {
+			c.Error(err)
+			logger.Error("Error checking rate limit", "error", err.Error())
+			c.Next()
+			return
+		}
+
+		if d := result.Rate; d != nil {
+			setLimitHeaders(c, "X-RateLimit-", d)
+			if !d.Allowed {
+				c.Header("Retry-After", ceilSeconds(d.RetryAfter))
+				apierror.Abort(c, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+		}
+		if d := result.Quota; d != nil {
+			setLimitHeaders(c, "X-Quota-", d)
+			if !d.Allowed {
+				c.Header("Retry-After", ceilSeconds(d.RetryAfter))
+				apierror.Abort(c, http.StatusTooManyRequests, "Daily quota exceeded")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// setLimitHeaders writes Limit, Remaining and Reset, the seconds until the
+// bucket is full again or the quota renews.
+func setLimitHeaders(c *gin.Context, prefix string, d *ratelimit.Decision) {
+	c.Header(prefix+"Limit", strconv.FormatInt(d.Limit, 10))
+	c.Header(prefix+"Remaining", strconv.FormatInt(max(d.Remaining, 0), 10))
+	c.Header(prefix+"Reset", ceilSeconds(d.Reset))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
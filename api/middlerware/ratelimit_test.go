@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"api-gateway/api/ratelimit"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitedRouter allows one anonymous request a minute per client IP.
+func limitedRouter(t *testing.T, trusted []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(trusted); err != nil {
+		t.Fatal(err)
+	}
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Config{
+		Limits: map[string]ratelimit.Limit{ratelimit.Any: {Rate: 1.0 / 60, Burst: 1}},
+	})
+	router.Use(RateLimit(limiter, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router.GET("/api/auth/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func get(router *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/ping", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	router := limitedRouter(t, nil)
+
+	if code := get(router, "203.0.113.7:4000", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", code)
+	}
+	// a new address in the header does not buy the caller a new bucket
+	if code := get(router, "203.0.113.7:4001", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For: status %d, want 429", code)
+	}
+	if code := get(router, "203.0.113.8:4000", ""); code != http.StatusOK {
+		t.Errorf("another client: status %d, want 200", code)
+	}
+}
+
+func TestRateLimitBehindTrustedProxy(t *testing.T) {
+	router := limitedRouter(t, []string{"10.0.0.1"})
+
+	if code := get(router, "10.0.0.1:4000", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first client: status %d, want 200", code)
+	}
+	if code := get(router, "10.0.0.1:4001", "198.51.100.2"); code != http.StatusOK {
+		t.Errorf("second client through the proxy: status %d, want 200", code)
+	}
+	if code := get(router, "10.0.0.1:4002", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("first client again: status %d, want 429", code)
+	}
+}
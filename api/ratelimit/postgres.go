@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates the rate_limit_buckets and rate_limit_quotas
+// tables when they are missing. Replicas share them, so a caller gets the
+// configured rate across the cluster rather than per replica.
+func NewPostgresStore(db *sql.DB) (Store, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+			key        TEXT PRIMARY KEY,
+			tokens     DOUBLE PRECISION NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			taken      BOOLEAN NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS rate_limit_quotas (
+			key     TEXT PRIMARY KEY,
+			day     DATE NOT NULL,
+			used    BIGINT NOT NULL,
+			counted BOOLEAN NOT NULL
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{db: db}, nil
+}
+
+// refill is the tokens of bucket b once refilled up to $4.
+const refill = `LEAST($2::DOUBLE PRECISION,
+	b.tokens + GREATEST(0, EXTRACT(EPOCH FROM $4::TIMESTAMPTZ - b.updated_at)) * $3::DOUBLE PRECISION)`
+
+// Take refills and takes in one statement, so concurrent requests on
+// different replicas can't both take the last token.
+func (s *postgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (float64, bool, error) {
+	var tokens float64
+	var taken bool
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, taken)
+		VALUES ($1, $2::DOUBLE PRECISION - 1, $4::TIMESTAMPTZ, TRUE)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = `+refill+` - CASE WHEN `+refill+` >= 1 THEN 1 ELSE 0 END,
+			taken = `+refill+` >= 1,
+			updated_at = GREATEST(b.updated_at, $4::TIMESTAMPTZ)
+		RETURNING tokens, taken`,
+		key, limit.Burst, limit.Rate, now).Scan(&tokens, &taken)
+	return tokens, taken, err
+}
+
+func (s *postgresStore) Count(ctx context.Context, key string, day time.Time, max int64) (int64, bool, error) {
+	var used int64
+	var counted bool
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_quotas AS q (key, day, used, counted)
+		VALUES ($1, $2::DATE, 1, TRUE)
+		ON CONFLICT (key) DO UPDATE SET
+			used = CASE WHEN q.day <> $2::DATE THEN 1 WHEN q.used < $3 THEN q.used + 1 ELSE q.used END,
+			counted = q.day <> $2::DATE OR q.used < $3,
+			day = $2::DATE
+		RETURNING used, counted`,
+		key, day.Format("2006-01-02"), max).Scan(&used, &counted)
+	return used, counted, err
+}
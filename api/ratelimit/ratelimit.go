@@ -0,0 +1,222 @@
+// Package ratelimit limits how fast callers may use the gateway, with a
+// token bucket per caller and route group, and how often a day they may
+// call expensive routes.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Any stands for every route group in a limit key.
+const Any = "*"
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate per
+// second. The zero Limit means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit reads a limit such as "10/s", "600/m" or "100/h:20", where the
+// optional number after the colon is the burst; it defaults to the count.
+// "off" is no limit.
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" {
+		return Limit{}, nil
+	}
+	rate, burst, hasBurst := strings.Cut(spec, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+	per, known := units[unit]
+	if !ok || !known {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q is not count/unit with unit s, m or h", spec)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q needs a positive count", spec)
+	}
+	limit := Limit{Rate: float64(n) / per.Seconds(), Burst: n}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("ratelimit: limit %q needs a positive burst", spec)
+		}
+	}
+	return limit, nil
+}
+
+// ParseLimits reads "key=limit" entries, where key is a route group, such
+// as "wearable", or Any, optionally followed by "@role".
+func ParseLimits(entries []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(entries))
+	for _, entry := range entries {
+		key, spec, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("ratelimit: %q is not key=limit", entry)
+		}
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(key)] = limit
+	}
+	return limits, nil
+}
+
+// ParseQuotas reads "route=count" entries, where route is a full route
+// path, such as "/api/health/generate", optionally followed by "@role",
+// and count is how many requests a caller may make per UTC day. "off" is
+// no quota.
+func ParseQuotas(entries []string) (map[string]int64, error) {
+	quotas := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		key, spec, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("ratelimit: %q is not route=count", entry)
+		}
+		spec = strings.TrimSpace(spec)
+		var count int64
+		if spec != "off" {
+			n, err := strconv.ParseInt(spec, 10, 64)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("ratelimit: quota %q needs a positive count or off", entry)
+			}
+			count = n
+		}
+		quotas[strings.TrimSpace(key)] = count
+	}
+	return quotas, nil
+}
+
+type Config struct {
+	// Limits are looked up as group@role, group, *@role and *; the first
+	// one found applies.
+	Limits map[string]Limit
+	// Quotas are looked up as route@role and route.
+	Quotas map[string]int64
+	// Now is time.Now when nil.
+	Now func() time.Time
+}
+
+// Decision is the outcome of one bucket or quota.
+type Decision struct {
+	Allowed bool
+	// Limit is the burst of the bucket or the daily quota.
+	Limit     int64
+	Remaining int64
+	// Reset is how long until the bucket is full again or the quota renews.
+	Reset time.Duration
+	// RetryAfter is how long a refused caller should wait.
+	RetryAfter time.Duration
+}
+
+// Result holds the decisions for a request; nil ones did not apply.
+type Result struct {
+	Rate  *Decision
+	Quota *Decision
+}
+
+func (r Result) Allowed() bool {
+	return (r.Rate == nil || r.Rate.Allowed) && (r.Quota == nil || r.Quota.Allowed)
+}
+
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+	quotas map[string]int64
+	now    func() time.Time
+}
+
+func New(store Store, cfg Config) *Limiter {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Limiter{store: store, limits: cfg.Limits, quotas: cfg.Quotas, now: now}
+}
+
+// Group is the route group of a route path: the segment after /api.
+func Group(route string) string {
+	group := strings.TrimPrefix(route, "/api/")
+	group, _, _ = strings.Cut(group, "/")
+	return group
+}
+
+// Allow counts a request of subject, a caller with the role, to the route.
+// A request the rate limit refuses does not count against the quota.
+func (l *Limiter) Allow(ctx context.Context, subject, role, route string) (Result, error) {
+	var result Result
+	now := l.now()
+
+	group := Group(route)
+	if limit, ok := l.limit(group, role); ok {
+		tokens, allowed, err := l.store.Take(ctx, subject+"|"+group, limit, now)
+		if err != nil {
+			return Result{}, err
+		}
+		d := &Decision{
+			Allowed:   allowed,
+			Limit:     int64(limit.Burst),
+			Remaining: int64(math.Floor(tokens)),
+			Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+		}
+		if !allowed {
+			d.RetryAfter = seconds((1 - tokens) / limit.Rate)
+		}
+		result.Rate = d
+		if !allowed {
+			return result, nil
+		}
+	}
+
+	if max, ok := l.quota(route, role); ok {
+		day := now.UTC().Truncate(24 * time.Hour)
+		used, allowed, err := l.store.Count(ctx, subject+"|"+route, day, max)
+		if err != nil {
+			return Result{}, err
+		}
+		reset := day.Add(24 * time.Hour).Sub(now)
+		d := &Decision{Allowed: allowed, Limit: max, Remaining: max - used, Reset: reset}
+		if !allowed {
+			d.RetryAfter = reset
+		}
+		result.Quota = d
+	}
+	return result, nil
+}
+
+func (l *Limiter) limit(group, role string) (Limit, bool) {
+	for _, key := range []string{group + "@" + role, group, Any + "@" + role, Any} {
+		if limit, ok := l.limits[key]; ok {
+			return limit, limit.Burst > 0
+		}
+	}
+	return Limit{}, false
+}
+
+func (l *Limiter) quota(route, role string) (int64, bool) {
+	for _, key := range []string{route + "@" + role, route} {
+		if max, ok := l.quotas[key]; ok {
+			return max, max > 0
+		}
+	}
+	return 0, false
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
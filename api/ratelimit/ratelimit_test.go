@@ -0,0 +1,218 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+
+// testLimiter returns a limiter on a memory store whose clock only moves
+// when *now is changed.
+func testLimiter(limits map[string]Limit, quotas map[string]int64) (*Limiter, *time.Time) {
+	now := start
+	return New(NewMemoryStore(), Config{Limits: limits, Quotas: quotas, Now: func() time.Time { return now }}), &now
+}
+
+func allow(t *testing.T, l *Limiter, subject, role, route string) Result {
+	t.Helper()
+	result, err := l.Allow(context.Background(), subject, role, route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Limit
+	}{
+		{"10/s", Limit{Rate: 10, Burst: 10}},
+		{"600/m", Limit{Rate: 10, Burst: 600}},
+		{"3600/h:20", Limit{Rate: 1, Burst: 20}},
+		{" 60/m ", Limit{Rate: 1, Burst: 60}},
+		{"off", Limit{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "10", "10/d", "ten/s", "0/s", "-1/m", "10/s:", "10/s:0", "10/s:x"} {
+		if _, err := ParseLimit(spec); err == nil {
+			t.Errorf("ParseLimit(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	got, err := ParseLimits([]string{"*=10/s", "wearable@patient=1/s:5", " health =off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Limit{"*": {10, 10}, "wearable@patient": {1, 5}, "health": {}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for key, limit := range want {
+		if got[key] != limit {
+			t.Errorf("%s: %+v, want %+v", key, got[key], limit)
+		}
+	}
+
+	for _, entry := range []string{"10/s", "=10/s", "*=10/d"} {
+		if _, err := ParseLimits([]string{entry}); err == nil {
+			t.Errorf("ParseLimits(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestParseQuotas(t *testing.T) {
+	got, err := ParseQuotas([]string{"/api/health/generate=20", "/api/health/generate@doctor=off", "/api/import= 5 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"/api/health/generate": 20, "/api/health/generate@doctor": 0, "/api/import": 5}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for key, count := range want {
+		if c, ok := got[key]; !ok || c != count {
+			t.Errorf("%s: %d, want %d", key, c, count)
+		}
+	}
+
+	for _, entry := range []string{"20", "=20", "/api/x=0", "/api/x=-2", "/api/x=many"} {
+		if _, err := ParseQuotas([]string{entry}); err == nil {
+			t.Errorf("ParseQuotas(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	// 3 at once, then one a second
+	l, now := testLimiter(map[string]Limit{Any: {Rate: 1, Burst: 3}}, nil)
+
+	for i := 0; i < 3; i++ {
+		r := allow(t, l, "u1", "patient", "/api/wearable/add")
+		if !r.Allowed() || r.Rate.Remaining != int64(2-i) {
+			t.Fatalf("request %d: %+v, want allowed with %d left", i, r.Rate, 2-i)
+		}
+	}
+	r := allow(t, l, "u1", "patient", "/api/wearable/add")
+	if r.Allowed() || r.Rate.RetryAfter != time.Second || r.Rate.Reset != 3*time.Second {
+		t.Fatalf("over the burst: %+v, want refused, retry after 1s, full in 3s", r.Rate)
+	}
+	if r := allow(t, l, "u2", "patient", "/api/wearable/add"); !r.Allowed() {
+		t.Error("another caller refused; buckets are per caller")
+	}
+	if r := allow(t, l, "u1", "patient", "/api/health/generate"); !r.Allowed() {
+		t.Error("another route group refused; buckets are per group")
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	if r := allow(t, l, "u1", "patient", "/api/wearable/add"); r.Allowed() || r.Rate.RetryAfter != 500*time.Millisecond {
+		t.Errorf("half a token refilled: %+v, want refused for another 500ms", r.Rate)
+	}
+	*now = now.Add(500 * time.Millisecond)
+	if r := allow(t, l, "u1", "patient", "/api/wearable/add"); !r.Allowed() || r.Rate.Remaining != 0 {
+		t.Errorf("a token refilled: %+v, want allowed with none left", r.Rate)
+	}
+
+	// never refilled past the burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		allow(t, l, "u1", "patient", "/api/wearable/add")
+	}
+	if r := allow(t, l, "u1", "patient", "/api/wearable/add"); r.Allowed() {
+		t.Error("idle bucket held more than its burst")
+	}
+}
+
+func TestLimitLookup(t *testing.T) {
+	l, _ := testLimiter(map[string]Limit{
+		"wearable@patient": {Rate: 1, Burst: 1},
+		"wearable":         {Rate: 1, Burst: 2},
+		"*@doctor":         {Rate: 1, Burst: 3},
+		Any:                {Rate: 1, Burst: 4},
+		"health":           {},
+	}, nil)
+
+	tests := []struct {
+		role, route string
+		burst       int64
+	}{
+		{"patient", "/api/wearable/add", 1},
+		{"doctor", "/api/wearable/add", 2},
+		{"doctor", "/api/lifestyle/add", 3},
+		{"patient", "/api/lifestyle/add", 4},
+		// an off limit ends the lookup
+		{"doctor", "/api/health/generate", 0},
+	}
+	for i, tt := range tests {
+		r := allow(t, l, string(rune('a'+i)), tt.role, tt.route)
+		if tt.burst == 0 {
+			if r.Rate != nil {
+				t.Errorf("%s %s: limited by %+v, want no limit", tt.role, tt.route, r.Rate)
+			}
+			continue
+		}
+		if r.Rate == nil || r.Rate.Limit != tt.burst {
+			t.Errorf("%s %s: limit %+v, want burst %d", tt.role, tt.route, r.Rate, tt.burst)
+		}
+	}
+}
+
+func TestDailyQuota(t *testing.T) {
+	l, now := testLimiter(nil, map[string]int64{
+		"/api/health/generate":         2,
+		"/api/health/generate@doctor":  0,
+		"/api/health/generate@analyst": 1,
+	})
+
+	for i := 0; i < 2; i++ {
+		if r := allow(t, l, "u1", "patient", "/api/health/generate"); !r.Allowed() || r.Quota.Remaining != int64(1-i) {
+			t.Fatalf("request %d: %+v, want allowed with %d left", i, r.Quota, 1-i)
+		}
+	}
+	r := allow(t, l, "u1", "patient", "/api/health/generate")
+	// start is 22:00 UTC, so the quota renews at midnight
+	if r.Allowed() || r.Quota.RetryAfter != 2*time.Hour || r.Quota.Reset != 2*time.Hour {
+		t.Fatalf("over the quota: %+v, want refused until midnight", r.Quota)
+	}
+	if r := allow(t, l, "u2", "analyst", "/api/health/generate"); !r.Allowed() || r.Quota.Limit != 1 {
+		t.Errorf("role quota: %+v, want the analyst's quota of 1", r.Quota)
+	}
+	if r := allow(t, l, "u3", "doctor", "/api/health/generate"); r.Quota != nil {
+		t.Errorf("doctor: %+v, want no quota", r.Quota)
+	}
+	if r := allow(t, l, "u1", "patient", "/api/health/get"); r.Quota != nil {
+		t.Errorf("other route: %+v, want no quota", r.Quota)
+	}
+
+	*now = now.Add(2*time.Hour - time.Second)
+	if r := allow(t, l, "u1", "patient", "/api/health/generate"); r.Allowed() {
+		t.Error("quota renewed before midnight UTC")
+	}
+	*now = now.Add(time.Second)
+	if r := allow(t, l, "u1", "patient", "/api/health/generate"); !r.Allowed() || r.Quota.Remaining != 1 || r.Quota.Reset != 24*time.Hour {
+		t.Errorf("after midnight: %+v, want a new day's quota", r.Quota)
+	}
+}
+
+func TestRateLimitedRequestsDontCountAgainstQuota(t *testing.T) {
+	l, now := testLimiter(map[string]Limit{Any: {Rate: 1, Burst: 1}}, map[string]int64{"/api/health/generate": 2})
+
+	allow(t, l, "u1", "patient", "/api/health/generate")
+	if r := allow(t, l, "u1", "patient", "/api/health/generate"); r.Allowed() || r.Quota != nil {
+		t.Fatalf("rate limited: %+v, want refused before the quota is counted", r)
+	}
+	*now = now.Add(time.Second)
+	if r := allow(t, l, "u1", "patient", "/api/health/generate"); !r.Allowed() || r.Quota.Remaining != 0 {
+		t.Errorf("second counted request: %+v, want the last of the quota", r.Quota)
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	// Take refills the bucket under key for the time since it was last
+	// used and takes a token from it when there is one. It returns the
+	// tokens left and whether one was taken. A new bucket starts full.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (float64, bool, error)
+	// Count counts a use of the quota under key on the day, unless max uses
+	// were counted already. It returns the uses so far and whether this one
+	// was counted. Uses from earlier days are forgotten.
+	Count(ctx context.Context, key string, day time.Time, max int64) (int64, bool, error)
+}
+
+// sweepEvery is how many takes pass between sweeps of the idle buckets.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// full is when the bucket will have refilled, after which it can go.
+	full time.Time
+}
+
+type quota struct {
+	day  time.Time
+	used int64
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	quotas  map[string]*quota
+	takes   int
+}
+
+// NewMemoryStore keeps buckets and quotas in process memory, which is
+// right for a single replica; with several, each allows the full rate.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket), quotas: make(map[string]*quota)}
+}
+
+func (m *memoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (float64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.takes++; m.takes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), at: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.at = now
+	}
+	taken := b.tokens >= 1
+	if taken {
+		b.tokens--
+	}
+	b.full = b.at.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+	return b.tokens, taken, nil
+}
+
+func (m *memoryStore) Count(ctx context.Context, key string, day time.Time, max int64) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, ok := m.quotas[key]
+	if !ok || !q.day.Equal(day) {
+		q = &quota{day: day}
+		m.quotas[key] = q
+	}
+	if q.used >= max {
+		return q.used, false, nil
+	}
+	q.used++
+	return q.used, true, nil
+}
+
+// sweep drops the buckets that have refilled and the quotas of past days;
+// both would start over the same way.
+func (m *memoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	for key, q := range m.quotas {
+		if q.day.Before(today) {
+			delete(m.quotas, key)
+		}
+	}
+}
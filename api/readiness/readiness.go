@@ -0,0 +1,155 @@
+// Package readiness checks the dependencies of the gateway, so it only
+// reports itself ready to take traffic while the critical ones answer.
+package readiness
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Probe checks one dependency. It may return details, such as a connection
+// state, whether or not it fails.
+type Probe func(ctx context.Context) (map[string]string, error)
+
+type Check struct {
+	Name string
+	// Critical checks make the gateway unready when they fail; the others
+	// only show up in the report.
+	Critical bool
+	Probe    Probe
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Critical  bool              `json:"critical"`
+	LatencyMS int64             `json:"latency_ms"`
+	Error     string            `json:"error,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+func (r Report) Ready() bool { return r.Status == StatusReady }
+
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// New runs each check with the timeout; a check that runs out of time is
+// down.
+func New(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Check runs every check at once and reports not ready when a critical one
+// is down.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: results}
+	for _, r := range results {
+		if r.Critical && r.Status == StatusDown {
+			report.Status = StatusNotReady
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := check.Probe(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// GRPC checks a backend connection: it must not be failing to connect, and
+// the backend must answer the standard grpc.health.v1 check as serving. A
+// backend without the health service counts as up once it answers.
+func GRPC(conn *grpc.ClientConn) Probe {
+	return func(ctx context.Context) (map[string]string, error) {
+		details := map[string]string{}
+		state := conn.GetState()
+		if state == connectivity.Idle {
+			conn.Connect()
+		}
+		for state == connectivity.Idle || state == connectivity.Connecting {
+			if !conn.WaitForStateChange(ctx, state) {
+				break
+			}
+			state = conn.GetState()
+		}
+		details["state"] = state.String()
+		if state != connectivity.Ready {
+			return details, fmt.Errorf("connection is %s", state)
+		}
+
+		res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if status.Code(err) == codes.Unimplemented {
+			details["health"] = "not implemented"
+			return details, nil
+		}
+		if err != nil {
+			return details, err
+		}
+		details["health"] = res.GetStatus().String()
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return details, fmt.Errorf("backend is %s", res.GetStatus())
+		}
+		return details, nil
+	}
+}
+
+// Database checks that the database answers a ping.
+func Database(db *sql.DB) Probe {
+	return func(ctx context.Context) (map[string]string, error) {
+		return nil, db.PingContext(ctx)
+	}
+}
@@ -0,0 +1,168 @@
+package readiness
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func up(ctx context.Context) (map[string]string, error) { return nil, nil }
+
+func down(ctx context.Context) (map[string]string, error) {
+	return map[string]string{"state": "TRANSIENT_FAILURE"}, errors.New("connection refused")
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"no checks", nil, StatusReady},
+		{"all up", []Check{{"db", true, up}, {"kafka", false, up}}, StatusReady},
+		{"optional down", []Check{{"db", true, up}, {"kafka", false, down}}, StatusReady},
+		{"critical down", []Check{{"db", true, down}, {"kafka", false, up}}, StatusNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := New(time.Second, tt.checks...).Check(context.Background())
+			if report.Status != tt.want || report.Ready() != (tt.want == StatusReady) {
+				t.Errorf("status %s, ready %v, want %s", report.Status, report.Ready(), tt.want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("%d results, want one per check", len(report.Checks))
+			}
+			// results keep the order of the checks
+			for i, r := range report.Checks {
+				if r.Name != tt.checks[i].Name || r.Critical != tt.checks[i].Critical {
+					t.Errorf("result %d is %+v, want check %s", i, r, tt.checks[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	report := New(time.Second, Check{"backend", true, down}).Check(context.Background())
+	r := report.Checks[0]
+	if r.Status != StatusDown || r.Error != "connection refused" || r.Details["state"] != "TRANSIENT_FAILURE" {
+		t.Errorf("result %+v, want down with the probe's error and details", r)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	hang := func(ctx context.Context) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	began := time.Now()
+	report := New(50*time.Millisecond, Check{"a", true, hang}, Check{"b", false, hang}).Check(context.Background())
+	// the checks run at once, so both time out together
+	if took := time.Since(began); took > time.Second {
+		t.Errorf("check took %s, want about one timeout", took)
+	}
+	if report.Ready() {
+		t.Error("ready with a critical check out of time")
+	}
+	for _, r := range report.Checks {
+		if r.Status != StatusDown || r.Error != context.DeadlineExceeded.Error() || r.LatencyMS < 50 {
+			t.Errorf("result %+v, want down after the timeout", r)
+		}
+	}
+}
+
+// backend serves the health service on a local port until the test ends.
+func backend(t *testing.T) (*grpc.ClientConn, *health.Server, *grpc.Server) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, hs, srv
+}
+
+func probe(t *testing.T, p Probe) (map[string]string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return p(ctx)
+}
+
+func TestGRPC(t *testing.T) {
+	conn, hs, _ := backend(t)
+
+	details, err := probe(t, GRPC(conn))
+	if err != nil || details["state"] != "READY" || details["health"] != "SERVING" {
+		t.Errorf("serving backend: %v, %v, want up", details, err)
+	}
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	details, err = probe(t, GRPC(conn))
+	if err == nil || details["health"] != "NOT_SERVING" {
+		t.Errorf("not serving backend: %v, %v, want down", details, err)
+	}
+}
+
+func TestGRPCWithoutHealthService(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	go srv.Serve(lis)
+	defer srv.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	details, err := probe(t, GRPC(conn))
+	if err != nil || details["health"] != "not implemented" {
+		t.Errorf("backend without health service: %v, %v, want up", details, err)
+	}
+}
+
+func TestGRPCUnreachable(t *testing.T) {
+	conn, _, srv := backend(t)
+	srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	details, err := GRPC(conn)(ctx)
+	if err == nil || details["state"] == "READY" {
+		t.Errorf("stopped backend: %v, %v, want down", details, err)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	// nothing listens on port 1
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=x dbname=x sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := probe(t, Database(db)); err == nil {
+		t.Error("database that isn't there answered the ping")
+	}
+}
@@ -3,8 +3,14 @@ package api
 import (
  "api-gateway/api/handler"
  middleware "api-gateway/api/middlerware"
+ "api-gateway/casbin"
  "api-gateway/config"
+ "context"
+ "errors"
  "log/slog"
+ "net/http"
+ "strings"
+ "sync"
 
  "github.com/gin-gonic/gin"
 
@@ -16,12 +22,25 @@ import (
 
 type Controller interface {
  SetupRoutes(handler.Handler, *slog.Logger)
+ // StartServer serves until Shutdown is called, which is not an error.
  StartServer(config.Config) error
+ // Shutdown stops accepting connections and waits for the requests in
+ // flight until ctx is done.
+ Shutdown(ctx context.Context) error
+ ProtectedRoutes() []casbin.Route
 }
 
+// publicPrefixes are served without a token, so they need no policy.
+var publicPrefixes = []string{"/swagger/", "/api/auth/", "/healthz", "/readyz"}
+
 type controllerImpl struct {
  Port   string
  Router *gin.Engine
+
+ mu     sync.Mutex
+ server *http.Server
+ // stopped is set by Shutdown, even when it comes before StartServer
+ stopped bool
 }
 
 func NewController(router *gin.Engine) Controller {
@@ -29,8 +48,59 @@ func NewController(router *gin.Engine) Controller {
 }
 
 func (c *controllerImpl) StartServer(cfg config.Config) error {
+ c.mu.Lock()
+ if c.stopped {
+  c.mu.Unlock()
+  return nil
+ }
  c.Port = cfg.HTTP_PORT
- return c.Router.Run(c.Port)
+ c.server = &http.Server{
+  Addr:              c.Port,
+  Handler:           c.Router.Handler(),
+  ReadTimeout:       cfg.HTTP_READ_TIMEOUT,
+  ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+  WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+  IdleTimeout:       cfg.HTTP_IDLE_TIMEOUT,
+ }
+ server := c.server
+ c.mu.Unlock()
+
+ err := server.ListenAndServe()
+ if errors.Is(err, http.ErrServerClosed) {
+  return nil
+ }
+ return err
+}
+
+func (c *controllerImpl) Shutdown(ctx context.Context) error {
+ c.mu.Lock()
+ c.stopped = true
+ server := c.server
+ c.mu.Unlock()
+
+ if server == nil {
+  return nil
+ }
+ return server.Shutdown(ctx)
+}
+
+// ProtectedRoutes returns the registered routes that go through the
+// permission middleware.
+func (c *controllerImpl) ProtectedRoutes() []casbin.Route {
+ var routes []casbin.Route
+ for _, route := range c.Router.Routes() {
+  public := false
+  for _, prefix := range publicPrefixes {
+   if strings.HasPrefix(route.Path, prefix) {
+    public = true
+    break
+   }
+  }
+  if !public {
+   routes = append(routes, casbin.Route{Method: route.Method, Path: route.Path})
+  }
+ }
+ return routes
 }
 // @title Api Gateway
 // @version 1.0
@@ -41,11 +111,33 @@ func (c *controllerImpl) StartServer(cfg config.Config) error {
 // @BasePath /api/v1
 // @schemes http
 func (c *controllerImpl) SetupRoutes(h handler.Handler, logger *slog.Logger) {
+    c.Router.Use(middleware.RequestID())
+
     c.Router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-    router := c.Router.Group("/api")
-    router.Use(middleware.Check)
+    // probes are public: orchestrators call them without a token
+    c.Router.GET("/healthz", h.Healthz)
+    c.Router.GET("/readyz", h.Readyz)
+
+    api := c.Router.Group("/api")
+
+    // auth routes are public: they are how a client gets a token in the first place,
+    // so they are rate limited per client IP
+    auth := api.Group("/auth")
+    auth.Use(middleware.RateLimit(h.Limiter, h.Logger))
+    {
+        auth.POST("/register", h.Register)
+        auth.POST("/login", h.Login)
+        auth.POST("/refresh", h.RefreshToken)
+        auth.POST("/logout", h.Logout)
+        auth.POST("/logout-all", h.LogoutAll)
+    }
+
+    router := api.Group("")
+    router.Use(middleware.CheckMiddleware(h.Verifier, h.Revoked, h.Tokens.MaxTTL()))
     router.Use(middleware.CheckPermissionMiddleware(h.Enforcer))
+    // limited per user, and only once permitted, so refused requests don't use up quotas
+    router.Use(middleware.RateLimit(h.Limiter, h.Logger))
 
     users := router.Group("/user")
     {
@@ -60,6 +152,8 @@ func (c *controllerImpl) SetupRoutes(h handler.Handler, logger *slog.Logger) {
         health.GET("/getRealtimeHealthMonitoring/:user_id", h.GetRealtimeHealthMonitoring)
         health.GET("/getDailyHealthSummary/:date", h.GetDailyHealthSummary)
         health.GET("/getWeeklyHealthSummary/:start_date/:end_date", h.GetWeeklyHealthSummary)
+        health.POST("/realtime", h.AddRealTimeData)
+        health.GET("/realtime/:user_id", h.GetRealTimeData)
     }
 
     lifestyle := router.Group("/lifestyle")
@@ -83,15 +177,57 @@ func (c *controllerImpl) SetupRoutes(h handler.Handler, logger *slog.Logger) {
     wearable := router.Group("/wearable")
     {
         wearable.POST("/add", h.AddWearableData)
+        wearable.POST("/bulk", h.AddWearableDataBulk)
+        wearable.POST("/import", h.ImportWearableData)
         wearable.GET("/get", h.GetWearableData)
         wearable.GET("/getById/:id", h.GetWearableDataById)
         wearable.PUT("/update", h.UpdateWearableData)
         wearable.DELETE("/delete/:id", h.DeleteWearableData)
+        wearable.GET("/stream", h.StreamVitals)
+    }
+
+    careTeam := router.Group("/careTeam")
+    {
+        careTeam.POST("/grant", h.GrantCareTeam)
+        careTeam.GET("/list", h.GetCareTeam)
+        careTeam.DELETE("/revoke/:doctor_id", h.RevokeCareTeam)
+    }
+
+    admin := router.Group("/admin")
+    {
+        admin.GET("/policies", h.GetPolicies)
+        admin.POST("/policies", h.AddPolicy)
+        admin.DELETE("/policies", h.RemovePolicy)
+        admin.PUT("/policies", h.ReplacePolicies)
+        admin.GET("/roles", h.GetRoles)
+        admin.POST("/roles", h.AddRole)
+        admin.DELETE("/roles", h.RemoveRole)
+        admin.GET("/audit", h.GetPolicyAudit)
+        admin.POST("/doctors", h.RegisterDoctor)
+        admin.GET("/outbox", h.GetOutboxEvents)
+        admin.POST("/outbox/:id/replay", h.ReplayOutboxEvent)
+        admin.POST("/outbox/replay", h.ReplayDeadOutboxEvents)
     }
 
     notifications := router.Group("/notifications")
     {
         notifications.GET("/getAll", h.GetAllNotifications)
         notifications.GET("/new", h.GetAndMarkNotificationAsRead)
+        notifications.GET("/stream", h.StreamNotifications)
+    }
+
+    router.GET("/measurements", h.GetMeasurements)
+
+    alerts := router.Group("/alerts")
+    {
+        alerts.GET("/rules", h.GetAlertRules)
+        alerts.POST("/rules", h.AddAlertRule)
+        alerts.PUT("/rules/:id", h.UpdateAlertRule)
+        alerts.DELETE("/rules/:id", h.DeleteAlertRule)
+    }
+
+    jobs := router.Group("/jobs")
+    {
+        jobs.GET("/:id", h.GetJob)
     }
 }
\ No newline at end of file
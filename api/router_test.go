@@ -0,0 +1,40 @@
+package api
+
+import (
+	"api-gateway/api/handler"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSetupRoutesEmptyHandler sets the routes up the way check-policies
+// does, with nothing but a logger.
+func TestSetupRoutesEmptyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	controller := NewController(gin.New())
+	controller.SetupRoutes(handler.Handler{Logger: logger}, logger)
+
+	routes := controller.ProtectedRoutes()
+	if len(routes) == 0 {
+		t.Fatal("no protected routes")
+	}
+	for _, route := range routes {
+		for _, prefix := range publicPrefixes {
+			if strings.HasPrefix(route.Path, prefix) {
+				t.Errorf("public route %s %s listed as protected", route.Method, route.Path)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	controller.(*controllerImpl).Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz status %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,48 @@
+package stream
+
+import (
+	"api-gateway/kafka/consumer"
+	"api-gateway/kafka/event"
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"log/slog"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Alerts feeds hub from the alerts topic, one "alert" event per raised
+// alert, keyed by the patient. Alerts go to the vitals hub, next to the
+// readings that raised them, so they carry the same attributes.
+func Alerts(hub *Hub, logger *slog.Logger) consumer.Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		env, payload, err := event.Decode(msg)
+		if err != nil {
+			logger.Warn("Skipping undecodable alert event", "offset", msg.Offset, "error", err.Error())
+			return nil
+		}
+		alert, ok := payload.(*structpb.Struct)
+		fields := alert.GetFields()
+		patientID := fields["patient_id"].GetStringValue()
+		if !ok || patientID == "" {
+			logger.Warn("Skipping alert event without a patient", "offset", msg.Offset)
+			return nil
+		}
+
+		data, err := alert.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		hub.Publish(patientID, Event{
+			// ids of the werable topic are partition-offset pairs, so the
+			// event id keeps alerts apart from them
+			ID:   env.ID,
+			Type: "alert",
+			Data: data,
+			Attrs: map[string]string{
+				AttrDataType:   fields["data_type"].GetStringValue(),
+				AttrDeviceType: fields["device_type"].GetStringValue(),
+			},
+		})
+		return nil
+	}
+}
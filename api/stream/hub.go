@@ -0,0 +1,257 @@
+// Package stream fans events out to the server-sent event connections of
+// the users they belong to.
+package stream
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooManyConnections = errors.New("stream: too many connections for user")
+	// ErrClosed is returned by Subscribe once the hub is closed.
+	ErrClosed = errors.New("stream: hub closed")
+)
+
+// Event is one message on a stream. ID must be the same on every replica,
+// so a client can resume on any of them with Last-Event-ID.
+type Event struct {
+	ID   string
+	Type string
+	Data []byte
+	// Attrs are what subscribers can filter on, e.g. "data_type".
+	Attrs map[string]string
+}
+
+// Filter selects events by attribute. An event matches when, for every
+// attribute in the filter, its value is one of the listed ones, ignoring
+// case. A nil filter matches everything.
+type Filter map[string][]string
+
+func (f Filter) Match(e Event) bool {
+	for attr, values := range f {
+		if len(values) == 0 {
+			continue
+		}
+		matched := false
+		for _, v := range values {
+			if strings.EqualFold(e.Attrs[attr], v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+type HubConfig struct {
+	// MaxPerUser caps the open connections of one user on this replica.
+	MaxPerUser int
+	// History is how many recent events per user are kept for clients that
+	// resume with Last-Event-ID.
+	History int
+	// HistoryTTL is how long the history of a user without connections
+	// outlives their last event or connection; 10 minutes when zero.
+	HistoryTTL time.Duration
+	// Buffer is how many events a connection may fall behind before it is
+	// dropped. The client reconnects and catches up from the history.
+	Buffer int
+	// Now is time.Now when nil.
+	Now func() time.Time
+}
+
+// Hub keeps the open subscriptions and recent events of every user.
+type Hub struct {
+	mu      sync.Mutex
+	cfg     HubConfig
+	subs    map[string]map[*Subscription]struct{}
+	history map[string]*history
+	swept   time.Time
+	closed  bool
+}
+
+// history is what a user missed lately. at is when they last had an event
+// or closed a connection.
+type history struct {
+	events []Event
+	at     time.Time
+}
+
+func NewHub(cfg HubConfig) *Hub {
+	if cfg.MaxPerUser <= 0 {
+		cfg.MaxPerUser = 5
+	}
+	if cfg.History < 0 {
+		cfg.History = 0
+	}
+	if cfg.HistoryTTL <= 0 {
+		cfg.HistoryTTL = 10 * time.Minute
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 64
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Hub{
+		cfg:     cfg,
+		subs:    make(map[string]map[*Subscription]struct{}),
+		history: make(map[string]*history),
+		swept:   cfg.Now(),
+	}
+}
+
+// Subscription is one open connection. Events arrive on C until Done is
+// closed, either by Close or because the connection fell too far behind.
+type Subscription struct {
+	C <-chan Event
+
+	hub    *Hub
+	userID string
+	filter Filter
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Subscribe opens a subscription for the events of userID that match
+// filter. When lastEventID is set it also returns the kept matching events
+// that came after it, or all of them when it is no longer known.
+func (h *Hub) Subscribe(userID, lastEventID string, filter Filter) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, ErrClosed
+	}
+	if len(h.subs[userID]) >= h.cfg.MaxPerUser {
+		return nil, nil, ErrTooManyConnections
+	}
+
+	events := make(chan Event, h.cfg.Buffer)
+	sub := &Subscription{C: events, hub: h, userID: userID, filter: filter, events: events, done: make(chan struct{})}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+
+	var missed []Event
+	if kept := h.history[userID]; lastEventID != "" && kept != nil {
+		start := 0
+		for i, e := range kept.events {
+			if e.ID == lastEventID {
+				start = i + 1
+				break
+			}
+		}
+		for _, e := range kept.events[start:] {
+			if filter.Match(e) {
+				missed = append(missed, e)
+			}
+		}
+	}
+	return sub, missed, nil
+}
+
+// Publish sends e to every connection of userID and keeps it for resuming
+// clients. Connections whose buffer is full are dropped rather than waited
+// for, so one slow client can't hold up the others.
+func (h *Hub) Publish(userID string, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.cfg.Now()
+	h.sweep(now)
+	if h.cfg.History > 0 {
+		kept := h.history[userID]
+		if kept == nil {
+			kept = &history{}
+			h.history[userID] = kept
+		}
+		kept.events = append(kept.events, e)
+		if len(kept.events) > h.cfg.History {
+			kept.events = append([]Event(nil), kept.events[len(kept.events)-h.cfg.History:]...)
+		}
+		kept.at = now
+	}
+
+	for sub := range h.subs[userID] {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones, so open streams
+// don't hold up a shutdown. Their clients reconnect, to another replica,
+// and resume with Last-Event-ID.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Connections returns how many connections userID has open.
+func (h *Hub) Connections(userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[userID])
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if subs, ok := h.subs[sub.userID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subs, sub.userID)
+		}
+	}
+	// the client may come back for what it missed
+	if kept := h.history[sub.userID]; kept != nil {
+		kept.at = h.cfg.Now()
+	}
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// sweep forgets the history of users who have no connection and had no
+// event for HistoryTTL, so it is kept for at most twice that. Callers hold
+// mu.
+func (h *Hub) sweep(now time.Time) {
+	if now.Sub(h.swept) < h.cfg.HistoryTTL {
+		return
+	}
+	h.swept = now
+
+	cutoff := now.Add(-h.cfg.HistoryTTL)
+	for userID, kept := range h.history {
+		if len(h.subs[userID]) == 0 && kept.at.Before(cutoff) {
+			delete(h.history, userID)
+		}
+	}
+}
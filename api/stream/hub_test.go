@@ -0,0 +1,189 @@
+package stream
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+func vital(id int, dataType string) Event {
+	return Event{ID: strconv.Itoa(id), Type: "vital", Attrs: map[string]string{"data_type": dataType}}
+}
+
+// received drains what sub has been sent so far.
+func received(sub *Subscription) []string {
+	var ids []string
+	for {
+		select {
+		case e := <-sub.C:
+			ids = append(ids, e.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func ids(events []Event) []string {
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func subscribe(t *testing.T, h *Hub, userID, lastEventID string, filter Filter) (*Subscription, []Event) {
+	t.Helper()
+	sub, missed, err := h.Subscribe(userID, lastEventID, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sub.Close)
+	return sub, missed
+}
+
+func TestFanOut(t *testing.T) {
+	h := NewHub(HubConfig{History: 10})
+	all, _ := subscribe(t, h, "u1", "", nil)
+	hearts, _ := subscribe(t, h, "u1", "", Filter{"data_type": {"HEART_RATE"}})
+	other, _ := subscribe(t, h, "u2", "", nil)
+
+	h.Publish("u1", vital(1, "heart_rate"))
+	h.Publish("u1", vital(2, "steps"))
+	h.Publish("u2", vital(3, "steps"))
+
+	if got := received(all); !equal(got, []string{"1", "2"}) {
+		t.Errorf("unfiltered connection got %v, want 1 and 2", got)
+	}
+	if got := received(hearts); !equal(got, []string{"1"}) {
+		t.Errorf("heart rate connection got %v, want 1", got)
+	}
+	if got := received(other); !equal(got, []string{"3"}) {
+		t.Errorf("other user got %v, want only their event 3", got)
+	}
+}
+
+func TestSlowConnectionIsDropped(t *testing.T) {
+	h := NewHub(HubConfig{Buffer: 2})
+	slow, _ := subscribe(t, h, "u1", "", nil)
+	fast, _ := subscribe(t, h, "u1", "", nil)
+
+	h.Publish("u1", vital(1, "steps"))
+	h.Publish("u1", vital(2, "steps"))
+	received(fast)
+	h.Publish("u1", vital(3, "steps"))
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Error("a connection past its buffer is still open")
+	}
+	if got := received(fast); !equal(got, []string{"3"}) {
+		t.Errorf("the other connection got %v, want 3", got)
+	}
+	if n := h.Connections("u1"); n != 1 {
+		t.Errorf("%d connections open, want 1", n)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	h := NewHub(HubConfig{History: 3})
+	for i := 1; i <= 5; i++ {
+		h.Publish("u1", vital(i, []string{"steps", "heart_rate"}[i%2]))
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		filter      Filter
+		want        []string
+	}{
+		{"fresh connection", "", nil, nil},
+		{"known id", "3", nil, []string{"4", "5"}},
+		{"newest id", "5", nil, nil},
+		// 1 and 2 are past the history, so everything kept is sent
+		{"forgotten id", "1", nil, []string{"3", "4", "5"}},
+		{"filtered", "3", Filter{"data_type": {"steps"}}, []string{"4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, missed := subscribe(t, h, "u1", tt.lastEventID, tt.filter)
+			if got := ids(missed); !equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, missed := subscribe(t, h, "u2", "3", nil); len(missed) != 0 {
+		t.Errorf("another user replayed %v, want nothing", ids(missed))
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	h := NewHub(HubConfig{MaxPerUser: 2})
+	first, _ := subscribe(t, h, "u1", "", nil)
+	subscribe(t, h, "u1", "", nil)
+
+	if _, _, err := h.Subscribe("u1", "", nil); !errors.Is(err, ErrTooManyConnections) {
+		t.Fatalf("third connection: %v, want ErrTooManyConnections", err)
+	}
+	subscribe(t, h, "u2", "", nil)
+
+	first.Close()
+	first.Close()
+	subscribe(t, h, "u1", "", nil)
+
+	h.Close()
+	if _, _, err := h.Subscribe("u3", "", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: %v, want ErrClosed", err)
+	}
+	if n := h.Connections("u1"); n != 0 {
+		t.Errorf("%d connections open after Close, want 0", n)
+	}
+}
+
+func TestIdleHistoryIsForgotten(t *testing.T) {
+	now := start
+	h := NewHub(HubConfig{History: 10, HistoryTTL: 10 * time.Minute, Now: func() time.Time { return now }})
+
+	h.Publish("gone", vital(1, "steps"))
+	h.Publish("listening", vital(2, "steps"))
+	sub, _ := subscribe(t, h, "listening", "", nil)
+	h.Publish("reconnecting", vital(3, "steps"))
+	early, _ := subscribe(t, h, "reconnecting", "", nil)
+
+	now = now.Add(8 * time.Minute)
+	early.Close()
+	now = now.Add(3 * time.Minute)
+	h.Publish("active", vital(4, "steps"))
+
+	for user, kept := range map[string]bool{"gone": false, "listening": true, "reconnecting": true, "active": true} {
+		if _, ok := h.history[user]; ok != kept {
+			t.Errorf("history of %s kept = %v, want %v", user, ok, kept)
+		}
+	}
+	if _, missed := subscribe(t, h, "reconnecting", "0", nil); !equal(ids(missed), []string{"3"}) {
+		t.Errorf("a client back within the TTL replayed %v, want 3", ids(missed))
+	}
+
+	sub.Close()
+	now = now.Add(21 * time.Minute)
+	h.Publish("active", vital(5, "steps"))
+	if _, ok := h.history["listening"]; ok {
+		t.Error("history of a user gone for longer than the TTL is still kept")
+	}
+}
@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"api-gateway/genproto/user"
+	"api-gateway/kafka/consumer"
+	"api-gateway/kafka/event"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// Notifications feeds hub from the notification topic. Messages that are
+// not notification events are logged and skipped.
+func Notifications(hub *Hub, logger *slog.Logger) consumer.Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		env, payload, err := event.Decode(msg)
+		if err != nil {
+			logger.Warn("Skipping undecodable notification", "offset", msg.Offset, "error", err.Error())
+			return nil
+		}
+		req, ok := payload.(*user.CreateNotificationsReq)
+		if !ok || req.UserId == "" {
+			logger.Warn("Skipping notification without a user", "offset", msg.Offset)
+			return nil
+		}
+
+		data, err := json.Marshal(models.NotificationEvent{Message: req.Message, CreatedAt: env.OccurredAt})
+		if err != nil {
+			return err
+		}
+		hub.Publish(req.UserId, Event{ID: MessageID(msg), Type: "notification", Data: data})
+		return nil
+	}
+}
+
+// MessageID names an event after its place in the topic, which every
+// replica sees the same.
+func MessageID(msg kafka.Message) string {
+	return fmt.Sprintf("%d-%d", msg.Partition, msg.Offset)
+}
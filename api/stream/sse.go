@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeSSE writes the missed events and then everything sub receives as
+// server-sent events, until the client goes away or sub is dropped. A
+// comment line goes out every heartbeat so proxies keep the connection.
+func ServeSSE(ctx *gin.Context, sub *Subscription, missed []Event, heartbeat time.Duration) {
+	// a stream outlives any write timeout the server has
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	w := ctx.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, e := range missed {
+		writeEvent(w, e)
+	}
+	w.Flush()
+
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case e := <-sub.C:
+			writeEvent(w, e)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		w.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, e Event) {
+	fmt.Fprintf(w, "id: %s\n", e.ID)
+	if e.Type != "" {
+		fmt.Fprintf(w, "event: %s\n", e.Type)
+	}
+	// a newline in the data would end the field early
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
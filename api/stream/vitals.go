@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"api-gateway/genproto/health"
+	"api-gateway/kafka/consumer"
+	"api-gateway/kafka/event"
+	kafka "api-gateway/kafka/producer"
+	"api-gateway/models"
+	"context"
+	"encoding/json"
+	"log/slog"
+)
+
+// Attributes vitals stream subscribers can filter on.
+const (
+	AttrDataType   = "data_type"
+	AttrDeviceType = "device_type"
+)
+
+// Vitals feeds hub from the werable topic, one "reading" event per
+// wearable record, keyed by the patient. Imported readings are history and
+// are left out.
+func Vitals(hub *Hub, logger *slog.Logger) consumer.Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		if msg.Headers[event.HeaderSource] == event.SourceImport {
+			return nil
+		}
+		env, payload, err := event.Decode(msg)
+		if err != nil {
+			logger.Warn("Skipping undecodable wearable event", "offset", msg.Offset, "error", err.Error())
+			return nil
+		}
+		req, ok := payload.(*health.AddWearableDataReq)
+		if !ok || req.UserId == "" {
+			logger.Warn("Skipping wearable event without a user", "offset", msg.Offset)
+			return nil
+		}
+
+		data, err := json.Marshal(models.VitalReading{
+			UserID:     req.UserId,
+			DataType:   req.DataType,
+			DeviceType: req.DeviceType,
+			DataValue:  req.DataValue,
+			RecordedAt: env.OccurredAt,
+		})
+		if err != nil {
+			return err
+		}
+		hub.Publish(req.UserId, Event{
+			ID:    MessageID(msg),
+			Type:  "reading",
+			Data:  data,
+			Attrs: map[string]string{AttrDataType: req.DataType, AttrDeviceType: req.DeviceType},
+		})
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package tokenn
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type IssuerConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Algorithm signs the tokens. HS* algorithms use HMACSecret, the others
+	// the key in PrivateKeyFile.
+	Algorithm      string
+	HMACSecret     string
+	PrivateKeyFile string
+	// KeyID is put in the kid header so verifiers can pick the key from a JWKS.
+	KeyID    string
+	Issuer   string
+	Audience string
+}
+
+// Issuer mints token pairs for the refresh endpoint.
+type Issuer struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+
+	method   jwt.SigningMethod
+	key      interface{}
+	keyID    string
+	issuer   string
+	audience string
+}
+
+func NewIssuer(cfg IssuerConfig) (*Issuer, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil || cfg.Algorithm == "none" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", cfg.Algorithm)
+	}
+
+	var key interface{}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if cfg.HMACSecret == "" {
+			return nil, errors.New("no HMAC secret configured")
+		}
+		key = []byte(cfg.HMACSecret)
+	} else {
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("%s needs a private key file", cfg.Algorithm)
+		}
+		signer, err := LoadPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key = signer
+	}
+	if _, err := jwt.New(method).SignedString(key); err != nil {
+		return nil, fmt.Errorf("%s key: %w", cfg.Algorithm, err)
+	}
+
+	return &Issuer{
+		AccessTTL:  cfg.AccessTTL,
+		RefreshTTL: cfg.RefreshTTL,
+		method:     method,
+		key:        key,
+		keyID:      cfg.KeyID,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+	}, nil
+}
+
+// MaxTTL is the longest a token lives, so the longest a sign out must be
+// remembered. It is 0 without an issuer, as when routes are only set up to
+// be listed.
+func (i *Issuer) MaxTTL() time.Duration {
+	if i == nil {
+		return 0
+	}
+	return max(i.AccessTTL, i.RefreshTTL)
+}
+
+func (i *Issuer) GenerateTokens(userID, role string) (*Tokens, error) {
+	now := time.Now()
+
+	access, err := i.sign(i.claims(userID, role, TypeAccess, now, i.AccessTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := i.sign(i.claims(userID, role, TypeRefresh, now, i.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (i *Issuer) claims(userID, role, tokenType string, now time.Time, ttl time.Duration) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"user_id":    userID,
+		"role":       role,
+		"token_type": tokenType,
+		"jti":        newTokenID(),
+		"iat":        now.Unix(),
+		"nbf":        now.Unix(),
+		"exp":        now.Add(ttl).Unix(),
+	}
+	if i.issuer != "" {
+		claims["iss"] = i.issuer
+	}
+	if i.audience != "" {
+		claims["aud"] = i.audience
+	}
+	return claims
+}
+
+func (i *Issuer) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(i.method, claims)
+	if i.keyID != "" {
+		token.Header["kid"] = i.keyID
+	}
+	return token.SignedString(i.key)
+}
+
+func newTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,182 @@
+package tokenn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	return writeFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func pkix(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// rsaJWK is the public half of key as a JWKS entry.
+func rsaJWK(kid string, key *rsa.PrivateKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...jwk) {
+	t.Helper()
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	rsaPriv, ecPriv := rsaKey(t), ecKey(t)
+
+	tests := []struct {
+		name      string
+		blockType string
+		der       []byte
+		wantErr   string
+	}{
+		{"PKIX RSA", "PUBLIC KEY", pkix(t, &rsaPriv.PublicKey), ""},
+		{"PKIX ECDSA", "PUBLIC KEY", pkix(t, &ecPriv.PublicKey), ""},
+		{"PKCS1 RSA", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaPriv.PublicKey), ""},
+		{"garbage", "PUBLIC KEY", []byte("not a key"), "asn1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadPublicKey(writePEM(t, tt.blockType, tt.der))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want one about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || key == nil {
+				t.Errorf("key %v, error %v", key, err)
+			}
+		})
+	}
+
+	if _, err := LoadPublicKey(writeFile(t, "empty.pem", []byte("no pem here"))); err == nil {
+		t.Error("a file without PEM data was accepted")
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	rsaPriv, ecPriv := rsaKey(t), ecKey(t)
+	ecDER, err := x509.MarshalECPrivateKey(ecPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		blockType string
+		der       []byte
+	}{
+		{"PKCS1 RSA", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPriv)},
+		{"SEC1 ECDSA", "EC PRIVATE KEY", ecDER},
+		{"PKCS8 RSA", "PRIVATE KEY", pkcs8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPrivateKey(writePEM(t, tt.blockType, tt.der)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	rsaPriv, ecPriv := rsaKey(t), ecKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path,
+		rsaJWK("rsa", rsaPriv),
+		jwk{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64(ecPriv.X.Bytes()), Y: b64(ecPriv.Y.Bytes())},
+		jwk{Kty: "oct", Kid: "hmac", K: b64([]byte("secret"))},
+		// neither can sign anything we accept
+		jwk{Kty: "RSA", Kid: "encryption", Use: "enc", N: "AQAB", E: "AQAB"},
+		jwk{Kty: "RSA", N: "AQAB", E: "AQAB"},
+	)
+
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("loaded %d keys, want rsa, ec and hmac", len(keys))
+	}
+	if key, ok := keys["rsa"].(*rsa.PublicKey); !ok || !key.Equal(&rsaPriv.PublicKey) {
+		t.Errorf("rsa key %v, want the public key", keys["rsa"])
+	}
+	if key, ok := keys["ec"].(*ecdsa.PublicKey); !ok || !key.Equal(&ecPriv.PublicKey) {
+		t.Errorf("ec key %v, want the public key", keys["ec"])
+	}
+	if key, ok := keys["hmac"].([]byte); !ok || string(key) != "secret" {
+		t.Errorf("hmac key %v, want the secret", keys["hmac"])
+	}
+
+	for name, bad := range map[string]jwk{
+		"unknown curve":    {Kty: "EC", Kid: "k", Crv: "P-192", X: "AQ", Y: "AQ"},
+		"unknown key type": {Kty: "OKP", Kid: "k"},
+		"missing modulus":  {Kty: "RSA", Kid: "k", E: "AQAB"},
+	} {
+		writeJWKS(t, path, bad)
+		if _, err := LoadJWKS(path); err == nil {
+			t.Errorf("%s: loaded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package tokenn
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore returns a RevocationStore shared by every gateway
+// instance. The tables are created when missing.
+func NewPostgresStore(db *sql.DB) (RevocationStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS revoked_tokens (
+			token_id   TEXT PRIMARY KEY,
+			expires_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS revoked_users (
+			user_id    TEXT PRIMARY KEY,
+			revoked_at TIMESTAMPTZ NOT NULL
+		);
+		ALTER TABLE revoked_users ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresStore{db: db}, nil
+}
+
+func (p *postgresStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < now()`)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := p.db.ExecContext(ctx,
+		`INSERT INTO revoked_tokens (token_id, expires_at) VALUES ($1, $2) ON CONFLICT (token_id) DO NOTHING`,
+		tokenID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (p *postgresStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = $1 AND expires_at > now())`,
+		tokenID).Scan(&exists)
+	return exists, err
+}
+
+func (p *postgresStore) RevokeUser(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM revoked_users WHERE expires_at < now()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx,
+		`INSERT INTO revoked_users (user_id, revoked_at, expires_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id) DO UPDATE SET revoked_at = EXCLUDED.revoked_at, expires_at = EXCLUDED.expires_at`,
+		userID, at.Truncate(time.Second), expiresAt)
+	return err
+}
+
+func (p *postgresStore) UserRevokedAt(ctx context.Context, userID string) (time.Time, bool, error) {
+	var at time.Time
+	// markers from before expires_at existed have none and are kept
+	err := p.db.QueryRowContext(ctx,
+		`SELECT revoked_at FROM revoked_users WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		userID).Scan(&at)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return at, true, nil
+}
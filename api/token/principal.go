@@ -0,0 +1,85 @@
+package tokenn
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrMalformedClaims = errors.New("token claims are malformed")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID    string
+	Role      string
+	Scopes    []string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// PrincipalFromClaims builds a Principal out of verified claims. user_id and
+// role must be non-empty strings; scopes may come as a space separated
+// "scope" string or as a "scopes" array.
+func PrincipalFromClaims(claims jwt.MapClaims, tokenStr string) (*Principal, error) {
+	userID, ok := claims["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, ErrMalformedClaims
+	}
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		return nil, ErrMalformedClaims
+	}
+
+	var scopes []string
+	switch s := claims["scope"].(type) {
+	case nil:
+	case string:
+		scopes = strings.Fields(s)
+	default:
+		return nil, ErrMalformedClaims
+	}
+	switch s := claims["scopes"].(type) {
+	case nil:
+	case []interface{}:
+		for _, v := range s {
+			scope, ok := v.(string)
+			if !ok {
+				return nil, ErrMalformedClaims
+			}
+			scopes = append(scopes, scope)
+		}
+	default:
+		return nil, ErrMalformedClaims
+	}
+
+	return &Principal{
+		UserID:    userID,
+		Role:      role,
+		Scopes:    scopes,
+		TokenID:   TokenID(claims, tokenStr),
+		ExpiresAt: ExpiresAt(claims, time.Time{}),
+	}, nil
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,129 @@
+package tokenn
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// RevocationStore keeps track of tokens that must no longer be accepted,
+// either one by one (logout, refresh rotation) or for every token of a user
+// issued before some moment (sign out everywhere).
+type RevocationStore interface {
+	// Revoke denylists a token until it expires. It reports false when the
+	// token was already revoked, which lets refresh rotation detect reuse.
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error)
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeUser rejects every token of the user issued before at. at is
+	// kept to the second, like iat, and the marker is dropped at expiresAt,
+	// once every token it rejects has expired anyway.
+	RevokeUser(ctx context.Context, userID string, at, expiresAt time.Time) error
+	UserRevokedAt(ctx context.Context, userID string) (time.Time, bool, error)
+}
+
+// IsTokenRevoked checks both the token itself and the sign-out-everywhere
+// marker of its owner. A token without iat is taken to be issued maxTTL
+// before it expires; one without either can't be dated and is revoked.
+func IsTokenRevoked(ctx context.Context, store RevocationStore, claims jwt.MapClaims, tokenStr string, maxTTL time.Duration) (bool, error) {
+	revoked, err := store.IsRevoked(ctx, TokenID(claims, tokenStr))
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		return false, nil
+	}
+
+	at, ok, err := store.UserRevokedAt(ctx, userID)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	issuedAt, ok := claimTime(claims, "iat")
+	if !ok {
+		expiresAt, ok := claimTime(claims, "exp")
+		if !ok {
+			return true, nil
+		}
+		issuedAt = expiresAt.Add(-maxTTL)
+	}
+	// iat has whole seconds, so a token issued in the second of the sign-out
+	// is kept rather than one issued right after it rejected
+	return issuedAt.Before(at), nil
+}
+
+// revokedUser is the sign-out-everywhere marker of a user.
+type revokedUser struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]revokedUser
+}
+
+func NewMemoryStore() RevocationStore {
+	return &memoryStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]revokedUser),
+	}
+}
+
+func (m *memoryStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(time.Now())
+	if _, ok := m.tokens[tokenID]; ok {
+		return false, nil
+	}
+	m.tokens[tokenID] = expiresAt
+	return true, nil
+}
+
+func (m *memoryStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt, ok := m.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (m *memoryStore) RevokeUser(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(time.Now())
+	m.users[userID] = revokedUser{at: at.Truncate(time.Second), expiresAt: expiresAt}
+	return nil
+}
+
+func (m *memoryStore) UserRevokedAt(ctx context.Context, userID string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok || !time.Now().Before(user.expiresAt) {
+		return time.Time{}, false, nil
+	}
+	return user.at, true, nil
+}
+
+// sweep drops entries whose tokens have expired on their own. Callers hold mu.
+func (m *memoryStore) sweep(now time.Time) {
+	for id, expiresAt := range m.tokens {
+		if now.After(expiresAt) {
+			delete(m.tokens, id)
+		}
+	}
+	for id, user := range m.users {
+		if now.After(user.expiresAt) {
+			delete(m.users, id)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tokenn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestIsTokenRevokedSignOutEverywhere(t *testing.T) {
+	ctx := context.Background()
+	maxTTL := time.Hour
+	// sign out half way through a second, so iat can't tell before and after
+	at := time.Now().Truncate(time.Second).Add(-time.Minute + 500*time.Millisecond)
+	store := NewMemoryStore()
+	if err := store.RevokeUser(ctx, "u1", at, at.Add(maxTTL)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   bool
+	}{
+		{"issued before", jwt.MapClaims{"iat": float64(at.Add(-time.Second).Unix())}, true},
+		{"issued in the same second", jwt.MapClaims{"iat": float64(at.Unix())}, false},
+		{"issued after", jwt.MapClaims{"iat": float64(at.Add(time.Second).Unix())}, false},
+		{"no iat, expiring before a new token would", jwt.MapClaims{"exp": float64(at.Add(maxTTL - time.Minute).Unix())}, true},
+		{"no iat, expiring after a new token would", jwt.MapClaims{"exp": float64(at.Add(maxTTL + time.Minute).Unix())}, false},
+		{"no iat and no exp", jwt.MapClaims{}, true},
+		{"other user", jwt.MapClaims{"user_id": "u2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.claims["user_id"]; !ok {
+				tt.claims["user_id"] = "u1"
+			}
+			tt.claims["jti"] = tt.name
+			got, err := IsTokenRevoked(ctx, store, tt.claims, "", maxTTL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("revoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeUserExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	at := time.Now().Add(-2 * time.Hour)
+	if err := store.RevokeUser(ctx, "u1", at, at.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.UserRevokedAt(ctx, "u1"); err != nil || ok {
+		t.Errorf("expired marker found (%v, %v), want it dropped", ok, err)
+	}
+	revoked, err := IsTokenRevoked(ctx, store, jwt.MapClaims{"user_id": "u1"}, "", time.Hour)
+	if err != nil || revoked {
+		t.Errorf("token without iat revoked = %v (%v) after the marker expired, want false", revoked, err)
+	}
+}
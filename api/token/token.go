@@ -1,57 +1,103 @@
 package tokenn
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
 const (
-	signingkey = "key"
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
 )
 
-func ValidateAccessToken(tokenStr string) (bool, error) {
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrWrongTokenType = errors.New("wrong token type")
+	ErrTokenRevoked   = errors.New("token has been revoked")
+)
+
+func ValidateAccessToken(v Verifier, tokenStr string) (bool, error) {
 	if tokenStr == "" {
 		return false, nil
 	}
-	
-	_, err := ExtractAccessClaim(tokenStr)
+
+	_, err := ExtractAccessClaim(v, tokenStr)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func ExtractAccessClaim(tokenStr string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(signingkey), nil
-	})
-
+// ExtractAccessClaim parses an access token. Only tokens typed as access
+// are taken, so a refresh token, or an untyped one that may be a refresh
+// token, can't be used to call the API.
+func ExtractAccessClaim(v Verifier, tokenStr string) (*jwt.MapClaims, error) {
+	claims, err := v.Verify(tokenStr)
 	if err != nil {
-		fmt.Println("ldshgfiuesrhgfvriser", err)
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !(ok && token.Valid) {
-		fmt.Println("Pasr", err)
+	if tokenType(claims) != TypeAccess {
+		return nil, ErrWrongTokenType
+	}
+
+	return &claims, nil
+}
+
+// ExtractRefreshClaim parses a refresh token. Only tokens typed as refresh,
+// which the gateway issued itself, are taken, so a short-lived access token
+// can't be swapped for a new pair.
+func ExtractRefreshClaim(v Verifier, tokenStr string) (*jwt.MapClaims, error) {
+	claims, err := v.Verify(tokenStr)
+	if err != nil {
 		return nil, err
 	}
 
+	if tokenType(claims) != TypeRefresh {
+		return nil, ErrWrongTokenType
+	}
+
 	return &claims, nil
 }
 
-func GetUserInfoFromAccessToken(accessTokenString string) (string, string, error) {
-	refreshToken, err := jwt.Parse(accessTokenString, func(token *jwt.Token) (interface{}, error) { return []byte(signingkey), nil })
-	if err != nil || !refreshToken.Valid {
-		return "", "", err
+// TokenID identifies a token for revocation. Tokens minted by the gateway
+// carry a jti; for the others a hash of the raw token is used.
+func TokenID(claims jwt.MapClaims, tokenStr string) string {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		return jti
 	}
-	claims, ok := refreshToken.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", "", err
+	sum := sha256.Sum256([]byte(stripBearer(tokenStr)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExpiresAt returns the exp claim, or fallback when the token has none.
+func ExpiresAt(claims jwt.MapClaims, fallback time.Time) time.Time {
+	if exp, ok := claimTime(claims, "exp"); ok {
+		return exp
 	}
-	userID := claims["user_id"].(string)
-	Role := claims["role"].(string)
+	return fallback
+}
 
-	return userID, Role, nil
+func stripBearer(tokenStr string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tokenStr), "Bearer "))
+}
+
+func tokenType(claims jwt.MapClaims) string {
+	t, _ := claims["token_type"].(string)
+	return t
+}
+
+func claimTime(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	}
+	return time.Time{}, false
 }
@@ -0,0 +1,71 @@
+package tokenn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const testSecret = "test-secret"
+
+func testVerifier(t *testing.T) Verifier {
+	t.Helper()
+	v, err := NewVerifier(VerifierConfig{Algorithms: []string{"HS256"}, HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestExtractClaimsByType(t *testing.T) {
+	v := testVerifier(t)
+	issuer, err := NewIssuer(IssuerConfig{AccessTTL: time.Hour, RefreshTTL: 168 * time.Hour, Algorithm: "HS256", HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := issuer.GenerateTokens("u1", "patient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as the auth service issues them, without a token_type
+	untyped := signHS256(t, jwt.MapClaims{"user_id": "u1", "role": "patient", "exp": float64(time.Now().Add(time.Hour).Unix())})
+
+	tests := []struct {
+		name          string
+		token         string
+		access, fresh bool
+	}{
+		{"access token", pair.AccessToken, true, false},
+		{"refresh token", pair.RefreshToken, false, true},
+		{"untyped token", untyped, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractAccessClaim(v, tt.token)
+			if got := err == nil; got != tt.access {
+				t.Errorf("accepted as access token = %v (%v), want %v", got, err, tt.access)
+			}
+			if err != nil && !errors.Is(err, ErrWrongTokenType) {
+				t.Errorf("access error %v, want ErrWrongTokenType", err)
+			}
+			_, err = ExtractRefreshClaim(v, tt.token)
+			if got := err == nil; got != tt.fresh {
+				t.Errorf("accepted as refresh token = %v (%v), want %v", got, err, tt.fresh)
+			}
+			if err != nil && !errors.Is(err, ErrWrongTokenType) {
+				t.Errorf("refresh error %v, want ErrWrongTokenType", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package tokenn
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwksReloadInterval limits how often an unknown kid makes the verifier
+// re-read the JWKS file.
+const jwksReloadInterval = 30 * time.Second
+
+// Verifier checks the signature and the registered claims of a JWT.
+type Verifier interface {
+	Verify(tokenStr string) (jwt.MapClaims, error)
+}
+
+type VerifierConfig struct {
+	// Algorithms is the allow-list of accepted "alg" header values.
+	Algorithms []string
+	// HMACSecret verifies HS* tokens.
+	HMACSecret string
+	// PublicKeyFile is a PEM encoded RSA or ECDSA key for tokens without a kid.
+	PublicKeyFile string
+	// JWKSFile holds the keys for tokens carrying a kid. It is re-read when
+	// it changes on disk, so keys can be rotated without a restart.
+	JWKSFile string
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+type verifier struct {
+	cfg       VerifierConfig
+	secret    []byte
+	publicKey interface{}
+
+	mu         sync.RWMutex
+	jwks       map[string]interface{}
+	jwksMod    time.Time
+	jwksLoaded time.Time
+}
+
+func NewVerifier(cfg VerifierConfig) (Verifier, error) {
+	if len(cfg.Algorithms) == 0 {
+		return nil, errors.New("no signing algorithms allowed")
+	}
+	for _, alg := range cfg.Algorithms {
+		if jwt.GetSigningMethod(alg) == nil || alg == "none" {
+			return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+	}
+
+	v := &verifier{cfg: cfg}
+	if cfg.HMACSecret != "" {
+		v.secret = []byte(cfg.HMACSecret)
+	}
+	if cfg.PublicKeyFile != "" {
+		key, err := LoadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	}
+	if cfg.JWKSFile != "" {
+		if err := v.reloadJWKS(true); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (v *verifier) Verify(tokenStr string) (jwt.MapClaims, error) {
+	tokenStr = stripBearer(tokenStr)
+
+	parser := &jwt.Parser{ValidMethods: v.cfg.Algorithms, SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenStr, v.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !(ok && token.Valid) {
+		return nil, ErrInvalidToken
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *verifier) keyFunc(t *jwt.Token) (interface{}, error) {
+	if kid, ok := t.Header["kid"].(string); ok && kid != "" && v.cfg.JWKSFile != "" {
+		key, err := v.jwksKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		return matchKey(t.Method, key)
+	}
+
+	switch t.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if v.secret == nil {
+			return nil, errors.New("no HMAC secret configured")
+		}
+		return v.secret, nil
+	default:
+		if v.publicKey == nil {
+			return nil, errors.New("no public key configured")
+		}
+		return matchKey(t.Method, v.publicKey)
+	}
+}
+
+// matchKey makes sure the key type fits the algorithm family, so for example
+// an RSA public key is never used as an HMAC secret.
+func matchKey(method jwt.SigningMethod, key interface{}) (interface{}, error) {
+	ok := false
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok = key.([]byte)
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok = key.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		_, ok = key.(*ecdsa.PublicKey)
+	}
+	if !ok {
+		return nil, fmt.Errorf("key does not match algorithm %s", method.Alg())
+	}
+	return key, nil
+}
+
+func (v *verifier) jwksKey(kid string) (interface{}, error) {
+	v.mu.RLock()
+	key, ok := v.jwks[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if err := v.reloadJWKS(false); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.jwks[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (v *verifier) reloadJWKS(force bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !force && time.Since(v.jwksLoaded) < jwksReloadInterval {
+		return nil
+	}
+	v.jwksLoaded = time.Now()
+
+	info, err := os.Stat(v.cfg.JWKSFile)
+	if err != nil {
+		return err
+	}
+	if !force && !info.ModTime().After(v.jwksMod) {
+		return nil
+	}
+
+	keys, err := LoadJWKS(v.cfg.JWKSFile)
+	if err != nil {
+		return err
+	}
+	v.jwks = keys
+	v.jwksMod = info.ModTime()
+	return nil
+}
+
+func (v *verifier) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+
+	exp, ok := claimTime(claims, "exp")
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(exp.Add(v.cfg.Leeway)) {
+		return errors.New("token is expired")
+	}
+
+	if nbf, ok := claimTime(claims, "nbf"); ok && now.Add(v.cfg.Leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+
+	if v.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+			return errors.New("token has an unexpected issuer")
+		}
+	}
+
+	if v.cfg.Audience != "" && !hasAudience(claims, v.cfg.Audience) {
+		return errors.New("token has an unexpected audience")
+	}
+
+	return nil
+}
+
+func hasAudience(claims jwt.MapClaims, want string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
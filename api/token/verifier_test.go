@@ -0,0 +1,146 @@
+package tokenn
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// claims returns claims the test verifier accepts with changes applied; a
+// nil value removes the claim.
+func claims(changes jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{"user_id": "u1", "iss": "gateway", "aud": "api", "exp": float64(time.Now().Add(time.Hour).Unix())}
+	for k, v := range changes {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func unix(d time.Duration) float64 {
+	return float64(time.Now().Add(d).Unix())
+}
+
+func TestVerify(t *testing.T) {
+	key, rotated := rsaKey(t), rsaKey(t)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix(t, &key.PublicKey)})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, rsaJWK("k1", rotated))
+
+	v, err := NewVerifier(VerifierConfig{
+		Algorithms:    []string{"RS256", "HS256"},
+		HMACSecret:    testSecret,
+		PublicKeyFile: writeFile(t, "public.pem", publicPEM),
+		JWKSFile:      jwksFile,
+		Issuer:        "gateway",
+		Audience:      "api",
+		Leeway:        30 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256 with the public key", sign(t, jwt.SigningMethodRS256, key, "", claims(nil)), true},
+		{"HS256 with the secret", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(nil)), true},
+		{"RS256 with a JWKS key", sign(t, jwt.SigningMethodRS256, rotated, "k1", claims(nil)), true},
+		{"bearer prefix", "Bearer " + sign(t, jwt.SigningMethodRS256, key, "", claims(nil)), true},
+		{"alg none", none, false},
+		// the public key is no secret, so it must never verify an HMAC
+		{"HS256 signed with the public key", sign(t, jwt.SigningMethodHS256, publicPEM, "", claims(nil)), false},
+		{"HS256 signed with the public key and a kid", sign(t, jwt.SigningMethodHS256, publicPEM, "k1", claims(nil)), false},
+		{"algorithm not allowed", sign(t, jwt.SigningMethodRS512, key, "", claims(nil)), false},
+		{"signed with another key", sign(t, jwt.SigningMethodRS256, rotated, "", claims(nil)), false},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, key, "k9", claims(nil)), false},
+		{"expired", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"exp": unix(-time.Minute)})), false},
+		{"expired within the leeway", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"exp": unix(-10 * time.Second)})), true},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"exp": nil})), false},
+		{"not valid yet", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"nbf": unix(time.Minute)})), false},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"aud": "billing"})), false},
+		{"audience among others", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"aud": []string{"billing", "api"}})), true},
+		{"no audience", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"aud": nil})), false},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, key, "", claims(jwt.MapClaims{"iss": "someone"})), false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if ok := err == nil; ok != tt.ok {
+				t.Fatalf("accepted = %v (%v), want %v", ok, err, tt.ok)
+			}
+			if tt.ok && got["user_id"] != "u1" {
+				t.Errorf("claims %v, want the token's", got)
+			}
+		})
+	}
+}
+
+func TestVerifyJWKSRotation(t *testing.T) {
+	old, next := rsaKey(t), rsaKey(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, rsaJWK("old", old))
+	v, err := NewVerifier(VerifierConfig{Algorithms: []string{"RS256"}, JWKSFile: jwksFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jwt.SigningMethodRS256, next, "next", claims(nil))
+
+	if _, err := v.Verify(token); err == nil {
+		t.Fatal("token of a key not published yet was accepted")
+	}
+
+	// the new key is published next to the old one
+	writeJWKS(t, jwksFile, rsaJWK("old", old), rsaJWK("next", next))
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(jwksFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(token); err == nil {
+		t.Error("the file was read again within the reload interval")
+	}
+
+	v.(*verifier).jwksLoaded = time.Now().Add(-jwksReloadInterval)
+	if _, err := v.Verify(token); err != nil {
+		t.Errorf("token of the rotated key: %v", err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, old, "old", claims(nil))); err != nil {
+		t.Errorf("token of the old key during the rotation: %v", err)
+	}
+}
+
+func TestNewVerifierAlgorithms(t *testing.T) {
+	for _, algs := range [][]string{nil, {"none"}, {"HS256", "XX999"}} {
+		if _, err := NewVerifier(VerifierConfig{Algorithms: algs, HMACSecret: testSecret}); err == nil {
+			t.Errorf("algorithms %v were accepted", algs)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package casbin
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// AuditEntry is one change made to the policy through the admin API.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Ptype     string    `json:"ptype"`
+	Rule      []string  `json:"rule"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditLog keeps the trail of policy changes.
+type AuditLog interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	List(ctx context.Context, limit, offset int) ([]AuditEntry, error)
+}
+
+type postgresAudit struct {
+	db *sql.DB
+}
+
+// NewPostgresAudit creates the casbin_audit table when it is missing.
+func NewPostgresAudit(db *sql.DB) (AuditLog, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS casbin_audit (
+			id         BIGSERIAL PRIMARY KEY,
+			actor      TEXT NOT NULL,
+			action     TEXT NOT NULL,
+			ptype      TEXT NOT NULL,
+			rule       TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresAudit{db: db}, nil
+}
+
+func (a *postgresAudit) Record(ctx context.Context, entry AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := a.db.ExecContext(ctx,
+		`INSERT INTO casbin_audit (actor, action, ptype, rule, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		entry.Actor, entry.Action, entry.Ptype, strings.Join(entry.Rule, ", "), entry.CreatedAt)
+	return err
+}
+
+func (a *postgresAudit) List(ctx context.Context, limit, offset int) ([]AuditEntry, error) {
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT id, actor, action, ptype, rule, created_at FROM casbin_audit ORDER BY id DESC LIMIT $1 OFFSET $2`,
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var rule string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Ptype, &rule, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.Rule = strings.Split(rule, ", ")
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,125 @@
+package casbin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// Roles are the roles the auth service hands out. Each should have at least
+// one permission.
+var Roles = []string{"admin", "doctor", "patient"}
+
+// Route is a method and path pattern as gin registers it.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// CoverageReport lists where the policy and the router disagree.
+type CoverageReport struct {
+	UncoveredRoutes []Route
+	UnusedPolicies  [][]string
+	EmptyRoles      []string
+}
+
+func (r CoverageReport) OK() bool {
+	return len(r.UncoveredRoutes) == 0 && len(r.UnusedPolicies) == 0 && len(r.EmptyRoles) == 0
+}
+
+// Print writes the report in a form meant for people.
+func (r CoverageReport) Print(w io.Writer) {
+	if r.OK() {
+		fmt.Fprintln(w, "policy coverage: ok")
+		return
+	}
+	for _, route := range r.UncoveredRoutes {
+		fmt.Fprintf(w, "route without policy: %s %s\n", route.Method, route.Path)
+	}
+	for _, rule := range r.UnusedPolicies {
+		fmt.Fprintf(w, "policy matching no route: %v\n", rule)
+	}
+	for _, role := range r.EmptyRoles {
+		fmt.Fprintf(w, "role without permissions: %s\n", role)
+	}
+}
+
+// CheckCoverage compares the protected routes with the p rules the enforcer
+// holds. Rules whose action is not an HTTP method, such as cross_user and
+// care_team, guard record access rather than routes and are skipped.
+func CheckCoverage(enforcer *casbin.Enforcer, routes []Route) (CoverageReport, error) {
+	report := CoverageReport{}
+
+	rules, err := enforcer.GetPolicy()
+	if err != nil {
+		return report, err
+	}
+
+	for _, route := range routes {
+		covered := false
+		for _, rule := range rules {
+			if len(rule) >= 3 && rule[2] == route.Method && util.KeyMatch(route.Path, rule[1]) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			report.UncoveredRoutes = append(report.UncoveredRoutes, route)
+		}
+	}
+
+	for _, rule := range rules {
+		if len(rule) < 3 || !isHTTPMethod(rule[2]) {
+			continue
+		}
+		used := false
+		for _, route := range routes {
+			if rule[2] == route.Method && util.KeyMatch(route.Path, rule[1]) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			report.UnusedPolicies = append(report.UnusedPolicies, rule)
+		}
+	}
+
+	roles := map[string]bool{}
+	for _, role := range Roles {
+		roles[role] = true
+	}
+	assignments, err := enforcer.GetGroupingPolicy()
+	if err != nil {
+		return report, err
+	}
+	for _, assignment := range assignments {
+		if len(assignment) >= 2 {
+			roles[assignment[1]] = true
+		}
+	}
+	for role := range roles {
+		permissions, err := enforcer.GetImplicitPermissionsForUser(role)
+		if err != nil {
+			return report, err
+		}
+		if len(permissions) == 0 {
+			report.EmptyRoles = append(report.EmptyRoles, role)
+		}
+	}
+	sort.Strings(report.EmptyRoles)
+
+	return report, nil
+}
+
+func isHTTPMethod(act string) bool {
+	switch act {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
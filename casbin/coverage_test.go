@@ -0,0 +1,90 @@
+package casbin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestCheckCoverage(t *testing.T) {
+	enforcer, err := casbin.NewEnforcer("model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddPolicies([][]string{
+		{"admin", "/api/user/profile/:id", "GET"},
+		{"patient", "/api/user/profile/:id", "GET"},
+		{"patient", "/api/lifestyle/*", "GET"},
+		// no route is registered for it any more
+		{"admin", "/api/user/delete/:id", "DELETE"},
+		// guards record access, not a route
+		{"doctor", "/api/health", "cross_user"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// nurse gets its permissions from patient, auditor gets none
+	if _, err := enforcer.AddGroupingPolicies([][]string{{"u1", "nurse"}, {"nurse", "patient"}, {"u2", "auditor"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := CheckCoverage(enforcer, []Route{
+		{"GET", "/api/user/profile/:id"},
+		{"GET", "/api/lifestyle/getAllLifestyleData"},
+		{"POST", "/api/lifestyle/addLifestyleData"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Route{{"POST", "/api/lifestyle/addLifestyleData"}}; !reflect.DeepEqual(report.UncoveredRoutes, want) {
+		t.Errorf("uncovered routes %v, want %v", report.UncoveredRoutes, want)
+	}
+	if want := [][]string{{"admin", "/api/user/delete/:id", "DELETE"}}; !reflect.DeepEqual(report.UnusedPolicies, want) {
+		t.Errorf("unused policies %v, want %v", report.UnusedPolicies, want)
+	}
+	if want := []string{"auditor"}; !reflect.DeepEqual(report.EmptyRoles, want) {
+		t.Errorf("empty roles %v, want %v", report.EmptyRoles, want)
+	}
+	if report.OK() {
+		t.Error("report is OK, want problems")
+	}
+
+	var out strings.Builder
+	report.Print(&out)
+	for _, want := range []string{
+		"route without policy: POST /api/lifestyle/addLifestyleData",
+		"policy matching no route: [admin /api/user/delete/:id DELETE]",
+		"role without permissions: auditor",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report %q does not say %q", out.String(), want)
+		}
+	}
+}
+
+func TestCheckCoverageOK(t *testing.T) {
+	enforcer, err := casbin.NewEnforcer("model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range Roles {
+		if _, err := enforcer.AddPolicy(role, "/api/jobs/:id", "GET"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := CheckCoverage(enforcer, []Route{{"GET", "/api/jobs/:id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("report %+v, want OK", report)
+	}
+	var out strings.Builder
+	report.Print(&out)
+	if out.String() != "policy coverage: ok\n" {
+		t.Errorf("report printed %q", out.String())
+	}
+}
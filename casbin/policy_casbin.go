@@ -1,42 +1,40 @@
 package casbin
 
 import (
+	"api-gateway/config"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	xormadapter "github.com/casbin/xorm-adapter/v2"
 	_ "github.com/lib/pq"
 )
 
-const (
-	host     = "postgres_container"
-	port     = "5432"
-	dbname   = "casbin"
-	username = "macbookpro"
-	password = "1111"
-)
+func ConnectionString(cfg config.Config) string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable",
+		cfg.CASBIN_DB_HOST, cfg.CASBIN_DB_PORT, cfg.CASBIN_DB_USER, cfg.CASBIN_DB_NAME, cfg.CASBIN_DB_PASSWORD)
+}
 
-func CasbinEnforcer(logger *slog.Logger) (*casbin.Enforcer, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable", host, port, username, dbname, password)
-	
-	db, err := sql.Open("postgres", connStr)
+func ConnectDB(connString string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connString)
 	if err != nil {
-		logger.Error("Error connecting to database", "error", err.Error())
 		return nil, err
 	}
-	defer db.Close()
 
-	err = db.Ping()
-	if err != nil {
-		logger.Error("Error pinging the database", "error", err.Error())
+	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
-	query := `DROP TABLE IF EXISTS "casbin_rule";`
-	db.Exec(query)
+	return db, nil
+}
 
-	adapter, err := xormadapter.NewAdapter("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable", host, port, username, dbname, password))
+func CasbinEnforcer(db *sql.DB, connString string, logger *slog.Logger) (*casbin.Enforcer, error) {
+	adapter, err := xormadapter.NewAdapter("postgres", connString)
 	if err != nil {
 		logger.Error("Error creating Casbin adapter", "error", err.Error())
 		return nil, err
@@ -55,106 +53,286 @@ func CasbinEnforcer(logger *slog.Logger) (*casbin.Enforcer, error) {
 		return nil, err
 	}
 
-	// Add policies
-	policies := [][]string{
-		//user
-		{"admin", "api/user/profile/:id", "GET"},
-		{"admin", "api/user/updateUser/:id", "PUT"},
-		{"admin", "api/user/email/:email", "GET"},
-
-		{"patient", "api/user/profile/:id", "GET"},
-		{"patient", "api/user/updateUser/:id", "PUT"},
-		{"patient", "api/user/email/:email", "GET"},
-
-		{"doctor", "api/user/profile/:id", "GET"},
-		{"doctor", "api/user/updateUser/:id", "PUT"},
-		{"doctor", "api/user/email/:email", "GET"},
-
-		//health
-		{"admin", "/api/health/generate", "POST"},
-		{"admin", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
-		{"admin", "/api/health/getDailyHealthSummary/:date", "GET"},
-        {"admin", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
-
-		{"patient", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
-		{"patient", "/api/health/getDailyHealthSummary/:date", "GET"},
-        {"patient", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
-
-		{"doctor", "/api/health/generate", "POST"},
-		{"doctor", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
-		{"doctor", "/api/health/getDailyHealthSummary/:date", "GET"},
-        {"doctor", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
-
-		//lifestyle
-		{"admin", "/api/lifestyle/addLifestyleData", "POST"},
-		{"admin", "/api/lifestyle/getAllLifestyleData", "GET"},
-        {"admin", "/api/lifestyle/getLifestyleById/:id", "GET"},
-        {"admin", "/api/lifestyle/updateLifestyleData", "PUT"},
-		{"admin", "/api/lifestyle/deleteLifestyleData/:id", "DELETE"},
-
-		{"patient", "/api/lifestyle/addLifestyleData", "POST"},
-		{"patient", "/api/lifestyle/getAllLifestyleData", "GET"},
-
-		{"doctor", "/api/lifestyle/addLifestyleData", "POST"},
-		{"doctor", "/api/lifestyle/getAllLifestyleData", "GET"},
-        {"doctor", "/api/lifestyle/getLifestyleById/:id", "GET"},
-        {"doctor", "/api/lifestyle/updateLifestyleData", "PUT"},
-		{"doctor", "/api/lifestyle/deleteLifestyleData/:id", "DELETE"},
-		
-	    //medical report
-		{"admin", "/api/medicalReport/add", "POST"},
-		{"admin", "/api/medicalReport/get", "GET"},
-        {"admin", "/api/medicalReport/getById/:id", "GET"},
-        {"admin", "/api/medicalReport/update", "PUT"},
-        {"admin", "/api/medicalReport/delete/:id", "DELETE"},
-
-		{"patient", "/api/medicalReport/add", "POST"},
-		{"patient", "/api/medicalReport/get", "GET"},
-
-		{"doctor", "/api/medicalReport/add", "POST"},
-		{"doctor", "/api/medicalReport/get", "GET"},
-        {"doctor", "/api/medicalReport/getById/:id", "GET"},
-        {"doctor", "/api/medicalReport/update", "PUT"},
-        {"doctor", "/api/medicalReport/delete/:id", "DELETE"},
-
-		//wearable
-		{"admin", "/api/wearable/add", "POST"},
-        {"admin", "/api/wearable/get", "GET"},
-        {"admin", "/api/wearable/getById/:id", "GET"},
-        {"admin", "/api/wearable/update", "PUT"},
-        {"admin", "/api/wearable/delete/:id", "DELETE"},
-
-		{"patient", "/api/wearable/add", "POST"},
-        {"patient", "/api/wearable/get", "GET"},
-
-		{"doctor", "/api/wearable/add", "POST"},
-        {"doctor", "/api/wearable/get", "GET"},
-        {"doctor", "/api/wearable/getById/:id", "GET"},
-        {"doctor", "/api/wearable/update", "PUT"},
-        {"doctor", "/api/wearable/delete/:id", "DELETE"},
-
-		// notification
-		{"admin", "/api/notifications/getAll", "GET"},
-		{"admin", "/api/notifications/new", "GET"},
-
-		{"patient", "/api/notifications/getAll", "GET"},
-        {"patient", "/api/notifications/new", "GET"},
-
-		{"doctor", "/api/notifications/getAll", "GET"},
-        {"doctor", "/api/notifications/new", "GET"},
+	if retired := retiredPolicies(enforcer); len(retired) > 0 {
+		if _, err := enforcer.RemovePolicies(retired); err != nil {
+			logger.Error("Error removing retired Casbin policies", "error", err.Error())
+			return nil, err
+		}
 	}
 
-	_, err = enforcer.AddPolicies(policies)
+	seeded, err := seedPolicies(db, enforcer, defaultPolicies)
 	if err != nil {
-		logger.Error("Error adding Casbin policy", "error", err.Error())
+		logger.Error("Error seeding Casbin policy", "error", err.Error())
 		return nil, err
 	}
+	if seeded > 0 {
+		logger.Info("Seeded Casbin policies", "count", seeded)
+	}
+
+	return enforcer, nil
+}
+
+// ReadOnlyEnforcer loads the stored policy and nothing else: it neither
+// creates tables nor seeds or retires rules, so it suits tools that only
+// inspect the policy.
+func ReadOnlyEnforcer(db *sql.DB) (*casbin.Enforcer, error) {
+	return casbin.NewEnforcer("casbin/model.conf", readOnlyAdapter{db: db})
+}
 
-	err = enforcer.SavePolicy()
+var errReadOnly = errors.New("casbin: the policy is opened read-only")
+
+// readOnlyAdapter reads the casbin_rule table the xorm adapter keeps.
+type readOnlyAdapter struct {
+	db *sql.DB
+}
+
+func (a readOnlyAdapter) LoadPolicy(m model.Model) error {
+	rows, err := a.db.Query(`SELECT ptype, v0, v1, v2, v3, v4, v5 FROM casbin_rule`)
 	if err != nil {
-		logger.Error("Error saving Casbin policy", "error", err.Error())
-		return nil, err
+		return err
 	}
+	defer rows.Close()
+	for rows.Next() {
+		line := make([]string, 7)
+		if err := rows.Scan(&line[0], &line[1], &line[2], &line[3], &line[4], &line[5], &line[6]); err != nil {
+			return err
+		}
+		for len(line) > 0 && line[len(line)-1] == "" {
+			line = line[:len(line)-1]
+		}
+		if err := persist.LoadPolicyArray(line, m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
 
-	return enforcer, nil
+func (readOnlyAdapter) SavePolicy(model.Model) error                { return errReadOnly }
+func (readOnlyAdapter) AddPolicy(string, string, []string) error    { return errReadOnly }
+func (readOnlyAdapter) RemovePolicy(string, string, []string) error { return errReadOnly }
+func (readOnlyAdapter) RemoveFilteredPolicy(string, string, int, ...string) error {
+	return errReadOnly
+}
+
+// seedPolicies adds the default rules that were never seeded before. The
+// casbin_seed table remembers what has been seeded, so the table of live
+// rules is never dropped and operator changes are kept.
+func seedPolicies(db *sql.DB, enforcer *casbin.Enforcer, policies [][]string) (int, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS casbin_seed (
+			v0 TEXT NOT NULL,
+			v1 TEXT NOT NULL,
+			v2 TEXT NOT NULL,
+			PRIMARY KEY (v0, v1, v2)
+		)`)
+	if err != nil {
+		return 0, err
+	}
+
+	seeded := 0
+	for _, rule := range policies {
+		res, err := db.Exec(`INSERT INTO casbin_seed (v0, v1, v2) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			rule[0], rule[1], rule[2])
+		if err != nil {
+			return seeded, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		added, err := enforcer.AddPolicy(rule[0], rule[1], rule[2])
+		if err != nil {
+			return seeded, err
+		}
+		if added {
+			seeded++
+		}
+	}
+	return seeded, nil
+}
+
+// retiredPolicies returns the stored rules that earlier versions seeded by
+// mistake. The user routes were once written without a leading slash and so
+// never matched any request.
+func retiredPolicies(enforcer *casbin.Enforcer) [][]string {
+	var retired [][]string
+	for _, rule := range defaultPolicies {
+		if !strings.HasPrefix(rule[1], "/api/user/") {
+			continue
+		}
+		old := []string{rule[0], strings.TrimPrefix(rule[1], "/"), rule[2]}
+		if ok, _ := enforcer.HasPolicy(old[0], old[1], old[2]); ok {
+			retired = append(retired, old)
+		}
+	}
+	return retired
+}
+
+// defaultPolicies are seeded on start. A rule is only seeded once, so
+// removing it at runtime through the admin API sticks across restarts.
+var defaultPolicies = [][]string{
+	//user
+	{"admin", "/api/user/profile/:id", "GET"},
+	{"admin", "/api/user/updateUser/:id", "PUT"},
+	{"admin", "/api/user/email/:email", "GET"},
+
+	{"patient", "/api/user/profile/:id", "GET"},
+	{"patient", "/api/user/updateUser/:id", "PUT"},
+	{"patient", "/api/user/email/:email", "GET"},
+
+	{"doctor", "/api/user/profile/:id", "GET"},
+	{"doctor", "/api/user/updateUser/:id", "PUT"},
+	{"doctor", "/api/user/email/:email", "GET"},
+
+	//health
+	{"admin", "/api/health/generate", "POST"},
+	{"admin", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
+	{"admin", "/api/health/getDailyHealthSummary/:date", "GET"},
+	{"admin", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
+	{"admin", "/api/health/realtime", "POST"},
+	{"admin", "/api/health/realtime/:user_id", "GET"},
+
+	{"patient", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
+	{"patient", "/api/health/getDailyHealthSummary/:date", "GET"},
+	{"patient", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
+	{"patient", "/api/health/realtime", "POST"},
+	{"patient", "/api/health/realtime/:user_id", "GET"},
+
+	{"doctor", "/api/health/generate", "POST"},
+	{"doctor", "/api/health/getRealtimeHealthMonitoring/:user_id", "GET"},
+	{"doctor", "/api/health/getDailyHealthSummary/:date", "GET"},
+	{"doctor", "/api/health/getWeeklyHealthSummary/:start_date/:end_date", "GET"},
+	{"doctor", "/api/health/realtime", "POST"},
+	{"doctor", "/api/health/realtime/:user_id", "GET"},
+
+	//lifestyle
+	{"admin", "/api/lifestyle/addLifestyleData", "POST"},
+	{"admin", "/api/lifestyle/getAllLifestyleData", "GET"},
+	{"admin", "/api/lifestyle/getLifestyleById/:id", "GET"},
+	{"admin", "/api/lifestyle/updateLifestyleData", "PUT"},
+	{"admin", "/api/lifestyle/deleteLifestyleData/:id", "DELETE"},
+
+	{"patient", "/api/lifestyle/addLifestyleData", "POST"},
+	{"patient", "/api/lifestyle/getAllLifestyleData", "GET"},
+	{"patient", "/api/lifestyle/getLifestyleById/:id", "GET"},
+
+	{"doctor", "/api/lifestyle/addLifestyleData", "POST"},
+	{"doctor", "/api/lifestyle/getAllLifestyleData", "GET"},
+	{"doctor", "/api/lifestyle/getLifestyleById/:id", "GET"},
+	{"doctor", "/api/lifestyle/updateLifestyleData", "PUT"},
+	{"doctor", "/api/lifestyle/deleteLifestyleData/:id", "DELETE"},
+	
+	    //medical report
+	{"admin", "/api/medicalReport/add", "POST"},
+	{"admin", "/api/medicalReport/get", "GET"},
+	{"admin", "/api/medicalReport/getById/:id", "GET"},
+	{"admin", "/api/medicalReport/update", "PUT"},
+	{"admin", "/api/medicalReport/delete/:id", "DELETE"},
+
+	{"patient", "/api/medicalReport/add", "POST"},
+	{"patient", "/api/medicalReport/get", "GET"},
+	{"patient", "/api/medicalReport/getById/:id", "GET"},
+
+	{"doctor", "/api/medicalReport/add", "POST"},
+	{"doctor", "/api/medicalReport/get", "GET"},
+	{"doctor", "/api/medicalReport/getById/:id", "GET"},
+	{"doctor", "/api/medicalReport/update", "PUT"},
+	{"doctor", "/api/medicalReport/delete/:id", "DELETE"},
+
+	//wearable
+	{"admin", "/api/wearable/add", "POST"},
+	{"admin", "/api/wearable/bulk", "POST"},
+	{"admin", "/api/wearable/get", "GET"},
+	{"admin", "/api/wearable/getById/:id", "GET"},
+	{"admin", "/api/wearable/import", "POST"},
+	{"admin", "/api/wearable/stream", "GET"},
+	{"admin", "/api/wearable/update", "PUT"},
+	{"admin", "/api/wearable/delete/:id", "DELETE"},
+
+	{"patient", "/api/wearable/add", "POST"},
+	{"patient", "/api/wearable/bulk", "POST"},
+	{"patient", "/api/wearable/get", "GET"},
+	{"patient", "/api/wearable/getById/:id", "GET"},
+	{"patient", "/api/wearable/import", "POST"},
+	{"patient", "/api/wearable/stream", "GET"},
+
+	{"doctor", "/api/wearable/add", "POST"},
+	{"doctor", "/api/wearable/bulk", "POST"},
+	{"doctor", "/api/wearable/get", "GET"},
+	{"doctor", "/api/wearable/getById/:id", "GET"},
+	{"doctor", "/api/wearable/import", "POST"},
+	{"doctor", "/api/wearable/stream", "GET"},
+	{"doctor", "/api/wearable/update", "PUT"},
+	{"doctor", "/api/wearable/delete/:id", "DELETE"},
+
+	// notification
+	{"admin", "/api/notifications/getAll", "GET"},
+	{"admin", "/api/notifications/new", "GET"},
+	{"admin", "/api/notifications/stream", "GET"},
+
+	{"patient", "/api/notifications/getAll", "GET"},
+	{"patient", "/api/notifications/new", "GET"},
+	{"patient", "/api/notifications/stream", "GET"},
+
+	{"doctor", "/api/notifications/getAll", "GET"},
+	{"doctor", "/api/notifications/new", "GET"},
+	{"doctor", "/api/notifications/stream", "GET"},
+
+	// jobs
+	{"admin", "/api/jobs/:id", "GET"},
+	{"patient", "/api/jobs/:id", "GET"},
+	{"doctor", "/api/jobs/:id", "GET"},
+
+	// measurement registry
+	{"admin", "/api/measurements", "GET"},
+	{"patient", "/api/measurements", "GET"},
+	{"doctor", "/api/measurements", "GET"},
+
+	// alert rules
+	{"admin", "/api/alerts/rules", "GET"},
+	{"admin", "/api/alerts/rules", "POST"},
+	{"admin", "/api/alerts/rules/:id", "PUT"},
+	{"admin", "/api/alerts/rules/:id", "DELETE"},
+
+	{"doctor", "/api/alerts/rules", "GET"},
+	{"doctor", "/api/alerts/rules", "POST"},
+	{"doctor", "/api/alerts/rules/:id", "PUT"},
+	{"doctor", "/api/alerts/rules/:id", "DELETE"},
+
+	// cross-user access: lets a role work with records owned by other users
+	{"admin", "/api/*", "cross_user"},
+
+	// care team: a doctor reaches only the patients who granted them access
+	{"doctor", "/api/user/profile/*", "care_team"},
+	{"doctor", "/api/health/*", "care_team"},
+	{"doctor", "/api/lifestyle/*", "care_team"},
+	{"doctor", "/api/medicalReport/*", "care_team"},
+	{"doctor", "/api/wearable/*", "care_team"},
+	{"doctor", "/api/alerts/*", "care_team"},
+
+	{"admin", "/api/careTeam/list", "GET"},
+
+	{"patient", "/api/careTeam/grant", "POST"},
+	{"patient", "/api/careTeam/list", "GET"},
+	{"patient", "/api/careTeam/revoke/:doctor_id", "DELETE"},
+
+	{"doctor", "/api/careTeam/list", "GET"},
+
+	// policy administration
+	{"admin", "/api/admin/policies", "GET"},
+	{"admin", "/api/admin/policies", "POST"},
+	{"admin", "/api/admin/policies", "DELETE"},
+	{"admin", "/api/admin/policies", "PUT"},
+	{"admin", "/api/admin/roles", "GET"},
+	{"admin", "/api/admin/roles", "POST"},
+	{"admin", "/api/admin/roles", "DELETE"},
+	{"admin", "/api/admin/audit", "GET"},
+
+	// accounts with more than patient rights
+	{"admin", "/api/admin/doctors", "POST"},
+
+	// outbox
+	{"admin", "/api/admin/outbox", "GET"},
+	{"admin", "/api/admin/outbox/:id/replay", "POST"},
+	{"admin", "/api/admin/outbox/replay", "POST"},
 }
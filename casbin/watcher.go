@@ -0,0 +1,186 @@
+package casbin
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/lib/pq"
+)
+
+// watcherChannel is the Postgres channel replicas use to announce policy changes.
+const watcherChannel = "casbin_policy_update"
+
+// Watcher tells the other gateway replicas that the policy changed, so each
+// of them reloads it from the adapter. It is casbin's persist.Watcher; pass
+// it to Enforcer.SetWatcher.
+type Watcher = persist.Watcher
+
+type postgresWatcher struct {
+	db       *sql.DB
+	listener *pq.Listener
+	instance string
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	callback func(string)
+	done     chan struct{}
+	closed   sync.Once
+}
+
+// NewPostgresWatcher listens with LISTEN/NOTIFY on the casbin database that
+// db is connected to through connString. Notifications sent by this
+// replica are ignored.
+func NewPostgresWatcher(db *sql.DB, connString string, logger *slog.Logger) (Watcher, error) {
+	listener := pq.NewListener(connString, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Error("Casbin watcher connection event", "event", event, "error", err.Error())
+		}
+	})
+	if err := listener.Listen(watcherChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	w := &postgresWatcher{
+		db:       db,
+		listener: listener,
+		instance: newInstanceID(),
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *postgresWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+func (w *postgresWatcher) Update() error {
+	_, err := w.db.Exec(`SELECT pg_notify($1, $2)`, watcherChannel, w.instance)
+	return err
+}
+
+func (w *postgresWatcher) Close() {
+	w.closed.Do(func() {
+		close(w.done)
+		w.listener.Close()
+	})
+}
+
+func (w *postgresWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case n := <-w.listener.NotificationChannel():
+			// a nil notification follows a reconnect, when changes may
+			// have been missed, so it reloads as well
+			if n != nil && n.Extra == w.instance {
+				continue
+			}
+			payload := ""
+			if n != nil {
+				payload = n.Extra
+			}
+			w.notify(payload)
+		case <-time.After(90 * time.Second):
+			if err := w.listener.Ping(); err != nil {
+				w.logger.Error("Casbin watcher ping failed", "error", err.Error())
+			}
+		}
+	}
+}
+
+func (w *postgresWatcher) notify(payload string) {
+	w.mu.Lock()
+	callback := w.callback
+	w.mu.Unlock()
+
+	if callback != nil {
+		w.logger.Info("Reloading Casbin policy", "from", payload)
+		callback(payload)
+	}
+}
+
+// MemoryBus connects in-process watchers. It stands in for Postgres when
+// several enforcers share one process, such as in tests.
+type MemoryBus struct {
+	mu       sync.Mutex
+	watchers map[*memoryWatcher]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{watchers: map[*memoryWatcher]struct{}{}}
+}
+
+// NewWatcher returns a watcher whose updates reach every other watcher on the bus.
+func (b *MemoryBus) NewWatcher() Watcher {
+	w := &memoryWatcher{bus: b, instance: newInstanceID()}
+	b.mu.Lock()
+	b.watchers[w] = struct{}{}
+	b.mu.Unlock()
+	return w
+}
+
+func (b *MemoryBus) publish(from *memoryWatcher) {
+	b.mu.Lock()
+	peers := make([]*memoryWatcher, 0, len(b.watchers))
+	for w := range b.watchers {
+		if w != from {
+			peers = append(peers, w)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, w := range peers {
+		w.mu.Lock()
+		callback := w.callback
+		w.mu.Unlock()
+		if callback != nil {
+			callback(from.instance)
+		}
+	}
+}
+
+type memoryWatcher struct {
+	bus      *MemoryBus
+	instance string
+
+	mu       sync.Mutex
+	callback func(string)
+}
+
+func (w *memoryWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+func (w *memoryWatcher) Update() error {
+	w.bus.publish(w)
+	return nil
+}
+
+func (w *memoryWatcher) Close() {
+	w.bus.mu.Lock()
+	delete(w.bus.watchers, w)
+	w.bus.mu.Unlock()
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,135 @@
+package casbin
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// sharedAdapter is the policy table that every replica reads and writes.
+type sharedAdapter struct {
+	mu    sync.Mutex
+	rules [][]string
+}
+
+func (a *sharedAdapter) LoadPolicy(m model.Model) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, rule := range a.rules {
+		if err := persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *sharedAdapter) SavePolicy(m model.Model) error { return nil }
+
+func (a *sharedAdapter) AddPolicy(sec, ptype string, rule []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rules = append(a.rules, append([]string{ptype}, rule...))
+	return nil
+}
+
+func (a *sharedAdapter) RemovePolicy(sec, ptype string, rule []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	line := append([]string{ptype}, rule...)
+	a.rules = slices.DeleteFunc(a.rules, func(r []string) bool { return slices.Equal(r, line) })
+	return nil
+}
+
+func (a *sharedAdapter) RemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return nil
+}
+
+// replica is an enforcer as one gateway replica runs it.
+func replica(t *testing.T, adapter persist.Adapter, bus *MemoryBus) *casbin.Enforcer {
+	t.Helper()
+	enforcer, err := casbin.NewEnforcer("model.conf", adapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher := bus.NewWatcher()
+	t.Cleanup(watcher.Close)
+	if err := enforcer.SetWatcher(watcher); err != nil {
+		t.Fatal(err)
+	}
+	return enforcer
+}
+
+func TestWatcherSyncsReplicas(t *testing.T) {
+	adapter := &sharedAdapter{}
+	bus := NewMemoryBus()
+	a := replica(t, adapter, bus)
+	b := replica(t, adapter, bus)
+
+	enforce := func(e *casbin.Enforcer) bool {
+		t.Helper()
+		allowed, err := e.Enforce("u1", "/api/user/profile/1", "GET")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return allowed
+	}
+
+	if _, err := a.AddPolicy("patient", "/api/user/*", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddNamedGroupingPolicy("g", "u1", "patient"); err != nil {
+		t.Fatal(err)
+	}
+	if !enforce(b) {
+		t.Error("policy added on one replica is not enforced on the other")
+	}
+
+	if _, err := b.AddNamedGroupingPolicy("g2", "d1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if linked, err := a.HasNamedGroupingPolicy("g2", "d1", "u1"); err != nil || !linked {
+		t.Errorf("care team link added on one replica is missing on the other (%v)", err)
+	}
+
+	if _, err := b.RemovePolicy("patient", "/api/user/*", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if enforce(a) {
+		t.Error("policy removed on one replica is still enforced on the other")
+	}
+}
+
+func TestWatcherSkipsSender(t *testing.T) {
+	bus := NewMemoryBus()
+	sender := bus.NewWatcher()
+	receiver := bus.NewWatcher()
+	defer sender.Close()
+
+	var mu sync.Mutex
+	var got []string
+	record := func(name string) func(string) {
+		return func(string) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, name)
+		}
+	}
+	sender.SetUpdateCallback(record("sender"))
+	receiver.SetUpdateCallback(record("receiver"))
+
+	if err := sender.Update(); err != nil {
+		t.Fatal(err)
+	}
+	receiver.Close()
+	if err := sender.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, []string{"receiver"}) {
+		t.Errorf("callbacks ran for %v, want only the receiver, once", got)
+	}
+}
@@ -1,41 +1,493 @@
 package main
 
 import (
+	"api-gateway/alert"
 	"api-gateway/api"
 	"api-gateway/api/handler"
+	"api-gateway/api/ratelimit"
+	"api-gateway/api/readiness"
+	"api-gateway/api/stream"
+	tokenn "api-gateway/api/token"
 	"api-gateway/casbin"
 	"api-gateway/config"
+	"api-gateway/kafka/consumer"
+	"api-gateway/kafka/event"
+	"api-gateway/kafka/job"
+	"api-gateway/kafka/outbox"
+	kafka "api-gateway/kafka/producer"
 	"api-gateway/logs"
 	"api-gateway/service"
-	"log"
+	"api-gateway/storage/postgres"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	log.Println("API Gateway started successfully!")
 	logger := logs.NewLogger()
 	logger.Info("API Gateway started successfully!")
 
-	enforcer, err := casbin.CasbinEnforcer(logger)
+	if len(os.Args) > 1 && os.Args[1] == "check-policies" {
+		os.Exit(checkPolicies(logger))
+	}
+	os.Exit(run(logger))
+}
+
+// run serves until SIGTERM or SIGINT and then shuts down in order: it stops
+// taking requests, drains the ones in flight, flushes the outbox and closes
+// the backends. It returns the exit status.
+func run(logger *slog.Logger) int {
+	config := config.Load()
+	casbinDSN := casbin.ConnectionString(config)
+	casbinDB, err := casbin.ConnectDB(casbinDSN)
+	if err != nil {
+		logger.Error("Error connecting to casbin database", "error", err.Error())
+		return 1
+	}
+	defer casbinDB.Close()
+
+	enforcer, err := casbin.CasbinEnforcer(casbinDB, casbinDSN, logger)
 	if err != nil {
-        log.Println("Error initializing casbin enforcer", "error", err.Error())
 		logger.Error("Error initializing enforcer", "error", err.Error())
-		return
+		return 1
     }
 
-	config := config.Load()
-	serviceManager, err := service.NewServiceManager()
+	watcher, err := casbin.NewPostgresWatcher(casbinDB, casbinDSN, logger)
+	if err != nil {
+		logger.Error("Error initializing casbin watcher", "error", err.Error())
+		return 1
+	}
+	defer watcher.Close()
+
+	if err := enforcer.SetWatcher(watcher); err != nil {
+		logger.Error("Error attaching casbin watcher", "error", err.Error())
+		return 1
+	}
+
+	audit, err := casbin.NewPostgresAudit(casbinDB)
+	if err != nil {
+		logger.Error("Error initializing policy audit", "error", err.Error())
+		return 1
+	}
+
+	serviceManager, err := service.NewServiceManager(config, logger)
 	if err != nil {
-		log.Println("Error initializing service manager", "error", err.Error())
 		logger.Error("Error initializing service manager", "error", err.Error())
-		return
+		return 1
 	}
 
+	var db *sql.DB
+	if config.TOKEN_STORE == "postgres" || config.OUTBOX_STORE == "postgres" || config.JOB_STORE == "postgres" || config.ALERT_STORE == "postgres" || config.RATE_LIMIT_STORE == "postgres" {
+		db, err = postgres.ConnectDB(config)
+		if err != nil {
+			logger.Error("Error connecting to gateway database", "error", err.Error())
+			return 1
+		}
+		defer db.Close()
+	}
+
+	revoked := tokenn.NewMemoryStore()
+	if config.TOKEN_STORE == "postgres" {
+		revoked, err = tokenn.NewPostgresStore(db)
+		if err != nil {
+			logger.Error("Error initializing token store", "error", err.Error())
+			return 1
+		}
+	}
 
-	handler := handler.NewHandler(serviceManager.UserService(), serviceManager.HealthSerivce(), serviceManager.LifeStyleService(),serviceManager.MedicalRecordService(), serviceManager.WearableService(), logger, enforcer)
-	controller := api.NewController(gin.Default())
+	events := outbox.NewMemoryStore()
+	if config.OUTBOX_STORE == "postgres" {
+		events, err = outbox.NewPostgresStore(db)
+		if err != nil {
+			logger.Error("Error initializing outbox", "error", err.Error())
+			return 1
+		}
+	}
+
+	jobs := job.NewMemoryStore()
+	if config.JOB_STORE == "postgres" {
+		jobs, err = job.NewPostgresStore(db)
+		if err != nil {
+			logger.Error("Error initializing job store", "error", err.Error())
+			return 1
+		}
+	}
+
+	rules := alert.NewMemoryStore()
+	if config.ALERT_STORE == "postgres" {
+		rules, err = alert.NewPostgresStore(db)
+		if err != nil {
+			logger.Error("Error initializing alert rule store", "error", err.Error())
+			return 1
+		}
+	}
+
+	limits, err := ratelimit.ParseLimits(config.RATE_LIMITS)
+	if err != nil {
+		logger.Error("Error parsing rate limits", "error", err.Error())
+		return 1
+	}
+	quotas, err := ratelimit.ParseQuotas(config.RATE_QUOTAS)
+	if err != nil {
+		logger.Error("Error parsing rate quotas", "error", err.Error())
+		return 1
+	}
+	buckets := ratelimit.NewMemoryStore()
+	if config.RATE_LIMIT_STORE == "postgres" {
+		buckets, err = ratelimit.NewPostgresStore(db)
+		if err != nil {
+			logger.Error("Error initializing rate limit store", "error", err.Error())
+			return 1
+		}
+	}
+	limiter := ratelimit.New(buckets, ratelimit.Config{Limits: limits, Quotas: quotas})
+
+	verifier, err := tokenn.NewVerifier(tokenn.VerifierConfig{
+		Algorithms:    config.JWT_ALGORITHMS,
+		HMACSecret:    config.ACCESS_TOKEN,
+		PublicKeyFile: config.JWT_PUBLIC_KEY_FILE,
+		JWKSFile:      config.JWT_JWKS_FILE,
+		Issuer:        config.JWT_ISSUER,
+		Audience:      config.JWT_AUDIENCE,
+		Leeway:        config.JWT_LEEWAY,
+	})
+	if err != nil {
+		logger.Error("Error initializing token verifier", "error", err.Error())
+		return 1
+	}
+
+	tokens, err := tokenn.NewIssuer(tokenn.IssuerConfig{
+		AccessTTL:      config.ACCESS_TOKEN_TTL,
+		RefreshTTL:     config.REFRESH_TOKEN_TTL,
+		Algorithm:      config.JWT_SIGNING_ALG,
+		HMACSecret:     config.ACCESS_TOKEN,
+		PrivateKeyFile: config.JWT_PRIVATE_KEY_FILE,
+		KeyID:          config.JWT_KEY_ID,
+		Issuer:         config.JWT_ISSUER,
+		Audience:       config.JWT_AUDIENCE,
+	})
+	if err != nil {
+		logger.Error("Error initializing token issuer", "error", err.Error())
+		return 1
+	}
+
+	producer, err := kafka.NewKafkaProducer(kafka.Config{
+		Brokers:        config.KAFKA_BROKERS,
+		RequiredAcks:   config.KAFKA_REQUIRED_ACKS,
+		BatchSize:      config.KAFKA_BATCH_SIZE,
+		BatchTimeout:   config.KAFKA_BATCH_TIMEOUT,
+		BatchBytes:     config.KAFKA_BATCH_BYTES,
+		Compression:    config.KAFKA_COMPRESSION,
+		MaxAttempts:    config.KAFKA_MAX_ATTEMPTS,
+		WriteTimeout:   config.KAFKA_WRITE_TIMEOUT,
+		PublishTimeout: config.KAFKA_PUBLISH_TIMEOUT,
+	})
+	if err != nil {
+		logger.Error("Error initializing kafka producer", "error", err.Error())
+		return 1
+	}
+	defer func() {
+		if err := producer.Close(); err != nil {
+			logger.Error("Error closing kafka producer", "error", err.Error())
+		}
+	}()
+
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	relay := outbox.NewRelay(events, producer, logger, outbox.RelayConfig{
+		PollInterval: config.OUTBOX_POLL_INTERVAL,
+		BatchSize:    config.OUTBOX_BATCH_SIZE,
+		MaxAttempts:  config.OUTBOX_MAX_ATTEMPTS,
+		BaseBackoff:  config.OUTBOX_BASE_BACKOFF,
+		MaxBackoff:   config.OUTBOX_MAX_BACKOFF,
+		Retention:    config.OUTBOX_RETENTION,
+	})
+	// workers are the goroutines that stop with background
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		relay.Run(background)
+	}()
+
+	sweeper := job.NewSweeper(jobs, logger, job.SweeperConfig{
+		Deadline:  config.JOB_DEADLINE,
+		Retention: config.JOB_RETENTION,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		sweeper.Run(background)
+	}()
+
+	replies, err := consumer.NewKafkaConsumer(consumer.Config{
+		Brokers: config.KAFKA_BROKERS,
+		Topic:   config.KAFKA_REPLY_TOPIC,
+		GroupID: config.KAFKA_REPLY_GROUP,
+	}, logger)
+	if err != nil {
+		logger.Error("Error initializing job reply consumer", "error", err.Error())
+		return 1
+	}
+	defer replies.Close()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		if err := replies.Consume(background, job.ReplyHandler(jobs, logger)); err != nil {
+			logger.Error("Job reply consumer stopped", "error", err.Error())
+		}
+	}()
+
+	// every replica reads the whole topic, as any of them may hold the
+	// user's stream, and only needs what arrives from now on
+	host, _ := os.Hostname()
+	notifications := stream.NewHub(stream.HubConfig{
+		MaxPerUser: config.STREAM_MAX_CONNS_PER_USER,
+		History:    config.STREAM_HISTORY,
+		HistoryTTL: config.STREAM_HISTORY_TTL,
+	})
+	notificationFeed, err := consumer.NewKafkaConsumer(consumer.Config{
+		Brokers:     config.KAFKA_BROKERS,
+		Topic:       "notification",
+		GroupID:     config.STREAM_GROUP_PREFIX + "-notification-" + host,
+		StartOffset: "last",
+	}, logger)
+	if err != nil {
+		logger.Error("Error initializing notification consumer", "error", err.Error())
+		return 1
+	}
+	defer notificationFeed.Close()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		if err := notificationFeed.Consume(background, stream.Notifications(notifications, logger)); err != nil {
+			logger.Error("Notification consumer stopped", "error", err.Error())
+		}
+	}()
+
+	vitals := stream.NewHub(stream.HubConfig{
+		MaxPerUser: config.STREAM_MAX_CONNS_PER_USER,
+		History:    config.STREAM_HISTORY,
+		HistoryTTL: config.STREAM_HISTORY_TTL,
+	})
+	vitalsFeed, err := consumer.NewKafkaConsumer(consumer.Config{
+		Brokers:     config.KAFKA_BROKERS,
+		Topic:       "werable",
+		GroupID:     config.STREAM_GROUP_PREFIX + "-werable-" + host,
+		StartOffset: "last",
+	}, logger)
+	if err != nil {
+		logger.Error("Error initializing wearable consumer", "error", err.Error())
+		return 1
+	}
+	defer vitalsFeed.Close()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		if err := vitalsFeed.Consume(background, stream.Vitals(vitals, logger)); err != nil {
+			logger.Error("Wearable consumer stopped", "error", err.Error())
+		}
+	}()
+
+	alertFeed, err := consumer.NewKafkaConsumer(consumer.Config{
+		Brokers:     config.KAFKA_BROKERS,
+		Topic:       "alerts",
+		GroupID:     config.STREAM_GROUP_PREFIX + "-alerts-" + host,
+		StartOffset: "last",
+	}, logger)
+	if err != nil {
+		logger.Error("Error initializing alert consumer", "error", err.Error())
+		return 1
+	}
+	defer alertFeed.Close()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		if err := alertFeed.Consume(background, stream.Alerts(vitals, logger)); err != nil {
+			logger.Error("Alert consumer stopped", "error", err.Error())
+		}
+	}()
+
+	if config.EVENT_ENCODING != event.EncodingJSON && config.EVENT_ENCODING != event.EncodingProtobuf {
+		logger.Error("Unknown event encoding", "encoding", config.EVENT_ENCODING)
+		return 1
+	}
+	encoder := event.Encoder{Producer: config.EVENT_PRODUCER, Encoding: config.EVENT_ENCODING}
+
+	handler := &handler.Handler{
+		User:       serviceManager.UserService(),
+		Health:     serviceManager.HealthSerivce(),
+		Lifestyle:  serviceManager.LifeStyleService(),
+		Mecdical:   serviceManager.MedicalRecordService(),
+		Wearable:   serviceManager.WearableService(),
+		Logger:     logger,
+		Enforcer:   enforcer,
+		Tokens:     tokens,
+		Verifier:   verifier,
+		Revoked:    revoked,
+		Audit:      audit,
+		Producer:   producer,
+		Outbox:     events,
+		Events:     encoder,
+		Jobs:       jobs,
+		ReplyTopic: config.KAFKA_REPLY_TOPIC,
+		Streams: handler.Streams{
+			Notifications: notifications,
+			Vitals:        vitals,
+			Heartbeat:     config.STREAM_HEARTBEAT,
+		},
+		Bulk: handler.BulkLimits{
+			MaxItems:       config.WEARABLE_BULK_MAX_ITEMS,
+			MaxBytes:       config.WEARABLE_BULK_MAX_BYTES,
+			ImportMaxBytes: config.WEARABLE_IMPORT_MAX_BYTES,
+			ImportTimeout:  config.WEARABLE_IMPORT_TIMEOUT,
+		},
+		Alerts: handler.Alerts{
+			Rules:  rules,
+			Engine: alert.NewEngine(rules, alert.SystemClock),
+		},
+		Limiter:   limiter,
+		Readiness: newReadiness(config, serviceManager, casbinDB, db, producer),
+	}
+	router := gin.Default()
+	// the client IP keys the rate limit of anonymous callers, so only
+	// the configured proxies may set it through X-Forwarded-For
+	if err := router.SetTrustedProxies(config.HTTP_TRUSTED_PROXIES); err != nil {
+		logger.Error("Error setting trusted proxies", "error", err.Error())
+		return 1
+	}
+	// handlers hand the gin context to backend calls; let it end with the request
+	router.ContextWithFallback = true
+	controller := api.NewController(router)
 	controller.SetupRoutes(*handler, logger)
-	controller.StartServer(config)
 
-}
\ No newline at end of file
+	report, err := casbin.CheckCoverage(enforcer, controller.ProtectedRoutes())
+	if err != nil {
+		logger.Error("Error checking policy coverage", "error", err.Error())
+		return 1
+	}
+	if !report.OK() {
+		report.Print(os.Stderr)
+		logger.Warn("Policy does not cover the router", "uncovered_routes", len(report.UncoveredRoutes), "unused_policies", len(report.UnusedPolicies), "empty_roles", len(report.EmptyRoles))
+		if config.POLICY_CHECK_STRICT {
+			logger.Error("Refusing to start: POLICY_CHECK_STRICT is set")
+			return 1
+		}
+	}
+
+	stopping, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- controller.StartServer(config)
+	}()
+
+	status := 0
+	select {
+	case err := <-served:
+		// the server only stops by itself when it can't serve, e.g. the port is taken
+		logger.Error("HTTP server stopped", "error", fmt.Sprint(err))
+		status = 1
+	case <-stopping.Done():
+		logger.Info("Shutting down", "timeout", config.HTTP_SHUTDOWN_TIMEOUT.String())
+	}
+	// a second signal kills the process at once
+	stopSignals()
+
+	shutdown, cancel := context.WithTimeout(context.Background(), config.HTTP_SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	// open streams would hold up the drain; their clients resume elsewhere
+	notifications.Close()
+	vitals.Close()
+	if err := controller.Shutdown(shutdown); err != nil {
+		logger.Error("Error draining HTTP requests", "error", err.Error())
+		status = 1
+	}
+
+	// no request can queue events anymore, so what is left can go out
+	stopBackground()
+	workers.Wait()
+	if err := relay.Flush(shutdown); err != nil {
+		logger.Error("Error flushing outbox", "error", err.Error())
+		status = 1
+	}
+
+	if err := serviceManager.Close(); err != nil {
+		logger.Error("Error closing backend connections", "error", err.Error())
+	}
+	// the consumers, the producer and the databases close as run returns
+	logger.Info("Shutdown complete", "status", status)
+	return status
+}
+
+// newReadiness checks the backends, the databases and Kafka. The checks
+// named in READY_CRITICAL decide readiness; Kafka is left out by default,
+// as the outbox holds events while it is down.
+func newReadiness(cfg config.Config, services service.ServiceManager, casbinDB, db *sql.DB, producer *kafka.KafkaProducer) *readiness.Checker {
+	critical := make(map[string]bool)
+	for _, name := range cfg.READY_CRITICAL {
+		critical[name] = true
+	}
+
+	var checks []readiness.Check
+	backends := services.Backends()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, readiness.Check{Name: name, Critical: critical[name], Probe: readiness.GRPC(backends[name])})
+	}
+
+	checks = append(checks, readiness.Check{Name: "casbin_db", Critical: critical["casbin_db"], Probe: readiness.Database(casbinDB)})
+	if db != nil {
+		checks = append(checks, readiness.Check{Name: "gateway_db", Critical: critical["gateway_db"], Probe: readiness.Database(db)})
+	}
+	checks = append(checks, readiness.Check{Name: "kafka", Critical: critical["kafka"], Probe: func(ctx context.Context) (map[string]string, error) {
+		return nil, producer.Ping(ctx)
+	}})
+	return readiness.New(cfg.READY_CHECK_TIMEOUT, checks...)
+}
+
+// checkPolicies is the check-policies subcommand. It reports where the stored
+// policy and the router disagree and exits non-zero when they do.
+func checkPolicies(logger *slog.Logger) int {
+	db, err := casbin.ConnectDB(casbin.ConnectionString(config.Load()))
+	if err != nil {
+		logger.Error("Error connecting to casbin database", "error", err.Error())
+		return 2
+	}
+	defer db.Close()
+
+	enforcer, err := casbin.ReadOnlyEnforcer(db)
+	if err != nil {
+		logger.Error("Error loading casbin policy", "error", err.Error())
+		return 2
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	controller := api.NewController(gin.New())
+	controller.SetupRoutes(handler.Handler{Logger: logger}, logger)
+
+	report, err := casbin.CheckCoverage(enforcer, controller.ProtectedRoutes())
+	if err != nil {
+		logger.Error("Error checking policy coverage", "error", err.Error())
+		return 2
+	}
+	report.Print(os.Stdout)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -19,7 +21,121 @@ type Config struct {
 	DB_USER           string
 	DB_PASSWORD       string
 	DB_NAME           string
+
+	// CASBIN_DB_* locate the policy database; host, port, user and
+	// password default to the DB_* ones.
+	CASBIN_DB_HOST     string
+	CASBIN_DB_PORT     string
+	CASBIN_DB_USER     string
+	CASBIN_DB_PASSWORD string
+	CASBIN_DB_NAME     string
+
 	ACCESS_TOKEN      string
+	ACCESS_TOKEN_TTL  time.Duration
+	REFRESH_TOKEN_TTL time.Duration
+	TOKEN_STORE       string
+
+	HTTP_READ_TIMEOUT        time.Duration
+	HTTP_READ_HEADER_TIMEOUT time.Duration
+	HTTP_WRITE_TIMEOUT       time.Duration
+	HTTP_IDLE_TIMEOUT        time.Duration
+	// HTTP_SHUTDOWN_TIMEOUT bounds draining requests and flushing the
+	// outbox on SIGTERM.
+	HTTP_SHUTDOWN_TIMEOUT time.Duration
+	// HTTP_TRUSTED_PROXIES are the addresses or CIDRs whose
+	// X-Forwarded-For header is believed; the client IP is the peer
+	// address otherwise. None by default.
+	HTTP_TRUSTED_PROXIES []string
+
+	JWT_ALGORITHMS       []string
+	JWT_SIGNING_ALG      string
+	JWT_PUBLIC_KEY_FILE  string
+	JWT_PRIVATE_KEY_FILE string
+	JWT_JWKS_FILE        string
+	JWT_KEY_ID           string
+	JWT_ISSUER           string
+	JWT_AUDIENCE         string
+	JWT_LEEWAY           time.Duration
+
+	POLICY_CHECK_STRICT bool
+
+	KAFKA_BROKERS         []string
+	KAFKA_REQUIRED_ACKS   string
+	KAFKA_BATCH_SIZE      int
+	KAFKA_BATCH_TIMEOUT   time.Duration
+	KAFKA_BATCH_BYTES     int64
+	KAFKA_COMPRESSION     string
+	KAFKA_MAX_ATTEMPTS    int
+	KAFKA_WRITE_TIMEOUT   time.Duration
+	KAFKA_PUBLISH_TIMEOUT time.Duration
+
+	OUTBOX_STORE         string
+	OUTBOX_POLL_INTERVAL time.Duration
+	OUTBOX_BATCH_SIZE    int
+	OUTBOX_MAX_ATTEMPTS  int
+	OUTBOX_BASE_BACKOFF  time.Duration
+	OUTBOX_MAX_BACKOFF   time.Duration
+	OUTBOX_RETENTION     time.Duration
+
+	EVENT_ENCODING string
+	EVENT_PRODUCER string
+
+	JOB_STORE         string
+	KAFKA_REPLY_TOPIC string
+	KAFKA_REPLY_GROUP string
+	// JOB_DEADLINE is how long a job waits for its reply before it
+	// expires; JOB_RETENTION how long a finished one is kept.
+	JOB_DEADLINE  time.Duration
+	JOB_RETENTION time.Duration
+
+	ALERT_STORE string
+
+	// GRPC_*_ADDRS are host:port addresses, or files of them, found as
+	// GRPC_RESOLVER says and looked up again every GRPC_RESOLVE_INTERVAL;
+	// see service.Endpoints.
+	GRPC_USER_ADDRS       []string
+	GRPC_HEALTH_ADDRS     []string
+	GRPC_RESOLVER         string
+	GRPC_RESOLVE_INTERVAL time.Duration
+	GRPC_LB_POLICY        string
+
+	// GRPC_* are the call policies of the backends. GRPC_CALL_POLICIES
+	// overrides them per backend or method; see service.ParseOverrides.
+	GRPC_TIMEOUT               time.Duration
+	GRPC_RETRY_ATTEMPTS        int
+	GRPC_RETRY_BACKOFF         time.Duration
+	GRPC_RETRY_MAX_BACKOFF     time.Duration
+	GRPC_CALL_POLICIES         []string
+	GRPC_BREAKER_WINDOW        time.Duration
+	GRPC_BREAKER_MIN_REQUESTS  int
+	GRPC_BREAKER_FAILURE_RATIO float64
+	GRPC_BREAKER_OPEN_FOR      time.Duration
+
+	// READY_CRITICAL names the readiness checks that make the gateway
+	// unready when they fail.
+	READY_CHECK_TIMEOUT time.Duration
+	READY_CRITICAL      []string
+
+	// RATE_LIMITS and RATE_QUOTAS are comma-separated; see
+	// ratelimit.ParseLimits and ratelimit.ParseQuotas.
+	RATE_LIMIT_STORE string
+	RATE_LIMITS      []string
+	RATE_QUOTAS      []string
+
+	// STREAM_GROUP_PREFIX plus the host name is the consumer group of the
+	// stream feeds; every replica needs every message.
+	STREAM_GROUP_PREFIX       string
+	STREAM_HEARTBEAT          time.Duration
+	STREAM_HISTORY            int
+	STREAM_HISTORY_TTL        time.Duration
+	STREAM_MAX_CONNS_PER_USER int
+
+	WEARABLE_BULK_MAX_ITEMS   int
+	WEARABLE_BULK_MAX_BYTES   int64
+	WEARABLE_IMPORT_MAX_BYTES int64
+	// WEARABLE_IMPORT_TIMEOUT replaces the HTTP timeouts for an import,
+	// whose upload can be large.
+	WEARABLE_IMPORT_TIMEOUT time.Duration
 }
 
 func Load() Config {
@@ -38,11 +154,112 @@ func Load() Config {
 	config.DB_USER = cast.ToString(coalesce("DB_USER", "postgres"))
 	config.DB_PASSWORD = cast.ToString(coalesce("DB_PASSWORD", "1111"))
 	config.DB_NAME = cast.ToString(coalesce("DB_NAME", "postgres"))
+	config.CASBIN_DB_HOST = cast.ToString(coalesce("CASBIN_DB_HOST", config.DB_HOST))
+	config.CASBIN_DB_PORT = cast.ToString(coalesce("CASBIN_DB_PORT", config.DB_PORT))
+	config.CASBIN_DB_USER = cast.ToString(coalesce("CASBIN_DB_USER", config.DB_USER))
+	config.CASBIN_DB_PASSWORD = cast.ToString(coalesce("CASBIN_DB_PASSWORD", config.DB_PASSWORD))
+	config.CASBIN_DB_NAME = cast.ToString(coalesce("CASBIN_DB_NAME", "casbin"))
 	config.ACCESS_TOKEN = cast.ToString(coalesce("ACCESS_TOKEN", "key"))
+	config.ACCESS_TOKEN_TTL = cast.ToDuration(coalesce("ACCESS_TOKEN_TTL", "1h"))
+	config.REFRESH_TOKEN_TTL = cast.ToDuration(coalesce("REFRESH_TOKEN_TTL", "168h"))
+	config.TOKEN_STORE = cast.ToString(coalesce("TOKEN_STORE", "memory"))
+
+	config.HTTP_READ_TIMEOUT = cast.ToDuration(coalesce("HTTP_READ_TIMEOUT", "30s"))
+	config.HTTP_READ_HEADER_TIMEOUT = cast.ToDuration(coalesce("HTTP_READ_HEADER_TIMEOUT", "10s"))
+	config.HTTP_WRITE_TIMEOUT = cast.ToDuration(coalesce("HTTP_WRITE_TIMEOUT", "60s"))
+	config.HTTP_IDLE_TIMEOUT = cast.ToDuration(coalesce("HTTP_IDLE_TIMEOUT", "120s"))
+	config.HTTP_SHUTDOWN_TIMEOUT = cast.ToDuration(coalesce("HTTP_SHUTDOWN_TIMEOUT", "30s"))
+	config.HTTP_TRUSTED_PROXIES = splitList(cast.ToString(coalesce("HTTP_TRUSTED_PROXIES", "")))
+
+	config.JWT_ALGORITHMS = splitList(cast.ToString(coalesce("JWT_ALGORITHMS", "HS256")))
+	config.JWT_SIGNING_ALG = cast.ToString(coalesce("JWT_SIGNING_ALG", "HS256"))
+	config.JWT_PUBLIC_KEY_FILE = cast.ToString(coalesce("JWT_PUBLIC_KEY_FILE", ""))
+	config.JWT_PRIVATE_KEY_FILE = cast.ToString(coalesce("JWT_PRIVATE_KEY_FILE", ""))
+	config.JWT_JWKS_FILE = cast.ToString(coalesce("JWT_JWKS_FILE", ""))
+	config.JWT_KEY_ID = cast.ToString(coalesce("JWT_KEY_ID", ""))
+	config.JWT_ISSUER = cast.ToString(coalesce("JWT_ISSUER", ""))
+	config.JWT_AUDIENCE = cast.ToString(coalesce("JWT_AUDIENCE", ""))
+	config.JWT_LEEWAY = cast.ToDuration(coalesce("JWT_LEEWAY", "30s"))
+
+	config.POLICY_CHECK_STRICT = cast.ToBool(coalesce("POLICY_CHECK_STRICT", false))
+
+	config.KAFKA_BROKERS = splitList(cast.ToString(coalesce("KAFKA_BROKERS", "kafka:9092")))
+	config.KAFKA_REQUIRED_ACKS = cast.ToString(coalesce("KAFKA_REQUIRED_ACKS", "all"))
+	config.KAFKA_BATCH_SIZE = cast.ToInt(coalesce("KAFKA_BATCH_SIZE", 100))
+	config.KAFKA_BATCH_TIMEOUT = cast.ToDuration(coalesce("KAFKA_BATCH_TIMEOUT", "10ms"))
+	config.KAFKA_BATCH_BYTES = cast.ToInt64(coalesce("KAFKA_BATCH_BYTES", 1048576))
+	config.KAFKA_COMPRESSION = cast.ToString(coalesce("KAFKA_COMPRESSION", "none"))
+	config.KAFKA_MAX_ATTEMPTS = cast.ToInt(coalesce("KAFKA_MAX_ATTEMPTS", 5))
+	config.KAFKA_WRITE_TIMEOUT = cast.ToDuration(coalesce("KAFKA_WRITE_TIMEOUT", "10s"))
+	config.KAFKA_PUBLISH_TIMEOUT = cast.ToDuration(coalesce("KAFKA_PUBLISH_TIMEOUT", "15s"))
+
+	config.OUTBOX_STORE = cast.ToString(coalesce("OUTBOX_STORE", "postgres"))
+	config.OUTBOX_POLL_INTERVAL = cast.ToDuration(coalesce("OUTBOX_POLL_INTERVAL", "1s"))
+	config.OUTBOX_BATCH_SIZE = cast.ToInt(coalesce("OUTBOX_BATCH_SIZE", 100))
+	config.OUTBOX_MAX_ATTEMPTS = cast.ToInt(coalesce("OUTBOX_MAX_ATTEMPTS", 10))
+	config.OUTBOX_BASE_BACKOFF = cast.ToDuration(coalesce("OUTBOX_BASE_BACKOFF", "1s"))
+	config.OUTBOX_MAX_BACKOFF = cast.ToDuration(coalesce("OUTBOX_MAX_BACKOFF", "5m"))
+	config.OUTBOX_RETENTION = cast.ToDuration(coalesce("OUTBOX_RETENTION", "168h"))
+
+	config.EVENT_ENCODING = cast.ToString(coalesce("EVENT_ENCODING", "json"))
+	config.EVENT_PRODUCER = cast.ToString(coalesce("EVENT_PRODUCER", "api-gateway"))
+
+	config.JOB_STORE = cast.ToString(coalesce("JOB_STORE", "postgres"))
+	config.KAFKA_REPLY_TOPIC = cast.ToString(coalesce("KAFKA_REPLY_TOPIC", "job-reply"))
+	config.KAFKA_REPLY_GROUP = cast.ToString(coalesce("KAFKA_REPLY_GROUP", "api-gateway"))
+	config.JOB_DEADLINE = cast.ToDuration(coalesce("JOB_DEADLINE", "1h"))
+	config.JOB_RETENTION = cast.ToDuration(coalesce("JOB_RETENTION", "168h"))
+
+	config.ALERT_STORE = cast.ToString(coalesce("ALERT_STORE", "postgres"))
+
+	config.GRPC_USER_ADDRS = splitList(cast.ToString(coalesce("GRPC_USER_ADDRS", "l-auth-service:"+strings.TrimPrefix(config.GRPC_USER_PORT, ":"))))
+	config.GRPC_HEALTH_ADDRS = splitList(cast.ToString(coalesce("GRPC_HEALTH_ADDRS", "health:"+strings.TrimPrefix(config.GRPC_PRODUCT_PORT, ":"))))
+	config.GRPC_RESOLVER = cast.ToString(coalesce("GRPC_RESOLVER", "dns"))
+	config.GRPC_RESOLVE_INTERVAL = cast.ToDuration(coalesce("GRPC_RESOLVE_INTERVAL", "30s"))
+	config.GRPC_LB_POLICY = cast.ToString(coalesce("GRPC_LB_POLICY", "round_robin"))
+
+	config.GRPC_TIMEOUT = cast.ToDuration(coalesce("GRPC_TIMEOUT", "5s"))
+	config.GRPC_RETRY_ATTEMPTS = cast.ToInt(coalesce("GRPC_RETRY_ATTEMPTS", 3))
+	config.GRPC_RETRY_BACKOFF = cast.ToDuration(coalesce("GRPC_RETRY_BACKOFF", "100ms"))
+	config.GRPC_RETRY_MAX_BACKOFF = cast.ToDuration(coalesce("GRPC_RETRY_MAX_BACKOFF", "1s"))
+	config.GRPC_CALL_POLICIES = splitList(cast.ToString(coalesce("GRPC_CALL_POLICIES", "")))
+	config.GRPC_BREAKER_WINDOW = cast.ToDuration(coalesce("GRPC_BREAKER_WINDOW", "30s"))
+	config.GRPC_BREAKER_MIN_REQUESTS = cast.ToInt(coalesce("GRPC_BREAKER_MIN_REQUESTS", 20))
+	config.GRPC_BREAKER_FAILURE_RATIO = cast.ToFloat64(coalesce("GRPC_BREAKER_FAILURE_RATIO", 0.5))
+	config.GRPC_BREAKER_OPEN_FOR = cast.ToDuration(coalesce("GRPC_BREAKER_OPEN_FOR", "15s"))
+
+	config.READY_CHECK_TIMEOUT = cast.ToDuration(coalesce("READY_CHECK_TIMEOUT", "2s"))
+	config.READY_CRITICAL = splitList(cast.ToString(coalesce("READY_CRITICAL", "user,health,casbin_db,gateway_db")))
+
+	config.RATE_LIMIT_STORE = cast.ToString(coalesce("RATE_LIMIT_STORE", "memory"))
+	config.RATE_LIMITS = splitList(cast.ToString(coalesce("RATE_LIMITS", "*=20/s:40,auth=10/m,wearable=10/s:50")))
+	config.RATE_QUOTAS = splitList(cast.ToString(coalesce("RATE_QUOTAS", "/api/health/generate=50")))
+
+	config.STREAM_GROUP_PREFIX = cast.ToString(coalesce("STREAM_GROUP_PREFIX", "api-gateway-stream"))
+	config.STREAM_HEARTBEAT = cast.ToDuration(coalesce("STREAM_HEARTBEAT", "15s"))
+	config.STREAM_HISTORY = cast.ToInt(coalesce("STREAM_HISTORY", 100))
+	config.STREAM_HISTORY_TTL = cast.ToDuration(coalesce("STREAM_HISTORY_TTL", "10m"))
+	config.STREAM_MAX_CONNS_PER_USER = cast.ToInt(coalesce("STREAM_MAX_CONNS_PER_USER", 5))
+
+	config.WEARABLE_BULK_MAX_ITEMS = cast.ToInt(coalesce("WEARABLE_BULK_MAX_ITEMS", 1000))
+	config.WEARABLE_BULK_MAX_BYTES = cast.ToInt64(coalesce("WEARABLE_BULK_MAX_BYTES", 5<<20))
+	config.WEARABLE_IMPORT_MAX_BYTES = cast.ToInt64(coalesce("WEARABLE_IMPORT_MAX_BYTES", 256<<20))
+	config.WEARABLE_IMPORT_TIMEOUT = cast.ToDuration(coalesce("WEARABLE_IMPORT_TIMEOUT", "10m"))
 
 	return config
 }
 
+// splitList turns a comma separated value into a slice, dropping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func coalesce(env string, defaultValue interface{}) interface{} {
 	value, exists := os.LookupEnv(env)
 	if !exists {
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"api-gateway/measure"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const appleTimeLayout = "2006-01-02 15:04:05 -0700"
+
+// appleTypes maps HealthKit types onto data types. The systolic and
+// diastolic records are not listed; they are read from the blood pressure
+// correlation that holds them.
+var appleTypes = map[string]string{
+	"HKQuantityTypeIdentifierHeartRate":              measure.HeartRate,
+	"HKQuantityTypeIdentifierStepCount":              measure.Steps,
+	"HKQuantityTypeIdentifierBodyMass":               measure.Weight,
+	"HKQuantityTypeIdentifierBloodGlucose":           measure.Glucose,
+	"HKQuantityTypeIdentifierOxygenSaturation":       measure.OxygenSaturation,
+	"HKQuantityTypeIdentifierDistanceWalkingRunning": measure.Distance,
+	"HKQuantityTypeIdentifierActiveEnergyBurned":     measure.Calories,
+	"HKCategoryTypeIdentifierSleepAnalysis":          measure.SleepMinutes,
+	"HKCorrelationTypeIdentifierBloodPressure":       measure.BloodPressure,
+}
+
+type appleRecord struct {
+	Type       string        `xml:"type,attr"`
+	SourceName string        `xml:"sourceName,attr"`
+	Device     string        `xml:"device,attr"`
+	Unit       string        `xml:"unit,attr"`
+	Value      string        `xml:"value,attr"`
+	StartDate  string        `xml:"startDate,attr"`
+	EndDate    string        `xml:"endDate,attr"`
+	Records    []appleRecord `xml:"Record"`
+}
+
+// readAppleHealth reads export.xml of Apple Health one element at a time,
+// so exports of any size take little memory. Blood pressure is read from
+// its correlations, which pair the systolic and diastolic records.
+func readAppleHealth(r io.Reader, each func(Result) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Record", "Correlation":
+			var rec appleRecord
+			if err := dec.DecodeElement(&rec, &start); err != nil {
+				return err
+			}
+			if err := each(appleResult(rec)); err != nil {
+				return err
+			}
+		case "HealthData":
+			// the root; its children are read one by one
+		default:
+			if err := dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func appleResult(rec appleRecord) Result {
+	dataType := appleTypes[rec.Type]
+	if dataType == "" {
+		return Result{Skipped: true}
+	}
+
+	at, err := time.Parse(appleTimeLayout, rec.StartDate)
+	if err != nil {
+		return Result{Invalid: fmt.Errorf("invalid startDate %q", rec.StartDate)}
+	}
+	record := Record{DataType: dataType, DeviceType: appleDevice(rec), At: at}
+
+	switch dataType {
+	case measure.SleepMinutes:
+		// only time asleep counts, not time in bed or awake
+		if !strings.HasPrefix(rec.Value, "HKCategoryValueSleepAnalysisAsleep") {
+			return Result{Skipped: true}
+		}
+		end, err := time.Parse(appleTimeLayout, rec.EndDate)
+		if err != nil || end.Before(at) {
+			return Result{Invalid: fmt.Errorf("invalid endDate %q", rec.EndDate)}
+		}
+		value, err := convert(dataType, end.Sub(at).Minutes(), "")
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	case measure.BloodPressure:
+		var systolic, diastolic string
+		for _, part := range rec.Records {
+			switch part.Type {
+			case "HKQuantityTypeIdentifierBloodPressureSystolic":
+				systolic = part.Value
+			case "HKQuantityTypeIdentifierBloodPressureDiastolic":
+				diastolic = part.Value
+			}
+		}
+		if systolic == "" || diastolic == "" {
+			return Result{Invalid: fmt.Errorf("blood pressure without systolic and diastolic values")}
+		}
+		value, err := bloodPressure(systolic, diastolic)
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	case measure.OxygenSaturation:
+		v, err := strconv.ParseFloat(rec.Value, 64)
+		if err != nil {
+			return Result{Invalid: fmt.Errorf("invalid %s value %q", dataType, rec.Value)}
+		}
+		// Apple stores a fraction under the unit "%"
+		if v <= 1 {
+			v *= 100
+		}
+		if record.Value, err = convert(dataType, v, ""); err != nil {
+			return Result{Invalid: err}
+		}
+	default:
+		value, err := parseValue(dataType, rec.Value, rec.Unit)
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	}
+	return Result{Record: record}
+}
+
+// appleDevice names the device a record came from, e.g. "Apple Watch".
+func appleDevice(rec appleRecord) string {
+	// device looks like "<<HKDevice: 0x...>, name:Apple Watch, manufacturer:Apple Inc., ...>"
+	if i := strings.Index(rec.Device, "name:"); i >= 0 {
+		name := rec.Device[i+len("name:"):]
+		if j := strings.IndexAny(name, ",>"); j >= 0 {
+			name = name[:j]
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	if rec.SourceName != "" {
+		return rec.SourceName
+	}
+	return "Apple Health"
+}
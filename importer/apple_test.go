@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadAppleHealth(t *testing.T) {
+	got := readFixture(t, "apple_export.xml", FormatAppleHealth, Options{})
+	equal(t, got, []string{
+		"heart_rate Apple Watch 72 2024-06-01T06:00:00Z",
+		// no device, so the source names it
+		"steps iPhone 532 2024-06-01T06:00:00Z",
+		"weight Scale 70 2024-06-01T05:00:00Z",
+		"glucose Meter 5.5 2024-06-01T05:30:00Z",
+		// a fraction under the unit %
+		"oxygen_saturation Apple Watch 97 2024-06-01T01:00:00Z",
+		"sleep_minutes Apple Watch 450 2024-05-31T21:00:00Z",
+		// time in bed is not sleep
+		"skipped",
+		"blood_pressure Omron 121/79 2024-06-01T06:30:00Z",
+		// caffeine is not tracked
+		"skipped",
+		`invalid: invalid startDate "yesterday"`,
+		`invalid: invalid heart_rate value "fast"`,
+	})
+}
+
+func TestReadAppleHealthTruncated(t *testing.T) {
+	data, err := os.ReadFile("testdata/apple_export.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cut := strings.Index(string(data), "<Correlation")
+	records := 0
+	err = Read(strings.NewReader(string(data[:cut+40])), FormatAppleHealth, Options{}, func(Result) error {
+		records++
+		return nil
+	})
+	if err == nil {
+		t.Error("truncated export read without an error")
+	}
+	if records != 7 {
+		t.Errorf("read %d records before the break, want the 7 complete ones", records)
+	}
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"api-gateway/measure"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const fitbitDevice = "Fitbit"
+
+// fitbitTimeLayouts are the timestamps of Fitbit's Takeout files and Web
+// API. They carry no zone and are read as UTC.
+var fitbitTimeLayouts = []string{
+	"01/02/06 15:04:05",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"01/02/06",
+	"2006-01-02",
+}
+
+// fitbitSeries are the Web API keys whose entries are plain
+// {dateTime, value} pairs, with the data type and unit they hold.
+var fitbitSeries = map[string]struct{ dataType, unit string }{
+	"activities-steps":    {measure.Steps, ""},
+	"activities-calories": {measure.Calories, "kcal"},
+	"activities-distance": {measure.Distance, "km"},
+}
+
+// fitbitPlainTypes may be named in Options.DataType for Takeout files of
+// plain {dateTime, value} pairs.
+var fitbitPlainTypes = map[string]bool{measure.Steps: true, measure.Calories: true}
+
+type fitbitEntry struct {
+	DateTime string          `json:"dateTime"`
+	Value    json.RawMessage `json:"value"`
+
+	// weight logs
+	Weight *float64 `json:"weight"`
+	Date   string   `json:"date"`
+	Time   string   `json:"time"`
+
+	// sleep logs
+	StartTime     string   `json:"startTime"`
+	MinutesAsleep *float64 `json:"minutesAsleep"`
+}
+
+// readFitbit reads a Fitbit Takeout file, which is an array of entries, or
+// a Web API response with the series, sleep and weight keys.
+func readFitbit(r io.Reader, opts Options, each func(Result) error) error {
+	if opts.DataType != "" && !fitbitPlainTypes[opts.DataType] {
+		return fmt.Errorf("%w: data_type must be one of: steps, calories", ErrInvalidOption)
+	}
+
+	keys := []string{"sleep", "weight"}
+	for key := range fitbitSeries {
+		keys = append(keys, key)
+	}
+	return streamArrays(r, keys, func(key string, raw json.RawMessage) error {
+		var entry fitbitEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return each(Result{Invalid: fmt.Errorf("invalid entry: %v", err)})
+		}
+		return each(fitbitResult(key, entry, opts))
+	})
+}
+
+func fitbitResult(key string, e fitbitEntry, opts Options) Result {
+	switch {
+	case e.MinutesAsleep != nil:
+		at, err := fitbitTime(e.StartTime)
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		value, err := convert(measure.SleepMinutes, *e.MinutesAsleep, "")
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		return Result{Record: Record{DataType: measure.SleepMinutes, DeviceType: fitbitDevice, Value: value, At: at}}
+
+	case e.Weight != nil:
+		at, err := fitbitTime(strings.TrimSpace(e.Date + " " + e.Time))
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		value, err := convert(measure.Weight, *e.Weight, opts.Unit)
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		return Result{Record: Record{DataType: measure.Weight, DeviceType: fitbitDevice, Value: value, At: at}}
+	}
+
+	if len(e.Value) == 0 || e.DateTime == "" {
+		return Result{Skipped: true}
+	}
+	at, err := fitbitTime(e.DateTime)
+	if err != nil {
+		return Result{Invalid: err}
+	}
+
+	if e.Value[0] == '{' {
+		var heart struct {
+			BPM *float64 `json:"bpm"`
+		}
+		if err := json.Unmarshal(e.Value, &heart); err != nil || heart.BPM == nil {
+			// e.g. daily heart rate zones
+			return Result{Skipped: true}
+		}
+		value, err := convert(measure.HeartRate, *heart.BPM, "")
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		return Result{Record: Record{DataType: measure.HeartRate, DeviceType: fitbitDevice, Value: value, At: at}}
+	}
+
+	dataType, unit := opts.DataType, opts.Unit
+	if series, ok := fitbitSeries[key]; ok {
+		dataType, unit = series.dataType, series.unit
+	}
+	if dataType == "" {
+		return Result{Invalid: fmt.Errorf("entry of unknown type; set data_type")}
+	}
+
+	value, err := parseValue(dataType, strings.Trim(string(e.Value), `"`), unit)
+	if err != nil {
+		return Result{Invalid: err}
+	}
+	return Result{Record: Record{DataType: dataType, DeviceType: fitbitDevice, Value: value, At: at}}
+}
+
+func fitbitTime(value string) (time.Time, error) {
+	for _, layout := range fitbitTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
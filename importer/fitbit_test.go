@@ -0,0 +1,38 @@
+package importer
+
+import "testing"
+
+func TestReadFitbitWebAPI(t *testing.T) {
+	got := readFixture(t, "fitbit_webapi.json", FormatFitbit, Options{Unit: "lb"})
+	// results follow the file, whatever the order of fitbitSeries
+	equal(t, got, []string{
+		"steps Fitbit 8042 2024-06-01T00:00:00Z",
+		`invalid: invalid steps value "many"`,
+		// km
+		"distance Fitbit 6100 2024-06-01T00:00:00Z",
+		"sleep_minutes Fitbit 412 2024-05-31T23:10:00Z",
+		// 154.3 lb
+		"weight Fitbit 69.99 2024-06-01T07:00:00Z",
+	})
+}
+
+func TestReadFitbitTakeout(t *testing.T) {
+	got := readFixture(t, "fitbit_takeout_heart.json", FormatFitbit, Options{})
+	equal(t, got, []string{
+		"heart_rate Fitbit 71 2024-06-01T08:00:00Z",
+		"heart_rate Fitbit 73 2024-06-01T08:00:05Z",
+		`invalid: invalid date "June 1st"`,
+	})
+
+	// plain entries say nothing about what they hold
+	got = readFixture(t, "fitbit_takeout_steps.json", FormatFitbit, Options{})
+	equal(t, got, []string{
+		"invalid: entry of unknown type; set data_type",
+		"invalid: entry of unknown type; set data_type",
+	})
+	got = readFixture(t, "fitbit_takeout_steps.json", FormatFitbit, Options{DataType: "steps"})
+	equal(t, got, []string{
+		"steps Fitbit 112 2024-06-01T08:00:00Z",
+		"steps Fitbit 0 2024-06-01T08:01:00Z",
+	})
+}
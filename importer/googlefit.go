@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"api-gateway/measure"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const googleFitDevice = "Google Fit"
+
+// googleFitTypes maps Google Fit data types onto data types.
+var googleFitTypes = map[string]string{
+	"com.google.heart_rate.bpm":    measure.HeartRate,
+	"com.google.step_count.delta":  measure.Steps,
+	"com.google.weight":            measure.Weight,
+	"com.google.blood_glucose":     measure.Glucose,
+	"com.google.blood_pressure":    measure.BloodPressure,
+	"com.google.oxygen_saturation": measure.OxygenSaturation,
+	"com.google.distance.delta":    measure.Distance,
+	"com.google.calories.expended": measure.Calories,
+	"com.google.sleep.segment":     measure.SleepMinutes,
+}
+
+// googleFitAsleep are the sleep segment types that count as asleep: sleep,
+// light, deep and REM.
+var googleFitAsleep = map[int64]bool{2: true, 4: true, 5: true, 6: true}
+
+type fitValue struct {
+	FpVal  *float64 `json:"fpVal"`
+	IntVal *int64   `json:"intVal"`
+}
+
+// fitPoint is a data point of a Google Takeout file ("Data Points", values
+// under fitValue) or of the Fitness REST API ("point", values under value).
+type fitPoint struct {
+	DataTypeName   string      `json:"dataTypeName"`
+	StartTimeNanos json.Number `json:"startTimeNanos"`
+	EndTimeNanos   json.Number `json:"endTimeNanos"`
+	Value          []fitValue  `json:"value"`
+	FitValue       []struct {
+		Value fitValue `json:"value"`
+	} `json:"fitValue"`
+}
+
+func (p fitPoint) values() []fitValue {
+	if len(p.Value) > 0 {
+		return p.Value
+	}
+	values := make([]fitValue, len(p.FitValue))
+	for i, v := range p.FitValue {
+		values[i] = v.Value
+	}
+	return values
+}
+
+func readGoogleFit(r io.Reader, each func(Result) error) error {
+	return streamArrays(r, []string{"Data Points", "point"}, func(_ string, raw json.RawMessage) error {
+		var point fitPoint
+		if err := json.Unmarshal(raw, &point); err != nil {
+			return each(Result{Invalid: fmt.Errorf("invalid data point: %v", err)})
+		}
+		return each(googleFitResult(point))
+	})
+}
+
+func googleFitResult(p fitPoint) Result {
+	dataType := googleFitTypes[p.DataTypeName]
+	if dataType == "" {
+		return Result{Skipped: true}
+	}
+
+	start, err := nanos(p.StartTimeNanos)
+	if err != nil {
+		return Result{Invalid: fmt.Errorf("invalid startTimeNanos %q", p.StartTimeNanos)}
+	}
+	record := Record{DataType: dataType, DeviceType: googleFitDevice, At: start}
+
+	values := p.values()
+	number := func(i int) (float64, bool) {
+		if i >= len(values) {
+			return 0, false
+		}
+		switch {
+		case values[i].FpVal != nil:
+			return *values[i].FpVal, true
+		case values[i].IntVal != nil:
+			return float64(*values[i].IntVal), true
+		}
+		return 0, false
+	}
+
+	switch dataType {
+	case measure.SleepMinutes:
+		stage, ok := number(0)
+		if !ok {
+			return Result{Invalid: fmt.Errorf("sleep segment without a stage")}
+		}
+		if !googleFitAsleep[int64(stage)] {
+			return Result{Skipped: true}
+		}
+		end, err := nanos(p.EndTimeNanos)
+		if err != nil || end.Before(start) {
+			return Result{Invalid: fmt.Errorf("invalid endTimeNanos %q", p.EndTimeNanos)}
+		}
+		value, err := convert(dataType, end.Sub(start).Minutes(), "")
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	case measure.BloodPressure:
+		systolic, ok1 := number(0)
+		diastolic, ok2 := number(1)
+		if !ok1 || !ok2 {
+			return Result{Invalid: fmt.Errorf("blood pressure without systolic and diastolic values")}
+		}
+		value, err := pressure(systolic, diastolic)
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	default:
+		v, ok := number(0)
+		if !ok {
+			return Result{Invalid: fmt.Errorf("%s point without a value", p.DataTypeName)}
+		}
+		value, err := convert(dataType, v, "")
+		if err != nil {
+			return Result{Invalid: err}
+		}
+		record.Value = value
+	}
+	return Result{Record: record}
+}
+
+func nanos(n json.Number) (time.Time, error) {
+	v, err := n.Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, v).UTC(), nil
+}
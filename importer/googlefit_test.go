@@ -0,0 +1,28 @@
+package importer
+
+import "testing"
+
+func TestReadGoogleFitTakeout(t *testing.T) {
+	got := readFixture(t, "googlefit_takeout.json", FormatGoogleFit, Options{})
+	equal(t, got, []string{
+		"heart_rate Google Fit 64 2024-06-01T06:00:00Z",
+		"steps Google Fit 1200 2024-06-01T06:00:00Z",
+		"blood_pressure Google Fit 118/76 2024-06-01T06:00:00Z",
+		// light sleep from 05:00 to 07:00
+		"sleep_minutes Google Fit 120 2024-06-01T05:00:00Z",
+		// awake
+		"skipped",
+		// hydration is not tracked
+		"skipped",
+		"invalid: com.google.weight point without a value",
+		"invalid: invalid data point: json: cannot unmarshal string into Go value of type importer.fitPoint",
+	})
+}
+
+func TestReadGoogleFitDataset(t *testing.T) {
+	got := readFixture(t, "googlefit_api.json", FormatGoogleFit, Options{})
+	equal(t, got, []string{
+		"weight Google Fit 70.5 2024-06-01T06:00:00Z",
+		`invalid: invalid data point: json: cannot unmarshal string "soon" into Go value of type json.Number: invalid syntax`,
+	})
+}
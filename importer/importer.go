@@ -0,0 +1,120 @@
+// Package importer reads health data exports of other apps and turns them
+// into wearable readings with the gateway's data types and units.
+package importer
+
+import (
+	"api-gateway/measure"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatAppleHealth = "apple_health"
+	FormatGoogleFit   = "google_fit"
+	FormatFitbit      = "fitbit"
+)
+
+// Formats lists the export formats Read understands.
+var Formats = []string{FormatAppleHealth, FormatGoogleFit, FormatFitbit}
+
+var (
+	ErrUnknownFormat = errors.New("importer: unknown format")
+	ErrInvalidOption = errors.New("importer: invalid option")
+)
+
+// Record is one reading in the gateway's terms: a data type of the measure
+// registry and a value in that type's canonical unit.
+type Record struct {
+	DataType   string
+	DeviceType string
+	Value      string
+	// At is when the reading was taken.
+	At time.Time
+}
+
+// Result is what became of one record of the export. A record either
+// yields a Record, is skipped because the gateway doesn't track its type,
+// or is invalid.
+type Result struct {
+	Record  Record
+	Skipped bool
+	Invalid error
+}
+
+type Options struct {
+	// DataType names what a Fitbit series of plain {dateTime, value}
+	// entries holds, e.g. "steps", as those entries don't say.
+	DataType string
+	// Unit is the unit of Fitbit values whose unit the export doesn't
+	// give, e.g. "lb" for weight logs of an account set to pounds.
+	Unit string
+}
+
+// Read streams the export in r and calls each for every record in it. An
+// error from each stops the import and is returned.
+func Read(r io.Reader, format string, opts Options, each func(Result) error) error {
+	switch format {
+	case FormatAppleHealth:
+		return readAppleHealth(r, each)
+	case FormatGoogleFit:
+		return readGoogleFit(r, each)
+	case FormatFitbit:
+		return readFitbit(r, opts, each)
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+// appleUnits renames units Apple writes differently from the registry.
+var appleUnits = map[string]string{
+	// mmol/L with the molar mass of glucose
+	"mmol<180.1558800000541>/L": "mmol/L",
+}
+
+// convert returns value, given in unit, in the canonical unit of dataType.
+// The empty unit means the value is already canonical.
+func convert(dataType string, value float64, unit string) (string, error) {
+	if renamed, ok := appleUnits[unit]; ok {
+		unit = renamed
+	}
+	v, err := measure.Convert(dataType, value, unit)
+	return v, readable(err)
+}
+
+func parseValue(dataType, value, unit string) (string, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s value %q", dataType, value)
+	}
+	return convert(dataType, v, unit)
+}
+
+// bloodPressure formats a reading in mmHg as "systolic/diastolic".
+func bloodPressure(systolic, diastolic string) (string, error) {
+	sys, err := strconv.ParseFloat(systolic, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid systolic value %q", systolic)
+	}
+	dia, err := strconv.ParseFloat(diastolic, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid diastolic value %q", diastolic)
+	}
+	return pressure(sys, dia)
+}
+
+func pressure(systolic, diastolic float64) (string, error) {
+	v, err := measure.Pressure(systolic, diastolic)
+	return v, readable(err)
+}
+
+// readable drops the package prefix of a registry error, as a report
+// lists it against the record.
+func readable(err error) error {
+	var invalid *measure.ValueError
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("%s value %s: %s", invalid.DataType, invalid.Value, invalid.Reason)
+	}
+	return err
+}
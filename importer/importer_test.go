@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readFixture imports testdata/name and describes every result as
+// "data_type device value time", "skipped" or "invalid: reason".
+func readFixture(t *testing.T, name, format string, opts Options) []string {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []string
+	err = Read(f, format, opts, func(r Result) error {
+		switch {
+		case r.Skipped:
+			got = append(got, "skipped")
+		case r.Invalid != nil:
+			got = append(got, "invalid: "+r.Invalid.Error())
+		default:
+			got = append(got, fmt.Sprintf("%s %s %s %s", r.Record.DataType, r.Record.DeviceType, r.Record.Value, r.Record.At.UTC().Format(time.RFC3339)))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func equal(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d: %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadOptions(t *testing.T) {
+	each := func(Result) error { return nil }
+	if err := Read(strings.NewReader("[]"), "samsung_health", Options{}, each); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("unknown format: %v, want ErrUnknownFormat", err)
+	}
+	if err := Read(strings.NewReader("[]"), FormatFitbit, Options{DataType: "weight"}, each); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("fitbit data_type weight: %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestReadStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	f, err := os.Open("testdata/googlefit_takeout.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err = Read(f, FormatGoogleFit, Options{}, func(Result) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("error %v after %d records, want stop after 1", err, calls)
+	}
+}
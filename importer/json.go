@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// streamArrays reads a JSON document one array element at a time. It calls
+// each for the elements of the document when it is an array, or of the
+// arrays under the given keys of the top-level object. Everything else is
+// skipped without being kept in memory.
+func streamArrays(r io.Reader, keys []string, each func(key string, raw json.RawMessage) error) error {
+	wanted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		wanted[k] = true
+	}
+
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		return streamElements(dec, "", each)
+	case json.Delim('{'):
+	default:
+		return fmt.Errorf("expected a JSON object or array")
+	}
+
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if wanted[key] && tok == json.Delim('[') {
+			if err := streamElements(dec, key, each); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := skipValue(dec, tok); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// streamElements calls each for every element of the array whose opening
+// bracket was just read, and reads the closing one.
+func streamElements(dec *json.Decoder, key string, each func(string, json.RawMessage) error) error {
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err := each(key, raw); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// skipValue reads past the value that starts with tok.
+func skipValue(dec *json.Decoder, tok json.Token) error {
+	if tok != json.Delim('{') && tok != json.Delim('[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package consumer reads the topics the gateway listens on, such as the
+// replies backend services send when they finish a job.
+package consumer
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Handler processes one message. A returned error is retried, so handlers
+// should return nil for messages they can never process and log them.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+// Consumer delivers messages of one topic to a handler. Tests can pass the
+// MemoryBroker's consumers.
+type Consumer interface {
+	// Consume calls handle for every message until ctx is done, which is
+	// not an error.
+	Consume(ctx context.Context, handle Handler) error
+	Close() error
+}
+
+type Config struct {
+	Brokers []string
+	Topic   string
+	// GroupID shares the topic between replicas; every message is handled
+	// by one of them and offsets are committed after it.
+	GroupID string
+	// StartOffset is where a new group starts reading: "first" (the
+	// default) or "last" for only what arrives from now on.
+	StartOffset string
+	// MaxWait bounds how long a fetch waits for new messages.
+	MaxWait time.Duration
+	// RetryBackoff is the first wait before a failed message is handled
+	// again. It doubles up to 30s.
+	RetryBackoff time.Duration
+}
+
+type kafkaConsumer struct {
+	reader *kafkago.Reader
+	logger *slog.Logger
+	cfg    Config
+}
+
+func NewKafkaConsumer(cfg Config, logger *slog.Logger) (Consumer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+	if cfg.Topic == "" || cfg.GroupID == "" {
+		return nil, errors.New("kafka: consumer needs a topic and a group id")
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+
+	startOffset := kafkago.FirstOffset
+	switch cfg.StartOffset {
+	case "", "first":
+	case "last":
+		startOffset = kafkago.LastOffset
+	default:
+		return nil, fmt.Errorf("kafka: unknown start offset %q", cfg.StartOffset)
+	}
+
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
+		StartOffset: startOffset,
+		MaxWait:     cfg.MaxWait,
+	})
+	return &kafkaConsumer{reader: reader, logger: logger, cfg: cfg}, nil
+}
+
+func (k *kafkaConsumer) Consume(ctx context.Context, handle Handler) error {
+	for {
+		record, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		msg := kafka.Message{Topic: record.Topic, Key: record.Key, Value: record.Value, Partition: record.Partition, Offset: record.Offset}
+		if len(record.Headers) > 0 {
+			msg.Headers = make(map[string]string, len(record.Headers))
+			for _, h := range record.Headers {
+				msg.Headers[h.Key] = string(h.Value)
+			}
+		}
+
+		if !k.handle(ctx, handle, msg, record.Offset) {
+			return nil
+		}
+		if err := k.reader.CommitMessages(ctx, record); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// handle retries msg until it is handled. It reports false when ctx ends
+// first; the message is then not committed and is read again later.
+func (k *kafkaConsumer) handle(ctx context.Context, handle Handler, msg kafka.Message, offset int64) bool {
+	wait := k.cfg.RetryBackoff
+	for {
+		err := handle(ctx, msg)
+		if err == nil {
+			return true
+		}
+		k.logger.Warn("Error handling kafka message", "topic", msg.Topic, "offset", offset, "retry_in", wait, "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > 30*time.Second {
+			wait = 30 * time.Second
+		}
+	}
+}
+
+func (k *kafkaConsumer) Close() error {
+	return k.reader.Close()
+}
@@ -0,0 +1,143 @@
+package consumer
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBrokerClosed = errors.New("kafka: broker closed")
+
+// MemoryBroker is an in-process stand-in for Kafka in tests and local runs.
+// It is a kafka.ProducerIkafka, and every consumer it hands out sees each
+// message published to its topic after the consumer was created. Each topic
+// has a single partition.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	subs    map[string]map[*memoryConsumer]struct{}
+	offsets map[string]int64
+	closed  bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subs:    make(map[string]map[*memoryConsumer]struct{}),
+		offsets: make(map[string]int64),
+	}
+}
+
+func (b *MemoryBroker) Producermessage(ctx context.Context, topic string, msg []byte) error {
+	return b.Publish(ctx, kafka.Message{Topic: topic, Value: msg})
+}
+
+// Publish hands the messages to the topic's consumers. It blocks while a
+// consumer's buffer is full, like a broker that applies back pressure.
+func (b *MemoryBroker) Publish(ctx context.Context, msgs ...kafka.Message) error {
+	for _, msg := range msgs {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return ErrBrokerClosed
+		}
+		msg.Partition = 0
+		msg.Offset = b.offsets[msg.Topic]
+		b.offsets[msg.Topic]++
+		subs := make([]*memoryConsumer, 0, len(b.subs[msg.Topic]))
+		for c := range b.subs[msg.Topic] {
+			subs = append(subs, c)
+		}
+		b.mu.Unlock()
+
+		for _, c := range subs {
+			select {
+			case c.messages <- msg:
+			case <-c.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// Consumer subscribes to topic right away, so nothing published before
+// Consume is called gets lost.
+func (b *MemoryBroker) Consumer(topic string) Consumer {
+	c := &memoryConsumer{
+		broker:   b,
+		topic:    topic,
+		messages: make(chan kafka.Message, 256),
+		done:     make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c.done)
+		return c
+	}
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[*memoryConsumer]struct{})
+	}
+	b.subs[topic][c] = struct{}{}
+	return c
+}
+
+// Close stops every consumer.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for c := range subs {
+			c.closeOnce.Do(func() { close(c.done) })
+		}
+	}
+	b.subs = nil
+	return nil
+}
+
+type memoryConsumer struct {
+	broker    *MemoryBroker
+	topic     string
+	messages  chan kafka.Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *memoryConsumer) Consume(ctx context.Context, handle Handler) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.done:
+			return nil
+		case msg := <-c.messages:
+			// retry like the Kafka consumer does, with a short fixed wait
+			for handle(ctx, msg) != nil {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-c.done:
+					return nil
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}
+	}
+}
+
+func (c *memoryConsumer) Close() error {
+	c.broker.mu.Lock()
+	delete(c.broker.subs[c.topic], c)
+	c.broker.mu.Unlock()
+
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
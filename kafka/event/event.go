@@ -0,0 +1,269 @@
+// Package event defines the envelope every gateway message on Kafka is
+// wrapped in, and which payload each topic carries.
+package event
+
+import (
+	"api-gateway/genproto/health"
+	"api-gateway/genproto/user"
+	kafka "api-gateway/kafka/producer"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Kafka header names. They are set in both encodings, so consumers can
+// route and dedupe without decoding the value.
+const (
+	HeaderID            = "event-id"
+	HeaderType          = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderOccurredAt    = "occurred-at"
+	HeaderProducer      = "producer"
+	HeaderRequestID     = "request-id"
+	HeaderTraceID       = "trace-id"
+	HeaderContentType   = "content-type"
+	// HeaderReplyTo names the topic the handling service reports back on
+	// when the gateway tracks the event as a job.
+	HeaderReplyTo = "reply-to"
+	// HeaderSource is SourceImport on readings imported from another app.
+	// They are history, not live vitals, so they must not be streamed or
+	// raise alerts.
+	HeaderSource = "source"
+)
+
+// SourceImport marks events of data imported from another app.
+const SourceImport = "import"
+
+var (
+	ErrUnknownTopic = errors.New("event: no contract for topic")
+	ErrWrongPayload = errors.New("event: payload does not match the topic contract")
+)
+
+// Envelope is the JSON value of a message in the json encoding. In the
+// protobuf encoding the value is the bare payload and these fields travel
+// as headers only.
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Producer      string          `json:"producer"`
+	RequestID     string          `json:"request_id,omitempty"`
+	TraceID       string          `json:"trace_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Contract pins what a topic carries. Bump Version on any change that an
+// existing consumer can't read.
+type Contract struct {
+	Topic   string
+	Type    string
+	Version int
+	// New returns an empty payload to decode into.
+	New func() proto.Message
+}
+
+var contracts = map[string]Contract{
+	"health": {
+		Topic:   "health",
+		Type:    "health.recommendation.requested",
+		Version: 1,
+		New:     func() proto.Message { return &health.GenerateHealthRecommendationsReq{} },
+	},
+	"werable": {
+		Topic:   "werable",
+		Type:    "wearable.data.added",
+		Version: 1,
+		New:     func() proto.Message { return &health.AddWearableDataReq{} },
+	},
+	"notification": {
+		Topic:   "notification",
+		Type:    "notification.requested",
+		Version: 1,
+		New:     func() proto.Message { return &user.CreateNotificationsReq{} },
+	},
+	// alerts carries alert.Alert as a Struct, as there is no generated
+	// message for it.
+	"alerts": {
+		Topic:   "alerts",
+		Type:    "vital.alert.raised",
+		Version: 1,
+		New:     func() proto.Message { return &structpb.Struct{} },
+	},
+}
+
+// ContractFor returns the contract of a topic.
+func ContractFor(topic string) (Contract, bool) {
+	c, ok := contracts[topic]
+	return c, ok
+}
+
+// Contracts lists every topic contract, sorted by topic.
+func Contracts() []Contract {
+	list := make([]Contract, 0, len(contracts))
+	for _, c := range contracts {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Topic < list[j].Topic })
+	return list
+}
+
+// Meta is what the caller knows about where an event comes from.
+type Meta struct {
+	// Key orders events on a partition, usually the user id.
+	Key       string
+	RequestID string
+	TraceID   string
+	// ReplyTo is set for events tracked as jobs.
+	ReplyTo string
+	// OccurredAt is when the event happened, if not now, e.g. for
+	// readings imported from another app.
+	OccurredAt time.Time
+	// Source is put in the source header, e.g. SourceImport.
+	Source string
+}
+
+// Encoder turns payloads into Kafka messages.
+type Encoder struct {
+	// Producer names the sender in every envelope.
+	Producer string
+	// Encoding is EncodingJSON or EncodingProtobuf.
+	Encoding string
+	// Now is the clock; nil means time.Now.
+	Now func() time.Time
+}
+
+func (e Encoder) Encode(topic string, payload proto.Message, meta Meta) (kafka.Message, error) {
+	contract, ok := ContractFor(topic)
+	if !ok {
+		return kafka.Message{}, fmt.Errorf("%w %q", ErrUnknownTopic, topic)
+	}
+	if payload.ProtoReflect().Descriptor() != contract.New().ProtoReflect().Descriptor() {
+		return kafka.Message{}, fmt.Errorf("%w: %s wants %s", ErrWrongPayload, topic, contract.New().ProtoReflect().Descriptor().FullName())
+	}
+
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+	occurredAt := now()
+	if !meta.OccurredAt.IsZero() {
+		occurredAt = meta.OccurredAt
+	}
+	env := Envelope{
+		ID:            newID(),
+		Type:          contract.Type,
+		SchemaVersion: contract.Version,
+		OccurredAt:    occurredAt.UTC(),
+		Producer:      e.Producer,
+		RequestID:     meta.RequestID,
+		TraceID:       meta.TraceID,
+	}
+
+	headers := map[string]string{
+		HeaderID:            env.ID,
+		HeaderType:          env.Type,
+		HeaderSchemaVersion: strconv.Itoa(env.SchemaVersion),
+		HeaderOccurredAt:    env.OccurredAt.Format(time.RFC3339Nano),
+		HeaderProducer:      env.Producer,
+	}
+	if env.RequestID != "" {
+		headers[HeaderRequestID] = env.RequestID
+	}
+	if env.TraceID != "" {
+		headers[HeaderTraceID] = env.TraceID
+	}
+	if meta.ReplyTo != "" {
+		headers[HeaderReplyTo] = meta.ReplyTo
+	}
+	if meta.Source != "" {
+		headers[HeaderSource] = meta.Source
+	}
+
+	var value []byte
+	var err error
+	switch e.Encoding {
+	case EncodingProtobuf:
+		headers[HeaderContentType] = ContentTypeProtobuf
+		value, err = proto.MarshalOptions{Deterministic: true}.Marshal(payload)
+	case EncodingJSON, "":
+		headers[HeaderContentType] = ContentTypeJSON
+		env.Payload, err = json.Marshal(payload)
+		if err == nil {
+			value, err = json.Marshal(env)
+		}
+	default:
+		return kafka.Message{}, fmt.Errorf("event: unknown encoding %q", e.Encoding)
+	}
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	var key []byte
+	if meta.Key != "" {
+		key = []byte(meta.Key)
+	}
+	return kafka.Message{Topic: topic, Key: key, Value: value, Headers: headers}, nil
+}
+
+// Decode reads a message written by Encode in either encoding and returns
+// its envelope and typed payload. The envelope's Payload is left empty.
+func Decode(msg kafka.Message) (Envelope, proto.Message, error) {
+	contract, ok := ContractFor(msg.Topic)
+	if !ok {
+		return Envelope{}, nil, fmt.Errorf("%w %q", ErrUnknownTopic, msg.Topic)
+	}
+	payload := contract.New()
+
+	if msg.Headers[HeaderContentType] == ContentTypeProtobuf {
+		env := Envelope{
+			ID:        msg.Headers[HeaderID],
+			Type:      msg.Headers[HeaderType],
+			Producer:  msg.Headers[HeaderProducer],
+			RequestID: msg.Headers[HeaderRequestID],
+			TraceID:   msg.Headers[HeaderTraceID],
+		}
+		env.SchemaVersion, _ = strconv.Atoi(msg.Headers[HeaderSchemaVersion])
+		env.OccurredAt, _ = time.Parse(time.RFC3339Nano, msg.Headers[HeaderOccurredAt])
+		if err := proto.Unmarshal(msg.Value, payload); err != nil {
+			return env, nil, err
+		}
+		return env, payload, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(msg.Value, &env); err != nil {
+		return env, nil, err
+	}
+	if err := json.Unmarshal(env.Payload, payload); err != nil {
+		return env, nil, err
+	}
+	env.Payload = nil
+	return env, payload, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	// RFC 4122 version 4
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,173 @@
+package event
+
+import (
+	"api-gateway/genproto/health"
+	"api-gateway/genproto/user"
+	kafka "api-gateway/kafka/producer"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+func alertPayload(t *testing.T) proto.Message {
+	t.Helper()
+	s, err := structpb.NewStruct(map[string]any{
+		"rule_id":    "7",
+		"patient_id": "u1",
+		"data_type":  "heart_rate",
+		"value":      "130",
+		"severity":   "critical",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestContracts pins the topic, type and version of every contract; a
+// change here breaks consumers and wants a version bump.
+func TestContracts(t *testing.T) {
+	want := []struct {
+		topic, typ string
+		version    int
+	}{
+		{"alerts", "vital.alert.raised", 1},
+		{"health", "health.recommendation.requested", 1},
+		{"notification", "notification.requested", 1},
+		{"werable", "wearable.data.added", 1},
+	}
+	got := Contracts()
+	if len(got) != len(want) {
+		t.Fatalf("got %d contracts, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Topic != w.topic || got[i].Type != w.typ || got[i].Version != w.version {
+			t.Errorf("contract %d = %s %s v%d, want %s %s v%d", i, got[i].Topic, got[i].Type, got[i].Version, w.topic, w.typ, w.version)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	payloads := map[string]proto.Message{
+		"health": &health.GenerateHealthRecommendationsReq{
+			UserId:             "u1",
+			RecommendationType: "diet",
+			Description:        "less salt",
+			Priority:           2,
+		},
+		"werable": &health.AddWearableDataReq{
+			UserId:     "u1",
+			DeviceType: "watch",
+			DataType:   "heart_rate",
+			DataValue:  "72",
+		},
+		"notification": &user.CreateNotificationsReq{UserId: "u1", Message: "hello"},
+		"alerts":       alertPayload(t),
+	}
+
+	for _, encoding := range []string{EncodingJSON, EncodingProtobuf} {
+		for _, contract := range Contracts() {
+			t.Run(encoding+"/"+contract.Topic, func(t *testing.T) {
+				payload := payloads[contract.Topic]
+				enc := Encoder{Producer: "api-gateway", Encoding: encoding, Now: func() time.Time { return testNow }}
+				msg, err := enc.Encode(contract.Topic, payload, Meta{Key: "u1", RequestID: "req-1", TraceID: "trace-1", ReplyTo: "replies"})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if msg.Topic != contract.Topic || string(msg.Key) != "u1" {
+					t.Errorf("topic %q key %q, want %q u1", msg.Topic, msg.Key, contract.Topic)
+				}
+				contentType := ContentTypeJSON
+				if encoding == EncodingProtobuf {
+					contentType = ContentTypeProtobuf
+				}
+				headers := map[string]string{
+					HeaderType:          contract.Type,
+					HeaderSchemaVersion: strconv.Itoa(contract.Version),
+					HeaderOccurredAt:    testNow.Format(time.RFC3339Nano),
+					HeaderProducer:      "api-gateway",
+					HeaderRequestID:     "req-1",
+					HeaderTraceID:       "trace-1",
+					HeaderReplyTo:       "replies",
+					HeaderContentType:   contentType,
+				}
+				for name, want := range headers {
+					if got := msg.Headers[name]; got != want {
+						t.Errorf("header %s = %q, want %q", name, got, want)
+					}
+				}
+				if len(msg.Headers[HeaderID]) != 36 {
+					t.Errorf("header %s = %q, want a UUID", HeaderID, msg.Headers[HeaderID])
+				}
+
+				if encoding == EncodingJSON {
+					var env map[string]json.RawMessage
+					if err := json.Unmarshal(msg.Value, &env); err != nil {
+						t.Fatalf("value is not a JSON envelope: %v", err)
+					}
+					for _, field := range []string{"id", "type", "schema_version", "occurred_at", "producer", "payload"} {
+						if _, ok := env[field]; !ok {
+							t.Errorf("envelope has no %q", field)
+						}
+					}
+				}
+
+				env, decoded, err := Decode(msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if env.ID != msg.Headers[HeaderID] {
+					t.Errorf("envelope id %q, want %q", env.ID, msg.Headers[HeaderID])
+				}
+				if env.Type != contract.Type || env.SchemaVersion != contract.Version {
+					t.Errorf("envelope is %s v%d, want %s v%d", env.Type, env.SchemaVersion, contract.Type, contract.Version)
+				}
+				if !env.OccurredAt.Equal(testNow) || env.Producer != "api-gateway" || env.RequestID != "req-1" || env.TraceID != "trace-1" {
+					t.Errorf("envelope %+v does not match the meta", env)
+				}
+				if !proto.Equal(decoded, payload) {
+					t.Errorf("payload %v, want %v", decoded, payload)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeOccurredAt(t *testing.T) {
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("", 2*3600))
+	msg, err := Encoder{Now: func() time.Time { return testNow }}.Encode("werable", &health.AddWearableDataReq{}, Meta{OccurredAt: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Headers[HeaderOccurredAt]; got != "2024-05-01T06:00:00Z" {
+		t.Errorf("occurred-at %q, want the reading's time in UTC", got)
+	}
+	if msg.Key != nil {
+		t.Errorf("key %q, want none", msg.Key)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	enc := Encoder{Encoding: EncodingJSON}
+	if _, err := enc.Encode("unknown", &health.AddWearableDataReq{}, Meta{}); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("unknown topic: %v, want ErrUnknownTopic", err)
+	}
+	if _, err := enc.Encode("werable", &user.CreateNotificationsReq{}, Meta{}); !errors.Is(err, ErrWrongPayload) {
+		t.Errorf("wrong payload: %v, want ErrWrongPayload", err)
+	}
+	enc.Encoding = "xml"
+	if _, err := enc.Encode("werable", &health.AddWearableDataReq{}, Meta{}); err == nil {
+		t.Error("unknown encoding: no error")
+	}
+	if _, _, err := Decode(kafka.Message{Topic: "unknown"}); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("decode unknown topic: %v, want ErrUnknownTopic", err)
+	}
+}
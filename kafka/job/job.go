@@ -0,0 +1,161 @@
+// Package job tracks work the gateway handed to backend services over
+// Kafka, so clients can poll for the outcome of a 202 Accepted request.
+package job
+
+import (
+	"api-gateway/kafka/consumer"
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusExpired is a job whose reply did not come before the deadline.
+	StatusExpired = "expired"
+)
+
+type Job struct {
+	// ID is the event id of the event that started the job.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// UserID is the caller who started the job; only they, and roles with
+	// cross-user access, may see it.
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Store interface {
+	Create(ctx context.Context, job Job) error
+	// Get reports false when there is no job with the id.
+	Get(ctx context.Context, id string) (Job, bool, error)
+	// Complete settles a pending job. It reports false when no pending job
+	// has the id, so a repeated reply changes nothing.
+	Complete(ctx context.Context, id, status, reason string) (bool, error)
+	// Expire marks the jobs still pending that were created before the
+	// given time as expired, so a reply that will never come stops keeping
+	// them pending. A late reply is then ignored.
+	Expire(ctx context.Context, before time.Time) (int64, error)
+	// Purge drops the jobs that finished before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewMemoryStore keeps jobs in process memory. It is meant for tests and
+// local runs; jobs are lost on restart.
+func NewMemoryStore() Store {
+	return &memoryStore{jobs: make(map[string]Job)}
+}
+
+func (m *memoryStore) Create(ctx context.Context, job Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	job.Status = StatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	m.jobs[job.ID] = job
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, id string) (Job, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	return job, ok, nil
+}
+
+func (m *memoryStore) Complete(ctx context.Context, id, status, reason string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.Status != StatusPending {
+		return false, nil
+	}
+	job.Status = status
+	job.Error = reason
+	job.UpdatedAt = time.Now()
+	m.jobs[id] = job
+	return true, nil
+}
+
+func (m *memoryStore) Expire(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	now := time.Now()
+	for id, job := range m.jobs {
+		if job.Status == StatusPending && job.CreatedAt.Before(before) {
+			job.Status = StatusExpired
+			job.Error = expiredReason
+			job.UpdatedAt = now
+			m.jobs[id] = job
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, job := range m.jobs {
+		if job.Status != StatusPending && job.UpdatedAt.Before(before) {
+			delete(m.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Reply is what a backend service sends to the reply topic named in the
+// event's reply-to header once it is done with the event.
+type Reply struct {
+	// JobID is the event-id header of the event that was handled.
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReplyHandler settles jobs from the messages of the reply topic. Malformed
+// replies and replies to unknown jobs are logged and skipped.
+func ReplyHandler(store Store, logger *slog.Logger) consumer.Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		var reply Reply
+		if err := json.Unmarshal(msg.Value, &reply); err != nil {
+			logger.Warn("Skipping malformed job reply", "error", err.Error())
+			return nil
+		}
+		if reply.JobID == "" || (reply.Status != StatusSucceeded && reply.Status != StatusFailed) {
+			logger.Warn("Skipping invalid job reply", "job_id", reply.JobID, "status", reply.Status)
+			return nil
+		}
+
+		settled, err := store.Complete(ctx, reply.JobID, reply.Status, reply.Error)
+		if err != nil {
+			return err
+		}
+		if !settled {
+			logger.Info("Job reply matched no pending job", "job_id", reply.JobID, "status", reply.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package job
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sweeper := NewSweeper(store, logger, SweeperConfig{Deadline: time.Hour, Retention: 24 * time.Hour})
+
+	for _, id := range []string{"waiting", "answered", "lost"} {
+		if err := store.Create(ctx, Job{ID: id, Type: "health.recommendation.requested", UserID: "u1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Complete(ctx, "answered", StatusSucceeded, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(id string) string {
+		t.Helper()
+		j, found, err := store.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			return "gone"
+		}
+		return j.Status
+	}
+
+	// within the deadline nothing changes
+	if err := sweeper.SweepOnce(ctx, time.Now().Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("waiting"); got != StatusPending {
+		t.Errorf("job within the deadline is %s, want pending", got)
+	}
+
+	if err := sweeper.SweepOnce(ctx, time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"waiting": StatusExpired, "lost": StatusExpired, "answered": StatusSucceeded} {
+		if got := status(id); got != want {
+			t.Errorf("after the deadline %s is %s, want %s", id, got, want)
+		}
+	}
+	if j, _, _ := store.Get(ctx, "lost"); j.Error == "" {
+		t.Error("an expired job says nothing about why")
+	}
+
+	// a reply that comes after all leaves the expired job alone
+	reply, _ := json.Marshal(Reply{JobID: "lost", Status: StatusSucceeded})
+	if err := ReplyHandler(store, logger)(ctx, kafka.Message{Value: reply}); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("lost"); got != StatusExpired {
+		t.Errorf("late reply turned the job %s, want it to stay expired", got)
+	}
+
+	if err := sweeper.SweepOnce(ctx, time.Now().Add(25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"waiting", "answered", "lost"} {
+		if got := status(id); got != "gone" {
+			t.Errorf("past the retention %s is %s, want it purged", id, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates the jobs table when it is missing. Replicas share
+// it, so any of them can answer a status request.
+func NewPostgresStore(db *sql.DB) (Store, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS jobs (
+			id         TEXT PRIMARY KEY,
+			type       TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			status     TEXT NOT NULL DEFAULT 'pending',
+			error      TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) Create(ctx context.Context, job Job) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, type, user_id) VALUES ($1, $2, $3)`,
+		job.ID, job.Type, job.UserID)
+	return err
+}
+
+func (s *postgresStore) Get(ctx context.Context, id string) (Job, bool, error) {
+	var job Job
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, type, user_id, status, error, created_at, updated_at FROM jobs WHERE id = $1`, id).
+		Scan(&job.ID, &job.Type, &job.UserID, &job.Status, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, err
+	}
+	return job, true, nil
+}
+
+func (s *postgresStore) Complete(ctx context.Context, id, status, reason string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1 AND status = 'pending'`,
+		id, status, reason)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *postgresStore) Expire(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = 'expired', error = $2, updated_at = NOW() WHERE status = 'pending' AND created_at < $1`,
+		before, expiredReason)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *postgresStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM jobs WHERE status <> 'pending' AND updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,78 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// expiredReason is the error of an expired job.
+const expiredReason = "no reply before the deadline"
+
+type SweeperConfig struct {
+	// Deadline is how long a job may stay pending before it expires.
+	Deadline time.Duration
+	// Retention is how long finished jobs are kept for clients to poll.
+	Retention time.Duration
+	// Interval is how often the store is swept.
+	Interval time.Duration
+}
+
+// Sweeper expires the jobs whose reply is overdue and purges the ones that
+// finished long ago, so neither pile up in the store.
+type Sweeper struct {
+	store  Store
+	logger *slog.Logger
+	cfg    SweeperConfig
+}
+
+func NewSweeper(store Store, logger *slog.Logger, cfg SweeperConfig) *Sweeper {
+	if cfg.Deadline <= 0 {
+		cfg.Deadline = time.Hour
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	return &Sweeper{store: store, logger: logger, cfg: cfg}
+}
+
+// Run sweeps until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SweepOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Error("Error sweeping jobs", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepOnce expires and purges the jobs as of now.
+func (s *Sweeper) SweepOnce(ctx context.Context, now time.Time) error {
+	expired, err := s.store.Expire(ctx, now.Add(-s.cfg.Deadline))
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		s.logger.Warn("Expired jobs without a reply", "count", expired)
+	}
+
+	purged, err := s.store.Purge(ctx, now.Add(-s.cfg.Retention))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		s.logger.Info("Purged finished jobs", "count", purged)
+	}
+	return nil
+}
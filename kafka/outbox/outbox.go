@@ -0,0 +1,262 @@
+// Package outbox stores gateway events durably before they go to Kafka, so
+// a broker outage delays them instead of losing them.
+package outbox
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	// StatusDead marks events that ran out of attempts. They stay until an
+	// operator replays them.
+	StatusDead = "dead"
+)
+
+type Event struct {
+	ID            int64             `json:"id"`
+	Topic         string            `json:"topic"`
+	Key           string            `json:"key,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Payload       []byte            `json:"-"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	LastError     string            `json:"last_error,omitempty"`
+	NextAttemptAt time.Time         `json:"next_attempt_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	SentAt        *time.Time        `json:"sent_at,omitempty"`
+}
+
+// MarshalJSON shows a JSON payload as is, so operators can read it when
+// inspecting stuck events. Other payloads stay base64.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	var payload interface{} = e.Payload
+	if json.Valid(e.Payload) {
+		payload = json.RawMessage(e.Payload)
+	}
+	return json.Marshal(struct {
+		plain
+		Payload interface{} `json:"payload"`
+	}{plain(e), payload})
+}
+
+// Store is the outbox table. Handlers only Enqueue; the relay claims and
+// settles events; the admin endpoints list and replay them.
+type Store interface {
+	Enqueue(ctx context.Context, msg kafka.Message) (int64, error)
+	// EnqueueBatch stores all messages or, on error, none of them.
+	EnqueueBatch(ctx context.Context, msgs []kafka.Message) ([]int64, error)
+	// Claim returns up to limit due events and hides them from other
+	// claimers until lease passes, so replicas don't publish them twice.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed records a failed attempt. The event is retried at next,
+	// or becomes dead when dead is set.
+	MarkFailed(ctx context.Context, id int64, reason string, next time.Time, dead bool) error
+	List(ctx context.Context, status string, limit, offset int) ([]Event, error)
+	// Replay makes an event due again with a fresh attempt budget. It
+	// reports false when there is no such event.
+	Replay(ctx context.Context, id int64) (bool, error)
+	// ReplayDead does Replay for every dead event.
+	ReplayDead(ctx context.Context) (int64, error)
+	// PurgeSent drops events delivered before the given time.
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	nextID int64
+	events map[int64]*Event
+}
+
+// NewMemoryStore keeps the outbox in process memory. It is meant for tests
+// and local runs; events are lost on restart.
+func NewMemoryStore() Store {
+	return &memoryStore{events: make(map[int64]*Event)}
+}
+
+func (m *memoryStore) Enqueue(ctx context.Context, msg kafka.Message) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enqueue(msg), nil
+}
+
+func (m *memoryStore) EnqueueBatch(ctx context.Context, msgs []kafka.Message) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int64, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = m.enqueue(msg)
+	}
+	return ids, nil
+}
+
+func (m *memoryStore) enqueue(msg kafka.Message) int64 {
+	m.nextID++
+	now := time.Now()
+	m.events[m.nextID] = &Event{
+		ID:            m.nextID,
+		Topic:         msg.Topic,
+		Key:           string(msg.Key),
+		Headers:       copyHeaders(msg.Headers),
+		Payload:       append([]byte(nil), msg.Value...),
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	return m.nextID
+}
+
+func (m *memoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var claimed []Event
+	for _, e := range m.sorted() {
+		if len(claimed) >= limit {
+			break
+		}
+		if e.Status != StatusPending || e.NextAttemptAt.After(now) {
+			continue
+		}
+		e.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, *e)
+	}
+	return claimed, nil
+}
+
+func (m *memoryStore) MarkSent(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.events[id]; ok {
+		now := time.Now()
+		e.Status = StatusSent
+		e.Attempts++
+		e.SentAt = &now
+	}
+	return nil
+}
+
+func (m *memoryStore) MarkFailed(ctx context.Context, id int64, reason string, next time.Time, dead bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.events[id]; ok {
+		e.Attempts++
+		e.LastError = reason
+		e.NextAttemptAt = next
+		if dead {
+			e.Status = StatusDead
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) List(ctx context.Context, status string, limit, offset int) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []Event{}
+	sorted := m.sorted()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if status != "" && sorted[i].Status != status {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(events) >= limit {
+			break
+		}
+		events = append(events, *sorted[i])
+	}
+	return events, nil
+}
+
+func (m *memoryStore) Replay(ctx context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[id]
+	if !ok {
+		return false, nil
+	}
+	replay(e)
+	return true, nil
+}
+
+func (m *memoryStore) ReplayDead(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for _, e := range m.events {
+		if e.Status == StatusDead {
+			replay(e)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryStore) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, e := range m.events {
+		if e.Status == StatusSent && e.SentAt != nil && e.SentAt.Before(before) {
+			delete(m.events, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryStore) sorted() []*Event {
+	events := make([]*Event, 0, len(m.events))
+	for _, e := range m.events {
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
+
+// Message is the Kafka record the event is published as.
+func (e Event) Message() kafka.Message {
+	var key []byte
+	if e.Key != "" {
+		key = []byte(e.Key)
+	}
+	return kafka.Message{Topic: e.Topic, Key: key, Value: e.Payload, Headers: e.Headers}
+}
+
+func replay(e *Event) {
+	e.Status = StatusPending
+	e.Attempts = 0
+	e.LastError = ""
+	e.NextAttemptAt = time.Now()
+	e.SentAt = nil
+}
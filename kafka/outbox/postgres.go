@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates the outbox_events table when it is missing.
+func NewPostgresStore(db *sql.DB) (Store, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox_events (
+			id              BIGSERIAL PRIMARY KEY,
+			topic           TEXT NOT NULL,
+			payload         BYTEA NOT NULL,
+			status          TEXT NOT NULL DEFAULT 'pending',
+			attempts        INT NOT NULL DEFAULT 0,
+			last_error      TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			sent_at         TIMESTAMPTZ
+		);
+		ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS key TEXT NOT NULL DEFAULT '';
+		ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '{}';
+		CREATE INDEX IF NOT EXISTS outbox_events_due ON outbox_events (next_attempt_at) WHERE status = 'pending';`)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{db: db}, nil
+}
+
+const eventColumns = `id, topic, key, headers, payload, status, attempts, last_error, next_attempt_at, created_at, sent_at`
+
+func (s *postgresStore) Enqueue(ctx context.Context, msg kafka.Message) (int64, error) {
+	return insertEvent(ctx, s.db, msg)
+}
+
+func (s *postgresStore) EnqueueBatch(ctx context.Context, msgs []kafka.Message) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, len(msgs))
+	for i, msg := range msgs {
+		if ids[i], err = insertEvent(ctx, tx, msg); err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit()
+}
+
+// queryer is what insertEvent needs from a *sql.DB or *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertEvent(ctx context.Context, db queryer, msg kafka.Message) (int64, error) {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return 0, err
+	}
+	if msg.Headers == nil {
+		headers = []byte("{}")
+	}
+
+	var id int64
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO outbox_events (topic, key, headers, payload) VALUES ($1, $2, $3, $4) RETURNING id`,
+		msg.Topic, string(msg.Key), headers, msg.Value).Scan(&id)
+	return id, err
+}
+
+func (s *postgresStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE outbox_events SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+eventColumns,
+		limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING doesn't keep the subquery order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (s *postgresStore) MarkSent(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE outbox_events SET status = 'sent', attempts = attempts + 1, sent_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) MarkFailed(ctx context.Context, id int64, reason string, next time.Time, dead bool) error {
+	status := StatusPending
+	if dead {
+		status = StatusDead
+	}
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE outbox_events SET status = $2, attempts = attempts + 1, last_error = $3, next_attempt_at = $4 WHERE id = $1`,
+		id, status, reason, next)
+	return err
+}
+
+func (s *postgresStore) List(ctx context.Context, status string, limit, offset int) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+eventColumns+` FROM outbox_events
+		WHERE $1 = '' OR status = $1
+		ORDER BY id DESC LIMIT $2 OFFSET $3`,
+		status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (s *postgresStore) Replay(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE outbox_events SET status = 'pending', attempts = 0, last_error = '', next_attempt_at = NOW(), sent_at = NULL
+		WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *postgresStore) ReplayDead(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE outbox_events SET status = 'pending', attempts = 0, last_error = '', next_attempt_at = NOW()
+		WHERE status = 'dead'`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *postgresStore) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM outbox_events WHERE status = 'sent' AND sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		var headers []byte
+		var sentAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Topic, &e.Key, &headers, &e.Payload, &e.Status, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.CreatedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(headers, &e.Headers); err != nil {
+			return nil, err
+		}
+		if len(e.Headers) == 0 {
+			e.Headers = nil
+		}
+		if sentAt.Valid {
+			e.SentAt = &sentAt.Time
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	kafka "api-gateway/kafka/producer"
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts is how often an event is tried before it is marked dead.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Lease is how long a claimed event stays hidden from other replicas.
+	// It must be longer than a publish can take.
+	Lease time.Duration
+	// Retention is how long delivered events are kept for inspection.
+	Retention time.Duration
+}
+
+// Relay publishes due outbox events to Kafka, retrying failures with
+// exponential backoff.
+type Relay struct {
+	store    Store
+	producer kafka.ProducerIkafka
+	logger   *slog.Logger
+	cfg      RelayConfig
+}
+
+func NewRelay(store Store, producer kafka.ProducerIkafka, logger *slog.Logger, cfg RelayConfig) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &Relay{store: store, producer: producer, logger: logger, cfg: cfg}
+}
+
+// Run relays events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+	for {
+		// drain whatever is due before waiting for the next tick
+		for {
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("Error relaying outbox events", "error", err.Error())
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		if r.cfg.Retention > 0 && time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			if n, err := r.store.PurgeSent(ctx, time.Now().Add(-r.cfg.Retention)); err != nil {
+				r.logger.Error("Error purging outbox events", "error", err.Error())
+			} else if n > 0 {
+				r.logger.Info("Purged delivered outbox events", "count", n)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush relays the due events until none are left, so what the last
+// requests queued goes out before the gateway stops. Events that fail wait
+// for their retry, by another replica or after a restart.
+func (r *Relay) Flush(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			return err
+		}
+		if n < r.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due events in a single write and returns
+// how many it claimed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.Claim(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	msgs := make([]kafka.Message, len(events))
+	for i, e := range events {
+		msgs[i] = e.Message()
+	}
+	err = r.producer.Publish(ctx, msgs...)
+	if ctx.Err() != nil {
+		// the leases run out and another pass picks these up
+		return len(events), ctx.Err()
+	}
+
+	var publishErrs kafka.PublishErrors
+	if !errors.As(err, &publishErrs) || len(publishErrs) != len(events) {
+		// all or nothing: every event shares the outcome
+		publishErrs = make(kafka.PublishErrors, len(events))
+		for i := range publishErrs {
+			publishErrs[i] = err
+		}
+	}
+
+	for i, e := range events {
+		if err := r.settle(ctx, e, publishErrs[i]); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// settle records the outcome of publishing e.
+func (r *Relay) settle(ctx context.Context, e Event, publishErr error) error {
+	if publishErr == nil {
+		// delivery is at least once: if this fails, the event is published
+		// again when its lease runs out
+		return r.store.MarkSent(ctx, e.ID)
+	}
+
+	attempt := e.Attempts + 1
+	dead := attempt >= r.cfg.MaxAttempts
+	next := time.Now().Add(r.backoff(attempt))
+	if dead {
+		r.logger.Error("Outbox event is dead", "id", e.ID, "topic", e.Topic, "attempts", attempt, "error", publishErr.Error())
+	} else {
+		r.logger.Warn("Outbox event publish failed", "id", e.ID, "topic", e.Topic, "attempt", attempt, "retry_at", next, "error", publishErr.Error())
+	}
+	return r.store.MarkFailed(ctx, e.ID, publishErr.Error(), next, dead)
+}
+
+// backoff doubles the wait with every attempt, up to MaxBackoff, with up to
+// 20% jitter so replicas don't retry in lockstep.
+func (r *Relay) backoff(attempt int) time.Duration {
+	wait := r.cfg.BaseBackoff
+	for i := 1; i < attempt && wait < r.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.cfg.MaxBackoff {
+		wait = r.cfg.MaxBackoff
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+}
@@ -2,44 +2,169 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// ProducerIkafka is what handlers publish through. Tests can pass a fake.
 type ProducerIkafka interface {
-	Producermessage(topic string, msg []byte) error
-	Close()
+	Producermessage(ctx context.Context, topic string, msg []byte) error
+	// Publish writes messages with their keys and headers in one batch.
+	Publish(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// Message is one Kafka record. Messages with the same key land on the same
+// partition and keep their order.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+	// Partition and Offset are only set on consumed messages.
+	Partition int
+	Offset    int64
+}
+
+// Config tunes the producer. Zero values fall back to the defaults below.
+type Config struct {
+	Brokers []string
+	// RequiredAcks is "none", "one" or "all".
+	RequiredAcks string
+	BatchSize    int
+	BatchTimeout time.Duration
+	BatchBytes   int64
+	// Compression is "none", "gzip", "snappy", "lz4" or "zstd".
+	Compression string
+	MaxAttempts int
+	// WriteTimeout bounds one write to the broker.
+	WriteTimeout time.Duration
+	// PublishTimeout bounds a whole publish, retries included, on top of
+	// the caller's context.
+	PublishTimeout time.Duration
 }
 
 type KafkaProducer struct {
-	writer *kafka.Writer
+	writer         *kafka.Writer
+	brokers        []string
+	publishTimeout time.Duration
 }
 
-func NewKafkaProducerInit(brokers []string) (*KafkaProducer, error) {
+// NewKafkaProducer builds the producer the gateway shares between requests.
+// Writes are synchronous, so a nil error means the broker acknowledged the
+// message with the configured acks.
+func NewKafkaProducer(cfg Config) (*KafkaProducer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+
+	acks := kafka.RequireAll
+	if cfg.RequiredAcks != "" {
+		if err := acks.UnmarshalText([]byte(cfg.RequiredAcks)); err != nil {
+			return nil, fmt.Errorf("kafka: %w", err)
+		}
+	}
+
+	var compression kafka.Compression
+	if cfg.Compression != "" {
+		if err := compression.UnmarshalText([]byte(cfg.Compression)); err != nil {
+			return nil, fmt.Errorf("kafka: %w", err)
+		}
+	}
+
+	batchTimeout := cfg.BatchTimeout
+	if batchTimeout <= 0 {
+		// kafka-go waits a full second for a batch to fill by default,
+		// which every synchronous publish would pay for
+		batchTimeout = 10 * time.Millisecond
+	}
+
 	writer := &kafka.Writer{
-		Addr:                   kafka.TCP(brokers...),
+		Addr:                   kafka.TCP(cfg.Brokers...),
 		AllowAutoTopicCreation: true,
+		RequiredAcks:           acks,
+		BatchSize:              cfg.BatchSize,
+		BatchTimeout:           batchTimeout,
+		BatchBytes:             cfg.BatchBytes,
+		Compression:            compression,
+		MaxAttempts:            cfg.MaxAttempts,
+		WriteTimeout:           cfg.WriteTimeout,
 	}
 
-	return &KafkaProducer{writer: writer}, nil
+	return &KafkaProducer{writer: writer, brokers: cfg.Brokers, publishTimeout: cfg.PublishTimeout}, nil
+}
 
+// Producermessage publishes msg and waits for the broker. It gives up when
+// ctx is done, so a slow broker can't hold a request forever.
+func (k *KafkaProducer) Producermessage(ctx context.Context, topic string, msg []byte) error {
+	return k.Publish(ctx, Message{Topic: topic, Value: msg})
 }
 
-func (k *KafkaProducer) Producermessage(topic string, msg []byte) error {
-	return k.writer.WriteMessages(context.Background(), kafka.Message{
-		Topic: topic,
-		Value: msg,
-	})
+func (k *KafkaProducer) Publish(ctx context.Context, msgs ...Message) error {
+	if k.publishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.publishTimeout)
+		defer cancel()
+	}
+
+	records := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		record := kafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value}
+		keys := make([]string, 0, len(msg.Headers))
+		for key := range msg.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			record.Headers = append(record.Headers, kafka.Header{Key: key, Value: []byte(msg.Headers[key])})
+		}
+		records = append(records, record)
+	}
+	err := k.writer.WriteMessages(ctx, records...)
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) {
+		return PublishErrors(writeErrs)
+	}
+	return err
 }
 
-func (k *KafkaProducer) WriteToNotification(topic string, msg []byte) error {
-	return k.writer.WriteMessages(context.Background(), kafka.Message{
-		Topic: topic,
-		Value: msg,
-	})
+// PublishErrors is returned by Publish when only some messages failed. It
+// lines up with the messages; delivered ones have a nil error.
+type PublishErrors []error
+
+func (e PublishErrors) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("kafka: %d of %d messages failed: %v", failed, len(e), first)
 }
 
+// Ping dials the brokers until one answers. It fails only when none does,
+// as the writer gets by with any of them.
+func (k *KafkaProducer) Ping(ctx context.Context) error {
+	var errs []error
+	for _, broker := range k.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("kafka: no broker reachable: %w", errors.Join(errs...))
+}
 
-func (k *KafkaProducer) Close() {
-	k.writer.Close()
+// Close flushes pending messages and releases the connections.
+func (k *KafkaProducer) Close() error {
+	return k.writer.Close()
 }
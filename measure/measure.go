@@ -0,0 +1,323 @@
+// Package measure is the registry of the data types wearable and lifestyle
+// readings may carry. It parses values as clients send them, e.g. "154 lb"
+// or "120/80 mmHg", checks them and converts them into the data type's
+// canonical unit, so stored readings can be compared.
+package measure
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Data types.
+const (
+	HeartRate        = "heart_rate"
+	Steps            = "steps"
+	SleepMinutes     = "sleep_minutes"
+	Weight           = "weight"
+	BloodPressure    = "blood_pressure"
+	Glucose          = "glucose"
+	OxygenSaturation = "oxygen_saturation"
+	BodyTemperature  = "body_temperature"
+	Distance         = "distance"
+	Calories         = "calories"
+	WaterIntake      = "water_intake"
+	ExerciseMinutes  = "exercise_minutes"
+)
+
+// Kinds of values.
+const (
+	// KindNumber is a decimal number, e.g. "69.85".
+	KindNumber = "number"
+	// KindInteger is a whole number, e.g. "8000".
+	KindInteger = "integer"
+	// KindPressure is a "systolic/diastolic" pair, e.g. "120/80".
+	KindPressure = "pressure"
+)
+
+var ErrUnknownType = errors.New("measure: unknown data type")
+
+// ValueError says why a value doesn't fit its data type.
+type ValueError struct {
+	DataType string
+	Value    string
+	Reason   string
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("measure: %s value %q: %s", e.DataType, e.Value, e.Reason)
+}
+
+// Unit is a unit a value may be given in.
+type Unit struct {
+	Symbol  string   `json:"symbol"`
+	Aliases []string `json:"aliases,omitempty"`
+	// toCanonical converts a value in this unit into the canonical one.
+	toCanonical func(float64) float64
+}
+
+// Type describes a data type: the shape of its values, the unit they are
+// stored in and the range a reading may plausibly fall in.
+type Type struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Kind        string `json:"kind"`
+	// Unit is the canonical unit values are stored in.
+	Unit string `json:"unit"`
+	// Units are the units accepted on input, the canonical one first. A
+	// value without a unit is taken to be in the canonical one.
+	Units []Unit `json:"units"`
+	// Min and Max bound a value in the canonical unit. For blood pressure
+	// they bound the systolic value; the diastolic one must be lower.
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Example string  `json:"example"`
+}
+
+func same(v float64) float64 { return v }
+
+func scale(factor float64) func(float64) float64 {
+	return func(v float64) float64 { return v * factor }
+}
+
+var types = map[string]Type{
+	HeartRate: {
+		Description: "Heart rate", Kind: KindNumber, Unit: "bpm", Min: 20, Max: 300, Example: "72 bpm",
+		Units: []Unit{
+			{Symbol: "bpm", Aliases: []string{"beats/min", "count/min", "/min"}, toCanonical: same},
+			{Symbol: "bps", Aliases: []string{"beats/s"}, toCanonical: scale(60)},
+		},
+	},
+	Steps: {
+		Description: "Step count", Kind: KindInteger, Unit: "count", Min: 0, Max: 200000, Example: "8000",
+		Units: []Unit{{Symbol: "count", Aliases: []string{"steps"}, toCanonical: same}},
+	},
+	SleepMinutes: {
+		Description: "Time asleep", Kind: KindNumber, Unit: "min", Min: 0, Max: 1440, Example: "7.5 h",
+		Units: []Unit{
+			{Symbol: "min", Aliases: []string{"minutes"}, toCanonical: same},
+			{Symbol: "h", Aliases: []string{"hours", "hr"}, toCanonical: scale(60)},
+		},
+	},
+	Weight: {
+		Description: "Body weight", Kind: KindNumber, Unit: "kg", Min: 0.5, Max: 700, Example: "154 lb",
+		Units: []Unit{
+			{Symbol: "kg", toCanonical: same},
+			{Symbol: "g", toCanonical: scale(0.001)},
+			{Symbol: "lb", Aliases: []string{"lbs"}, toCanonical: scale(0.45359237)},
+			{Symbol: "st", toCanonical: scale(6.35029318)},
+		},
+	},
+	BloodPressure: {
+		Description: "Blood pressure as systolic/diastolic", Kind: KindPressure, Unit: "mmHg", Min: 40, Max: 300, Example: "120/80 mmHg",
+		Units: []Unit{
+			{Symbol: "mmHg", toCanonical: same},
+			{Symbol: "kPa", toCanonical: scale(7.50061683)},
+		},
+	},
+	Glucose: {
+		Description: "Blood glucose", Kind: KindNumber, Unit: "mmol/L", Min: 0.5, Max: 55, Example: "99 mg/dL",
+		Units: []Unit{
+			{Symbol: "mmol/L", toCanonical: same},
+			{Symbol: "mg/dL", toCanonical: scale(1 / 18.0156)},
+		},
+	},
+	OxygenSaturation: {
+		Description: "Blood oxygen saturation", Kind: KindNumber, Unit: "%", Min: 50, Max: 100, Example: "97 %",
+		Units: []Unit{{Symbol: "%", Aliases: []string{"percent"}, toCanonical: same}},
+	},
+	BodyTemperature: {
+		Description: "Body temperature", Kind: KindNumber, Unit: "°C", Min: 25, Max: 45, Example: "98.6 °F",
+		Units: []Unit{
+			{Symbol: "°C", Aliases: []string{"C", "degC"}, toCanonical: same},
+			{Symbol: "°F", Aliases: []string{"F", "degF"}, toCanonical: func(v float64) float64 { return (v - 32) * 5 / 9 }},
+		},
+	},
+	Distance: {
+		Description: "Distance covered", Kind: KindNumber, Unit: "m", Min: 0, Max: 500000, Example: "5.2 km",
+		Units: []Unit{
+			{Symbol: "m", toCanonical: same},
+			{Symbol: "km", toCanonical: scale(1000)},
+			{Symbol: "mi", toCanonical: scale(1609.344)},
+			{Symbol: "ft", toCanonical: scale(0.3048)},
+		},
+	},
+	Calories: {
+		Description: "Energy burned", Kind: KindNumber, Unit: "kcal", Min: 0, Max: 50000, Example: "350 kcal",
+		Units: []Unit{
+			{Symbol: "kcal", Aliases: []string{"Cal"}, toCanonical: same},
+			{Symbol: "kJ", toCanonical: scale(1 / 4.184)},
+		},
+	},
+	WaterIntake: {
+		Description: "Water drunk", Kind: KindNumber, Unit: "ml", Min: 0, Max: 20000, Example: "1.5 L",
+		Units: []Unit{
+			{Symbol: "ml", Aliases: []string{"mL"}, toCanonical: same},
+			{Symbol: "L", Aliases: []string{"l"}, toCanonical: scale(1000)},
+			{Symbol: "fl oz", Aliases: []string{"oz"}, toCanonical: scale(29.5735)},
+		},
+	},
+	ExerciseMinutes: {
+		Description: "Time spent exercising", Kind: KindNumber, Unit: "min", Min: 0, Max: 1440, Example: "45 min",
+		Units: []Unit{
+			{Symbol: "min", Aliases: []string{"minutes"}, toCanonical: same},
+			{Symbol: "h", Aliases: []string{"hours", "hr"}, toCanonical: scale(60)},
+		},
+	},
+}
+
+func init() {
+	for name, t := range types {
+		t.Name = name
+		types[name] = t
+	}
+}
+
+// Lookup returns the data type called name.
+func Lookup(name string) (Type, bool) {
+	t, ok := types[name]
+	return t, ok
+}
+
+// Types lists every data type, sorted by name.
+func Types() []Type {
+	list := make([]Type, 0, len(types))
+	for _, t := range types {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Names lists the names of every data type, sorted.
+func Names() []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	numberValue   = regexp.MustCompile(`^([+-]?(?:\d+\.?\d*|\.\d+))\s*(.*)$`)
+	pressureValue = regexp.MustCompile(`^(\d+\.?\d*)\s*/\s*(\d+\.?\d*)\s*(.*)$`)
+)
+
+// Normalize parses value, which may name its unit, e.g. "154 lb", and
+// returns it in the canonical unit of dataType without one, e.g. "69.85".
+// It fails with ErrUnknownType or a *ValueError.
+func Normalize(dataType, value string) (string, error) {
+	t, ok := types[dataType]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownType, dataType)
+	}
+	value = strings.TrimSpace(value)
+
+	if t.Kind == KindPressure {
+		m := pressureValue.FindStringSubmatch(value)
+		if m == nil {
+			return "", t.invalid(value, "must be systolic/diastolic, e.g. "+t.Example)
+		}
+		unit, err := t.unit(m[3])
+		if err != nil {
+			return "", t.invalid(value, err.Error())
+		}
+		systolic, _ := strconv.ParseFloat(m[1], 64)
+		diastolic, _ := strconv.ParseFloat(m[2], 64)
+		return t.pressure(value, unit.toCanonical(systolic), unit.toCanonical(diastolic))
+	}
+
+	m := numberValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", t.invalid(value, "must be a number, e.g. "+t.Example)
+	}
+	v, _ := strconv.ParseFloat(m[1], 64)
+	unit, err := t.unit(m[2])
+	if err != nil {
+		return "", t.invalid(value, err.Error())
+	}
+	return t.number(value, unit.toCanonical(v))
+}
+
+// Convert returns v, given in unit, in the canonical unit of dataType. The
+// empty unit is the canonical one. Blood pressure is two values, so it
+// can't be converted this way; use Pressure.
+func Convert(dataType string, v float64, unit string) (string, error) {
+	t, ok := types[dataType]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownType, dataType)
+	}
+	value := strconv.FormatFloat(v, 'f', -1, 64)
+	if t.Kind == KindPressure {
+		return "", t.invalid(value, "must be systolic/diastolic")
+	}
+	u, err := t.unit(unit)
+	if err != nil {
+		return "", t.invalid(value, err.Error())
+	}
+	return t.number(value, u.toCanonical(v))
+}
+
+// Pressure formats a blood pressure reading in mmHg as "systolic/diastolic".
+func Pressure(systolic, diastolic float64) (string, error) {
+	t := types[BloodPressure]
+	return t.pressure(fmt.Sprintf("%v/%v", systolic, diastolic), systolic, diastolic)
+}
+
+func (t Type) unit(symbol string) (Unit, error) {
+	symbol = strings.TrimSpace(symbol)
+	if symbol == "" {
+		return t.Units[0], nil
+	}
+	for _, u := range t.Units {
+		if strings.EqualFold(u.Symbol, symbol) {
+			return u, nil
+		}
+		for _, alias := range u.Aliases {
+			if strings.EqualFold(alias, symbol) {
+				return u, nil
+			}
+		}
+	}
+	symbols := make([]string, len(t.Units))
+	for i, u := range t.Units {
+		symbols[i] = u.Symbol
+	}
+	return Unit{}, fmt.Errorf("unit %q is not one of: %s", symbol, strings.Join(symbols, ", "))
+}
+
+func (t Type) number(value string, v float64) (string, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", t.invalid(value, "must be a number")
+	}
+	v = math.Round(v*100) / 100
+	if v < t.Min || v > t.Max {
+		return "", t.invalid(value, fmt.Sprintf("must be between %v and %v %s", t.Min, t.Max, t.Unit))
+	}
+	if t.Kind == KindInteger && v != math.Trunc(v) {
+		return "", t.invalid(value, "must be a whole number")
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64), nil
+}
+
+func (t Type) pressure(value string, systolic, diastolic float64) (string, error) {
+	systolic = math.Round(systolic*100) / 100
+	diastolic = math.Round(diastolic*100) / 100
+	switch {
+	case systolic < t.Min || systolic > t.Max:
+		return "", t.invalid(value, fmt.Sprintf("systolic must be between %v and %v %s", t.Min, t.Max, t.Unit))
+	case diastolic <= 0 || diastolic >= systolic:
+		return "", t.invalid(value, "diastolic must be above 0 and below systolic")
+	}
+	return strconv.FormatFloat(systolic, 'f', -1, 64) + "/" + strconv.FormatFloat(diastolic, 'f', -1, 64), nil
+}
+
+func (t Type) invalid(value, reason string) *ValueError {
+	return &ValueError{DataType: t.Name, Value: value, Reason: reason}
+}
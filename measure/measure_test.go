@@ -0,0 +1,160 @@
+package measure
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		dataType, value, want string
+	}{
+		{HeartRate, "72", "72"},
+		{HeartRate, " 72 BPM ", "72"},
+		{HeartRate, "1.2 beats/s", "72"},
+		{Steps, "8000 steps", "8000"},
+		{SleepMinutes, "7.5 h", "450"},
+		{Weight, "154 lb", "69.85"},
+		{Weight, "11 st", "69.85"},
+		{Weight, "70000 g", "70"},
+		{BloodPressure, "120/80", "120/80"},
+		{BloodPressure, "120 / 80 mmHg", "120/80"},
+		{BloodPressure, "16/10.7 kPa", "120.01/80.26"},
+		{Glucose, "99 mg/dL", "5.5"},
+		{OxygenSaturation, "97%", "97"},
+		{BodyTemperature, "98.6 °F", "37"},
+		{BodyTemperature, "36.6 degC", "36.6"},
+		{Distance, "5.2 km", "5200"},
+		{Distance, ".5 mi", "804.67"},
+		{Calories, "4184 kJ", "1000"},
+		{WaterIntake, "1.5 L", "1500"},
+		{WaterIntake, "1 fl oz", "29.57"},
+		{ExerciseMinutes, "45", "45"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.dataType, tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%s, %q) = %q, %v, want %q", tt.dataType, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		dataType, value string
+	}{
+		{HeartRate, ""},
+		{HeartRate, "fast"},
+		{HeartRate, "72 mph"},
+		{HeartRate, "19"},
+		{HeartRate, "301"},
+		// out of range only once converted
+		{HeartRate, "6 bps"},
+		{Steps, "8000.5"},
+		{Steps, "-1"},
+		{Weight, "0.4"},
+		{BodyTemperature, "37 °F"},
+		{OxygenSaturation, "101 %"},
+		{BloodPressure, "120"},
+		{BloodPressure, "120/80 psi"},
+		{BloodPressure, "80/120"},
+		{BloodPressure, "120/0"},
+		{BloodPressure, "301/80"},
+		{BloodPressure, "39/20"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.dataType, tt.value)
+		var valueErr *ValueError
+		if !errors.As(err, &valueErr) {
+			t.Errorf("Normalize(%s, %q) = %q, %v, want a *ValueError", tt.dataType, tt.value, got, err)
+			continue
+		}
+		if valueErr.DataType != tt.dataType || valueErr.Reason == "" {
+			t.Errorf("Normalize(%s, %q) error %+v, want the data type and a reason", tt.dataType, tt.value, valueErr)
+		}
+	}
+
+	if _, err := Normalize("mood", "good"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("unknown data type: %v, want ErrUnknownType", err)
+	}
+}
+
+func TestRangeBoundsAreInclusive(t *testing.T) {
+	for _, typ := range Types() {
+		if typ.Kind == KindPressure {
+			continue
+		}
+		for _, v := range []float64{typ.Min, typ.Max} {
+			if _, err := Convert(typ.Name, v, ""); err != nil {
+				t.Errorf("%s at its bound %v: %v", typ.Name, v, err)
+			}
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		dataType string
+		v        float64
+		unit     string
+		want     string
+	}{
+		{Weight, 154, "lb", "69.85"},
+		{Distance, 5.2, "", "5.2"},
+		{Distance, 5.2, "KM", "5200"},
+		{BodyTemperature, 98.6, "F", "37"},
+		{Glucose, 5.49, "mmol/L", "5.49"},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.dataType, tt.v, tt.unit)
+		if err != nil || got != tt.want {
+			t.Errorf("Convert(%s, %v, %q) = %q, %v, want %q", tt.dataType, tt.v, tt.unit, got, err, tt.want)
+		}
+	}
+
+	var valueErr *ValueError
+	if _, err := Convert(BloodPressure, 120, ""); !errors.As(err, &valueErr) {
+		t.Errorf("blood pressure: %v, want a *ValueError", err)
+	}
+	if _, err := Convert(Weight, 70, "oz"); !errors.As(err, &valueErr) {
+		t.Errorf("unknown unit: %v, want a *ValueError", err)
+	}
+	if _, err := Convert("mood", 1, ""); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("unknown data type: %v, want ErrUnknownType", err)
+	}
+}
+
+func TestPressure(t *testing.T) {
+	if got, err := Pressure(120.004, 79.996); err != nil || got != "120/80" {
+		t.Errorf("Pressure = %q, %v, want 120/80", got, err)
+	}
+	if _, err := Pressure(80, 120); err == nil {
+		t.Error("diastolic above systolic accepted")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names := Names()
+	types := Types()
+	if len(names) != len(types) || len(names) != 12 {
+		t.Fatalf("%d names and %d types, want 12 of each", len(names), len(types))
+	}
+	for i, typ := range types {
+		if typ.Name != names[i] {
+			t.Errorf("type %d is %q, want %q in name order", i, typ.Name, names[i])
+		}
+		if typ.Units[0].Symbol != typ.Unit {
+			t.Errorf("%s lists %q first, want its canonical unit %q", typ.Name, typ.Units[0].Symbol, typ.Unit)
+		}
+		// every type's own example must pass
+		if _, err := Normalize(typ.Name, typ.Example); err != nil {
+			t.Errorf("%s example %q: %v", typ.Name, typ.Example, err)
+		}
+	}
+	if typ, ok := Lookup(Weight); !ok || typ.Name != Weight || typ.Unit != "kg" {
+		t.Errorf("Lookup(weight) = %+v, %v", typ, ok)
+	}
+	if _, ok := Lookup("mood"); ok {
+		t.Error("Lookup(mood) found a type")
+	}
+}
@@ -1,11 +1,28 @@
 package models
 
+import "time"
+
 type Success struct {
 	Message string `json:"message"`
 }
 
-type Error struct {
-    Message string `json:"message"`
+// Liveness is the body of /healthz.
+type Liveness struct {
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of every error the gateway returns.
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+}
+
+// FieldError points at one invalid field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 type GetProfileRes struct {
@@ -67,4 +84,153 @@ type Warable struct {
 	DataType string `json:"data_type"`
 	DataValue string `json:"data_value"`
 	RecordedTimestamp string `json:"recorded_timestamp"`
-}
\ No newline at end of file
+}
+type RegisterReq struct {
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required,min=6"`
+	FirstName   string `json:"first_name" binding:"required"`
+	LastName    string `json:"last_name" binding:"required"`
+	DateOfBirth string `json:"date_of_birth" binding:"required,datetime=2006-01-02"`
+	Gender      string `json:"gender" binding:"required"`
+}
+
+type RegisterRes struct {
+	UserId string `json:"user_id"`
+}
+
+type LoginReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginRes struct {
+	UserId       string `json:"user_id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Role         string `json:"role"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokensRes struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type GrantCareTeamReq struct {
+	DoctorId string `json:"doctor_id" binding:"required"`
+}
+
+type CareTeam struct {
+	Doctors  []string `json:"doctors"`
+	Patients []string `json:"patients"`
+}
+
+type PolicyRule struct {
+	Subject string `json:"subject" binding:"required"`
+	Object  string `json:"object" binding:"required"`
+	Action  string `json:"action" binding:"required"`
+}
+
+type ReplacePoliciesReq struct {
+	Policies []PolicyRule `json:"policies" binding:"required,dive"`
+}
+
+type ReplacePoliciesRes struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+type RoleAssignment struct {
+	User string `json:"user" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+type ReplayRes struct {
+	Replayed int64 `json:"replayed"`
+}
+
+// JobAccepted answers requests that are processed in the background. Poll
+// Location for the outcome.
+type JobAccepted struct {
+	JobID    string `json:"job_id"`
+	Status   string `json:"status"`
+	Location string `json:"location"`
+}
+
+// NotificationEvent is the data of a "notification" server-sent event.
+type NotificationEvent struct {
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VitalReading is the data of a "reading" server-sent event on the vitals
+// stream.
+type VitalReading struct {
+	UserID     string    `json:"user_id"`
+	DataType   string    `json:"data_type"`
+	DeviceType string    `json:"device_type"`
+	DataValue  string    `json:"data_value"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// BulkWearableRes reports what happened to every reading of a bulk upload.
+type BulkWearableRes struct {
+	Received int              `json:"received"`
+	Accepted int              `json:"accepted"`
+	Rejected int              `json:"rejected"`
+	Items    []BulkItemResult `json:"items"`
+}
+
+type BulkItemResult struct {
+	// Index is the item's position in the upload, from 0.
+	Index int `json:"index"`
+	// Status is "accepted" or "rejected".
+	Status string `json:"status"`
+	// EventID is the id of the queued event. Bulk readings start no job,
+	// so there is no status to poll.
+	EventID string       `json:"event_id,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// ImportWearableRes summarises an import of another app's export.
+type ImportWearableRes struct {
+	Format   string `json:"format"`
+	Imported int    `json:"imported"`
+	// Skipped counts records of types the gateway doesn't track.
+	Skipped int `json:"skipped"`
+	Invalid int `json:"invalid"`
+	// Errors lists the first invalid records.
+	Errors []ImportError `json:"errors,omitempty"`
+}
+
+type ImportError struct {
+	// Record is the record's position in the export, from 0.
+	Record  int    `json:"record"`
+	Message string `json:"message"`
+}
+
+// AlertRuleReq is an alert rule as a doctor or admin sends it.
+type AlertRuleReq struct {
+	DataType string `json:"data_type" binding:"required" example:"heart_rate"`
+	// PatientID is left out for a rule that applies to every patient.
+	PatientID string  `json:"patient_id,omitempty"`
+	Condition string  `json:"condition" binding:"required" example:"above"`
+	Component string  `json:"component,omitempty"`
+	Threshold float64 `json:"threshold" example:"150"`
+	// WindowMinutes is how far back rise and fall look.
+	WindowMinutes int `json:"window_minutes,omitempty"`
+	// ForMinutes makes above and below wait until the value has stayed
+	// past the threshold that long.
+	ForMinutes      int    `json:"for_minutes,omitempty" example:"10"`
+	CooldownMinutes int    `json:"cooldown_minutes,omitempty" example:"15"`
+	Severity        string `json:"severity,omitempty" example:"critical"`
+}
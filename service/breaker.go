@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type BreakerConfig struct {
+	// Window is how far back the failure ratio looks.
+	Window time.Duration
+	// MinRequests is how many calls the window must hold before the breaker
+	// may open, so a few failures on a quiet backend don't trip it.
+	MinRequests int
+	// FailureRatio of failed calls in the window opens the breaker; 0 turns
+	// the breaker off.
+	FailureRatio float64
+	// OpenFor is how long an open breaker fails calls before it lets one
+	// through to probe the backend.
+	OpenFor time.Duration
+	// Now is the clock; time.Now if nil.
+	Now func() time.Time
+}
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breakerBuckets is how many slices the window is counted in.
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start  time.Time
+	total  int
+	failed int
+}
+
+// breaker fails calls to a backend fast once too many of them failed
+// recently, until a probe call succeeds again.
+type breaker struct {
+	backend string
+	cfg     BreakerConfig
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	state    int
+	openedAt time.Time
+	// probing is set while the one call let through a half-open breaker
+	// is under way.
+	probing bool
+	buckets [breakerBuckets]breakerBucket
+}
+
+func newBreaker(backend string, cfg BreakerConfig, logger *slog.Logger) *breaker {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &breaker{backend: backend, cfg: cfg, logger: logger}
+}
+
+// allow reports whether a call may go to the backend, and whether that
+// call is the probe of a half-open breaker. The caller hands probe back to
+// record, so only the probe's outcome closes or reopens the breaker.
+func (b *breaker) allow() (ok, probe bool) {
+	if b.cfg.FailureRatio <= 0 {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.cfg.Now().Sub(b.openedAt) < b.cfg.OpenFor {
+			return false, false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		b.logger.Info("Circuit breaker half-open, probing backend", "backend", b.backend)
+		return true, true
+	case breakerHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	}
+	return true, false
+}
+
+// backendFailures are the codes that say the backend, rather than the
+// request, is at fault.
+var backendFailures = map[codes.Code]bool{
+	codes.Unavailable:      true,
+	codes.DeadlineExceeded: true,
+	codes.Internal:         true,
+	codes.Unknown:          true,
+	codes.DataLoss:         true,
+}
+
+// record counts the outcome of an allowed call; probe is what allow
+// returned for it. Calls the caller gave up on say nothing about the
+// backend and are not counted.
+func (b *breaker) record(err error, probe bool) {
+	if b.cfg.FailureRatio <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+		if probe && b.state == breakerHalfOpen {
+			b.probing = false
+		}
+		return
+	}
+	failed := err != nil && backendFailures[status.Code(err)]
+
+	now := b.cfg.Now()
+	switch b.state {
+	case breakerOpen:
+		// a call let through before the breaker opened
+		return
+	case breakerHalfOpen:
+		if !probe {
+			// a call let through before the breaker opened; only the
+			// probe says whether the backend is back
+			return
+		}
+		b.probing = false
+		if failed {
+			b.open(now, "probe failed")
+			return
+		}
+		b.state = breakerClosed
+		b.buckets = [breakerBuckets]breakerBucket{}
+		b.logger.Info("Circuit breaker closed", "backend", b.backend)
+		return
+	}
+
+	width := b.cfg.Window / breakerBuckets
+	if width <= 0 {
+		width = time.Millisecond
+	}
+	start := now.Truncate(width)
+	bucket := &b.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	bucket.total++
+	if failed {
+		bucket.failed++
+	}
+
+	total, failures := 0, 0
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.cfg.Window {
+			total += bk.total
+			failures += bk.failed
+		}
+	}
+	if total >= b.cfg.MinRequests && float64(failures) >= b.cfg.FailureRatio*float64(total) {
+		b.open(now, "failure ratio reached")
+	}
+}
+
+func (b *breaker) open(now time.Time, reason string) {
+	b.state = breakerOpen
+	b.openedAt = now
+	b.logger.Warn("Circuit breaker opened", "backend", b.backend, "reason", reason, "open_for", b.cfg.OpenFor.String())
+}
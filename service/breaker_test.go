@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var start = time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+var (
+	down     = status.Error(codes.Unavailable, "backend down")
+	notFound = status.Error(codes.NotFound, "no such record")
+)
+
+// testBreaker opens once half of at least 4 calls in a minute failed and
+// stays open for 10 seconds; the clock only moves when *now is changed.
+func testBreaker() (*breaker, *time.Time) {
+	now := start
+	b := newBreaker("health", BreakerConfig{
+		Window:       time.Minute,
+		MinRequests:  4,
+		FailureRatio: 0.5,
+		OpenFor:      10 * time.Second,
+		Now:          func() time.Time { return now },
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return b, &now
+}
+
+// call runs one call through b that ends in err and reports whether it was
+// let through.
+func call(b *breaker, err error) bool {
+	ok, probe := b.allow()
+	if ok {
+		b.record(err, probe)
+	}
+	return ok
+}
+
+func TestBreakerOpens(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+		open bool
+	}{
+		{"too few calls", []error{down, down, down}, false},
+		{"under the ratio", []error{nil, nil, nil, down}, false},
+		{"at the ratio", []error{nil, down, nil, down}, true},
+		{"request errors", []error{notFound, notFound, notFound, notFound}, false},
+		{"canceled calls", []error{context.Canceled, status.Error(codes.Canceled, ""), nil, down}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := testBreaker()
+			for _, err := range tt.errs {
+				call(b, err)
+			}
+			if ok, _ := b.allow(); ok == tt.open {
+				t.Errorf("allowed a call = %v, want open %v", ok, tt.open)
+			}
+		})
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	b, now := testBreaker()
+	call(b, down)
+	call(b, down)
+	// the failures have left the window by the time the rest arrive
+	*now = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		call(b, nil)
+	}
+	call(b, down)
+	if ok, _ := b.allow(); !ok {
+		t.Error("breaker open on failures older than the window")
+	}
+}
+
+func TestBreakerOff(t *testing.T) {
+	b, _ := testBreaker()
+	b.cfg.FailureRatio = 0
+	for i := 0; i < 10; i++ {
+		if !call(b, down) {
+			t.Fatalf("call %d refused by a breaker that is off", i)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	for _, probeErr := range []error{nil, down} {
+		name := "probe succeeds"
+		if probeErr != nil {
+			name = "probe fails"
+		}
+		t.Run(name, func(t *testing.T) {
+			b, now := testBreaker()
+			// a call sent while the breaker was still closed
+			late, lateProbe := b.allow()
+			for i := 0; i < 4; i++ {
+				call(b, down)
+			}
+			if ok, _ := b.allow(); ok {
+				t.Fatal("breaker not open after four failures")
+			}
+
+			*now = now.Add(10 * time.Second)
+			ok, probe := b.allow()
+			if !ok || !probe {
+				t.Fatalf("allow after OpenFor = %v, %v, want the probe", ok, probe)
+			}
+			if ok, _ := b.allow(); ok {
+				t.Error("second call let through while the probe is under way")
+			}
+
+			// the late call ends opposite to the probe and must not decide
+			lateErr := down
+			if probeErr != nil {
+				lateErr = nil
+			}
+			if !late || lateProbe {
+				t.Fatalf("call before opening = %v, %v, want an ordinary call", late, lateProbe)
+			}
+			b.record(lateErr, lateProbe)
+			if b.state != breakerHalfOpen || !b.probing {
+				t.Fatalf("a call that is not the probe changed the state to %d", b.state)
+			}
+
+			b.record(probeErr, probe)
+			want := breakerClosed
+			if probeErr != nil {
+				want = breakerOpen
+			}
+			if b.state != want {
+				t.Errorf("state %d after the probe, want %d", b.state, want)
+			}
+			if ok, _ := b.allow(); ok != (probeErr == nil) {
+				t.Errorf("allowed a call after the probe = %v", ok)
+			}
+		})
+	}
+}
+
+func TestBreakerCanceledProbe(t *testing.T) {
+	b, now := testBreaker()
+	for i := 0; i < 4; i++ {
+		call(b, down)
+	}
+	*now = now.Add(10 * time.Second)
+	_, probe := b.allow()
+	b.record(context.Canceled, probe)
+
+	// the probe said nothing, so the next call probes instead
+	ok, probe := b.allow()
+	if !ok || !probe || b.state != breakerHalfOpen {
+		t.Errorf("allow after a canceled probe = %v, %v in state %d, want a new probe", ok, probe, b.state)
+	}
+}
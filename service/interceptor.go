@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryable are the codes worth another attempt: the backend was down, or
+// the attempt ran out of time.
+var retryable = map[codes.Code]bool{
+	codes.Unavailable:      true,
+	codes.DeadlineExceeded: true,
+}
+
+// CallInterceptor applies the call policies of the backend to every call
+// on its connection: a deadline per attempt, retries with jittered backoff
+// for idempotent methods, and a circuit breaker shared by all its methods
+// that fails calls with Unavailable while it is open.
+func CallInterceptor(backend string, policies Policies, logger *slog.Logger) grpc.UnaryClientInterceptor {
+	breaker := newBreaker(backend, policies.Breaker, logger)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		policy := policies.For(backend, method)
+		attempts := 1
+		if idempotent(method) && policy.Attempts > 1 {
+			attempts = policy.Attempts
+		}
+
+		var err error
+		for attempt := 1; ; attempt++ {
+			ok, probe := breaker.allow()
+			if !ok {
+				if err != nil {
+					// opened while retrying; the backend's own error says more
+					return err
+				}
+				return status.Errorf(codes.Unavailable, "%s backend unavailable: circuit breaker open", backend)
+			}
+
+			err = invokeWithTimeout(ctx, policy.Timeout, method, req, reply, cc, invoker, opts...)
+			breaker.record(err, probe)
+			if err == nil || attempt >= attempts || !retryable[status.Code(err)] || ctx.Err() != nil {
+				return err
+			}
+
+			wait := backoff(policy, attempt)
+			logger.Warn("Retrying backend call", "backend", backend, "method", method, "attempt", attempt, "code", status.Code(err).String(), "wait", wait.String())
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+func invokeWithTimeout(ctx context.Context, timeout time.Duration, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// backoff is a random wait up to BaseBackoff doubled for every attempt
+// made, capped at MaxBackoff.
+func backoff(policy CallPolicy, attempt int) time.Duration {
+	ceiling := policy.BaseBackoff << (attempt - 1)
+	if ceiling <= 0 || (policy.MaxBackoff > 0 && ceiling > policy.MaxBackoff) {
+		ceiling = policy.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := CallPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		// far enough to overflow the shift
+		{70, time.Second},
+	}
+	for _, tt := range tests {
+		var longest time.Duration
+		for i := 0; i < 200; i++ {
+			wait := backoff(policy, tt.attempt)
+			if wait < 0 || wait >= tt.ceiling {
+				t.Fatalf("attempt %d waits %s, want under %s", tt.attempt, wait, tt.ceiling)
+			}
+			longest = max(longest, wait)
+		}
+		// jittered over the whole range, not a fixed fraction of it
+		if longest < tt.ceiling/2 {
+			t.Errorf("attempt %d waits at most %s in 200 tries, want up to %s", tt.attempt, longest, tt.ceiling)
+		}
+	}
+
+	if wait := backoff(CallPolicy{}, 3); wait != 0 {
+		t.Errorf("no backoff configured waits %s, want 0", wait)
+	}
+}
+
+func TestCallInterceptorRetries(t *testing.T) {
+	policies := Policies{Default: CallPolicy{Attempts: 3, BaseBackoff: time.Millisecond}}
+	tests := []struct {
+		method string
+		err    error
+		calls  int
+	}{
+		{"/health.HealthCheck/GetDailyHealthSummary", status.Error(codes.Unavailable, ""), 3},
+		{"/health.HealthCheck/GetDailyHealthSummary", status.Error(codes.DeadlineExceeded, ""), 3},
+		{"/health.HealthCheck/GetDailyHealthSummary", status.Error(codes.NotFound, ""), 1},
+		{"/health.HealthCheck/GetDailyHealthSummary", nil, 1},
+		{"/health.HealthCheck/AddMedicalReport", status.Error(codes.Unavailable, ""), 1},
+		{"/user.Users/GetAndMarkNotificationAsRead", status.Error(codes.Unavailable, ""), 1},
+	}
+	for _, tt := range tests {
+		intercept := CallInterceptor("health", policies, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		calls := 0
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return tt.err
+		}
+		if err := intercept(context.Background(), tt.method, nil, nil, nil, invoker); status.Code(err) != status.Code(tt.err) {
+			t.Errorf("%s: error %v, want %v", tt.method, err, tt.err)
+		}
+		if calls != tt.calls {
+			t.Errorf("%s failing with %v: %d calls, want %d", tt.method, status.Code(tt.err), calls, tt.calls)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CallPolicy is how the gateway calls a backend method.
+type CallPolicy struct {
+	// Timeout bounds each attempt; the caller's own deadline still applies.
+	Timeout time.Duration
+	// Attempts is how many times an idempotent call is tried in all; other
+	// calls are tried once.
+	Attempts int
+	// BaseBackoff doubles after every failed attempt up to MaxBackoff; the
+	// wait is a random duration up to that.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Policies holds the call policy of every backend method.
+type Policies struct {
+	Default CallPolicy
+	// Overrides are keyed by backend, e.g. "health", or by full method, e.g.
+	// "/health.HealthCheck/GetDailyHealthSummary". Their zero fields keep
+	// the value they override; a method's override goes over its backend's.
+	Overrides map[string]CallPolicy
+	Breaker   BreakerConfig
+}
+
+// For returns the policy of a method of the backend.
+func (p Policies) For(backend, method string) CallPolicy {
+	policy := p.Default
+	for _, key := range []string{backend, method} {
+		o, ok := p.Overrides[key]
+		if !ok {
+			continue
+		}
+		if o.Timeout > 0 {
+			policy.Timeout = o.Timeout
+		}
+		if o.Attempts > 0 {
+			policy.Attempts = o.Attempts
+		}
+		if o.BaseBackoff > 0 {
+			policy.BaseBackoff = o.BaseBackoff
+		}
+		if o.MaxBackoff > 0 {
+			policy.MaxBackoff = o.MaxBackoff
+		}
+	}
+	return policy
+}
+
+// ParseOverrides reads "target:setting=value" entries, where target is a
+// backend or a full method and setting is timeout, attempts, backoff or
+// max_backoff, e.g. "health:timeout=10s" or
+// "/user.Users/GetUserProfile:attempts=5".
+func ParseOverrides(entries []string) (map[string]CallPolicy, error) {
+	overrides := make(map[string]CallPolicy)
+	for _, entry := range entries {
+		target, setting, ok := strings.Cut(entry, ":")
+		name, value, hasValue := strings.Cut(setting, "=")
+		if !ok || !hasValue || target == "" {
+			return nil, fmt.Errorf("service: %q is not target:setting=value", entry)
+		}
+		o := overrides[target]
+		var err error
+		switch name {
+		case "timeout":
+			o.Timeout, err = positiveDuration(value)
+		case "backoff":
+			o.BaseBackoff, err = positiveDuration(value)
+		case "max_backoff":
+			o.MaxBackoff, err = positiveDuration(value)
+		case "attempts":
+			o.Attempts, err = strconv.Atoi(value)
+			if err == nil && o.Attempts < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		default:
+			return nil, fmt.Errorf("service: %q sets unknown setting %q", entry, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("service: %q: %v", entry, err)
+		}
+		overrides[target] = o
+	}
+	return overrides, nil
+}
+
+func positiveDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	return d, err
+}
+
+// notIdempotent are Get methods that change state, so a retry after a lost
+// reply would repeat the change.
+var notIdempotent = map[string]bool{
+	"/user.Users/GetAndMarkNotificationAsRead": true,
+}
+
+// idempotent reports whether a method may be retried: the Get methods,
+// which only read.
+func idempotent(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	return strings.HasPrefix(name, "Get") && !notIdempotent[method]
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOverrides(t *testing.T) {
+	got, err := ParseOverrides([]string{
+		"health:timeout=10s",
+		"health:backoff=50ms",
+		"/user.Users/GetUserProfile:attempts=5",
+		"/user.Users/GetUserProfile:max_backoff=2s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]CallPolicy{
+		"health":                     {Timeout: 10 * time.Second, BaseBackoff: 50 * time.Millisecond},
+		"/user.Users/GetUserProfile": {Attempts: 5, MaxBackoff: 2 * time.Second},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for target, policy := range want {
+		if got[target] != policy {
+			t.Errorf("%s: %+v, want %+v", target, got[target], policy)
+		}
+	}
+
+	for _, entry := range []string{
+		"health",
+		"health:timeout",
+		":timeout=1s",
+		"health:retries=3",
+		"health:timeout=soon",
+		"health:timeout=0s",
+		"health:backoff=-1s",
+		"health:attempts=0",
+		"health:attempts=two",
+	} {
+		if _, err := ParseOverrides([]string{entry}); err == nil {
+			t.Errorf("%q parsed, want an error", entry)
+		}
+	}
+}
+
+func TestPoliciesFor(t *testing.T) {
+	p := Policies{
+		Default: CallPolicy{Timeout: 5 * time.Second, Attempts: 3, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+		Overrides: map[string]CallPolicy{
+			"health": {Timeout: 10 * time.Second, Attempts: 2},
+			"/health.HealthCheck/GetDailyHealthSummary": {Timeout: 30 * time.Second},
+		},
+	}
+	tests := []struct {
+		backend, method string
+		want            CallPolicy
+	}{
+		{"user", "/user.Users/GetUserProfile", p.Default},
+		{"health", "/health.HealthCheck/AddMedicalReport", CallPolicy{Timeout: 10 * time.Second, Attempts: 2, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}},
+		{"health", "/health.HealthCheck/GetDailyHealthSummary", CallPolicy{Timeout: 30 * time.Second, Attempts: 2, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}},
+	}
+	for _, tt := range tests {
+		if got := p.For(tt.backend, tt.method); got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"/user.Users/GetUserProfile", true},
+		{"/health.HealthCheck/GetDailyHealthSummary", true},
+		{"/user.Users/GetAndMarkNotificationAsRead", false},
+		{"/health.MedicalRecord/AddMedicalReport", false},
+		{"/user.Users/UpdateUserProfile", false},
+		// Get in the service name, not the method
+		{"/GetService.Users/DeleteUser", false},
+	}
+	for _, tt := range tests {
+		if got := idempotent(tt.method); got != tt.want {
+			t.Errorf("idempotent(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/balancer/pickfirst"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/resolver"
+)
+
+// Resolvers say how the addresses of a backend are found.
+const (
+	// ResolverDNS resolves every host:port to all the addresses of the host,
+	// so a backend scaled behind one name is spread over.
+	ResolverDNS = "dns"
+	// ResolverStatic uses the host:port addresses as they are, e.g. several
+	// local processes in development.
+	ResolverStatic = "static"
+	// ResolverFile reads host:port addresses, one per line, from the files
+	// named; lines starting with # are skipped.
+	ResolverFile = "file"
+)
+
+// balancers maps the load balancing policies of the config to those of
+// gRPC.
+var balancers = map[string]string{
+	"round_robin":   roundrobin.Name,
+	"least_request": leastrequest.Name,
+	"pick_first":    pickfirst.Name,
+}
+
+// resolverScheme names the backends' own resolver in dial targets.
+const resolverScheme = "gateway"
+
+// minResolveGap keeps connection failures, which ask for a new lookup, from
+// turning into a lookup storm.
+const minResolveGap = time.Second
+
+// Endpoints say where the replicas of a backend are.
+type Endpoints struct {
+	Resolver string
+	Addrs    []string
+	// Refresh is how often the addresses are looked up again, so replicas
+	// that come and go are picked up without a restart; 0 looks up once.
+	Refresh time.Duration
+}
+
+func (e Endpoints) validate() error {
+	switch e.Resolver {
+	case ResolverDNS, ResolverStatic, ResolverFile:
+	default:
+		return fmt.Errorf("unknown resolver %q, want dns, static or file", e.Resolver)
+	}
+	if len(e.Addrs) == 0 {
+		return fmt.Errorf("no addresses")
+	}
+	return nil
+}
+
+// lookup returns the current addresses, sorted.
+func (e Endpoints) lookup(ctx context.Context) ([]string, error) {
+	var addrs []string
+	switch e.Resolver {
+	case ResolverStatic:
+		addrs = slices.Clone(e.Addrs)
+	case ResolverDNS:
+		for _, addr := range e.Addrs {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				addrs = append(addrs, net.JoinHostPort(ip, port))
+			}
+		}
+	case ResolverFile:
+		for _, path := range e.Addrs {
+			listed, err := readAddrs(path)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, listed...)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("service: no addresses found for %s", strings.Join(e.Addrs, ", "))
+	}
+	slices.Sort(addrs)
+	return slices.Compact(addrs), nil
+}
+
+func readAddrs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var addrs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			addrs = append(addrs, line)
+		}
+	}
+	return addrs, scanner.Err()
+}
+
+// resolverBuilder gives a connection the endpoints of one backend.
+type resolverBuilder struct {
+	backend   string
+	endpoints Endpoints
+	logger    *slog.Logger
+}
+
+func (b *resolverBuilder) Scheme() string { return resolverScheme }
+
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &endpointWatcher{
+		backend:   b.backend,
+		endpoints: b.endpoints,
+		logger:    b.logger,
+		cc:        cc,
+		cancel:    cancel,
+		now:       make(chan struct{}, 1),
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+// endpointWatcher looks the endpoints up every Refresh, and when gRPC asks
+// after a connection failed, and hands changes to the connection.
+type endpointWatcher struct {
+	backend   string
+	endpoints Endpoints
+	logger    *slog.Logger
+	cc        resolver.ClientConn
+	cancel    context.CancelFunc
+	now       chan struct{}
+}
+
+func (w *endpointWatcher) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case w.now <- struct{}{}:
+	default:
+	}
+}
+
+func (w *endpointWatcher) Close() { w.cancel() }
+
+func (w *endpointWatcher) run(ctx context.Context) {
+	var tick <-chan time.Time
+	if w.endpoints.Refresh > 0 {
+		ticker := time.NewTicker(w.endpoints.Refresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var current []string
+	for {
+		addrs, err := w.endpoints.lookup(ctx)
+		switch {
+		case err != nil && current == nil:
+			w.cc.ReportError(err)
+		case err != nil:
+			// keep the addresses we have rather than none
+			w.logger.Warn("Backend lookup failed, keeping current endpoints", "backend", w.backend, "error", err.Error())
+		case !slices.Equal(addrs, current):
+			state := resolver.State{}
+			for _, addr := range addrs {
+				state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+			}
+			if err := w.cc.UpdateState(state); err != nil {
+				w.logger.Warn("Backend endpoints rejected", "backend", w.backend, "error", err.Error())
+			}
+			w.logger.Info("Backend endpoints changed", "backend", w.backend, "addresses", addrs)
+			current = addrs
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-w.now:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(minResolveGap):
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+func TestEndpointsValidate(t *testing.T) {
+	tests := []struct {
+		endpoints Endpoints
+		ok        bool
+	}{
+		{Endpoints{Resolver: ResolverDNS, Addrs: []string{"health:50051"}}, true},
+		{Endpoints{Resolver: ResolverStatic, Addrs: []string{"localhost:50051", "localhost:50052"}}, true},
+		{Endpoints{Resolver: ResolverFile, Addrs: []string{"/etc/gateway/health"}}, true},
+		{Endpoints{Resolver: "consul", Addrs: []string{"health:50051"}}, false},
+		{Endpoints{Resolver: ResolverStatic}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.endpoints.validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: validate() = %v, want ok %v", tt.endpoints, err, tt.ok)
+		}
+	}
+}
+
+// writeAddrs replaces the address file at path with content.
+func writeAddrs(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	one, two := filepath.Join(dir, "one"), filepath.Join(dir, "two")
+	writeAddrs(t, one, "# health replicas\n10.0.0.2:50051\n\n  10.0.0.1:50051  \n")
+	writeAddrs(t, two, "10.0.0.2:50051\n10.0.0.3:50051\n")
+	empty := filepath.Join(dir, "empty")
+	writeAddrs(t, empty, "# none yet\n")
+
+	tests := []struct {
+		name      string
+		endpoints Endpoints
+		want      []string
+	}{
+		{"static", Endpoints{Resolver: ResolverStatic, Addrs: []string{"b:2", "a:1", "b:2"}}, []string{"a:1", "b:2"}},
+		// an IP resolves to itself, so this needs no name server
+		{"dns", Endpoints{Resolver: ResolverDNS, Addrs: []string{"127.0.0.1:50051", "[::1]:50051"}}, []string{"127.0.0.1:50051", "[::1]:50051"}},
+		{"files", Endpoints{Resolver: ResolverFile, Addrs: []string{one, two}}, []string{"10.0.0.1:50051", "10.0.0.2:50051", "10.0.0.3:50051"}},
+	}
+	for _, tt := range tests {
+		got, err := tt.endpoints.lookup(context.Background())
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: lookup() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	for name, endpoints := range map[string]Endpoints{
+		"missing file": {Resolver: ResolverFile, Addrs: []string{filepath.Join(dir, "missing")}},
+		"empty file":   {Resolver: ResolverFile, Addrs: []string{empty}},
+		"dns, no port": {Resolver: ResolverDNS, Addrs: []string{"127.0.0.1"}},
+	} {
+		if got, err := endpoints.lookup(context.Background()); err == nil {
+			t.Errorf("%s: lookup() = %v, want an error", name, got)
+		}
+	}
+}
+
+// fakeConn records what the resolver hands the connection.
+type fakeConn struct {
+	resolver.ClientConn
+	states chan []string
+	errs   chan error
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{states: make(chan []string, 10), errs: make(chan error, 10)}
+}
+
+func (c *fakeConn) UpdateState(state resolver.State) error {
+	var addrs []string
+	for _, a := range state.Addresses {
+		addrs = append(addrs, a.Addr)
+	}
+	c.states <- addrs
+	return nil
+}
+
+func (c *fakeConn) ReportError(err error) { c.errs <- err }
+
+func build(t *testing.T, endpoints Endpoints) (resolver.Resolver, *fakeConn) {
+	t.Helper()
+	b := &resolverBuilder{backend: "health", endpoints: endpoints, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if b.Scheme() != resolverScheme {
+		t.Fatalf("scheme %q, want %q", b.Scheme(), resolverScheme)
+	}
+	cc := newFakeConn()
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r, cc
+}
+
+func expectState(t *testing.T, cc *fakeConn, want []string) {
+	t.Helper()
+	select {
+	case got := <-cc.states:
+		if !slices.Equal(got, want) {
+			t.Errorf("addresses %v, want %v", got, want)
+		}
+	case err := <-cc.errs:
+		t.Fatalf("error %v, want addresses %v", err, want)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no addresses, want %v", want)
+	}
+}
+
+func TestResolverRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health")
+	writeAddrs(t, path, "10.0.0.1:50051\n")
+	_, cc := build(t, Endpoints{Resolver: ResolverFile, Addrs: []string{path}, Refresh: 10 * time.Millisecond})
+	expectState(t, cc, []string{"10.0.0.1:50051"})
+
+	writeAddrs(t, path, "10.0.0.2:50051\n10.0.0.1:50051\n")
+	expectState(t, cc, []string{"10.0.0.1:50051", "10.0.0.2:50051"})
+
+	// a failed lookup keeps the addresses the connection has
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case got := <-cc.states:
+		t.Errorf("addresses %v after a failed lookup, want the current ones kept", got)
+	case err := <-cc.errs:
+		t.Errorf("error %v after a failed lookup, want the current addresses kept", err)
+	default:
+	}
+
+	// unchanged addresses are not handed over again
+	writeAddrs(t, path, "10.0.0.1:50051\n10.0.0.2:50051\n")
+	time.Sleep(50 * time.Millisecond)
+	if len(cc.states) != 0 {
+		t.Errorf("addresses handed over again unchanged: %v", <-cc.states)
+	}
+}
+
+func TestResolverFirstLookupFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health")
+	r, cc := build(t, Endpoints{Resolver: ResolverFile, Addrs: []string{path}})
+
+	select {
+	case err := <-cc.errs:
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("error %v, want the missing file", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported without addresses")
+	}
+
+	// a failed connection asks for another lookup, which finds the file
+	writeAddrs(t, path, "10.0.0.1:50051\n")
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	expectState(t, cc, []string{"10.0.0.1:50051"})
+}
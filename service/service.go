@@ -4,6 +4,9 @@ import (
 	"api-gateway/config"
 	"api-gateway/genproto/health"
 	"api-gateway/genproto/user"
+	"errors"
+	"fmt"
+	"log/slog"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -15,6 +18,10 @@ type ServiceManager interface {
 	LifeStyleService() health.LifeStyleClient
 	MedicalRecordService() health.MedicalRecordClient
 	WearableService() health.WearableClient
+	// Backends returns the connection of every backend by name.
+	Backends() map[string]*grpc.ClientConn
+	// Close closes the backend connections; calls in flight fail.
+	Close() error
 }
 
 type serviceManagerImpl struct {
@@ -23,6 +30,7 @@ type serviceManagerImpl struct {
 	lifeStyleClient health.LifeStyleClient
 	medicalRecordClient health.MedicalRecordClient
 	werableClient health.WearableClient
+	backends map[string]*grpc.ClientConn
 }
 
 func (s *serviceManagerImpl) UserService() user.UsersClient {
@@ -45,21 +53,69 @@ func (s *serviceManagerImpl) WearableService() health.WearableClient {
     return s.werableClient
 }
 
+func (s *serviceManagerImpl) Backends() map[string]*grpc.ClientConn {
+	return s.backends
+}
+
+func (s *serviceManagerImpl) Close() error {
+	var errs []error
+	for _, conn := range s.backends {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+
+// NewServiceManager dials the backends at the endpoints of the config,
+// spread over their replicas by the configured balancer. Every call goes
+// through CallInterceptor with the call policies of the config.
+func NewServiceManager(cfg config.Config, logger *slog.Logger) (ServiceManager, error) {
+	overrides, err := ParseOverrides(cfg.GRPC_CALL_POLICIES)
+	if err != nil {
+		return nil, err
+	}
+	policies := Policies{
+		Default: CallPolicy{
+			Timeout:     cfg.GRPC_TIMEOUT,
+			Attempts:    cfg.GRPC_RETRY_ATTEMPTS,
+			BaseBackoff: cfg.GRPC_RETRY_BACKOFF,
+			MaxBackoff:  cfg.GRPC_RETRY_MAX_BACKOFF,
+		},
+		Overrides: overrides,
+		Breaker: BreakerConfig{
+			Window:       cfg.GRPC_BREAKER_WINDOW,
+			MinRequests:  cfg.GRPC_BREAKER_MIN_REQUESTS,
+			FailureRatio: cfg.GRPC_BREAKER_FAILURE_RATIO,
+			OpenFor:      cfg.GRPC_BREAKER_OPEN_FOR,
+		},
+	}
 
-func NewServiceManager() (ServiceManager, error) {
-	connUser, err := grpc.Dial(
-		"l-auth-service"+config.Load().GRPC_USER_PORT,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	balancer, ok := balancers[cfg.GRPC_LB_POLICY]
+	if !ok {
+		return nil, fmt.Errorf("service: unknown load balancing policy %q, want round_robin, least_request or pick_first", cfg.GRPC_LB_POLICY)
+	}
+	dial := func(backend string, addrs []string) (*grpc.ClientConn, error) {
+		endpoints := Endpoints{Resolver: cfg.GRPC_RESOLVER, Addrs: addrs, Refresh: cfg.GRPC_RESOLVE_INTERVAL}
+		if err := endpoints.validate(); err != nil {
+			return nil, fmt.Errorf("service: %s backend: %w", backend, err)
+		}
+		return grpc.Dial(
+			resolverScheme+":///"+backend,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithResolvers(&resolverBuilder{backend: backend, endpoints: endpoints, logger: logger}),
+			grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"`+balancer+`": {}}]}`),
+			grpc.WithChainUnaryInterceptor(CallInterceptor(backend, policies, logger)),
+		)
+	}
+
+	connUser, err := dial("user", cfg.GRPC_USER_ADDRS)
 	if err != nil {
 		return nil, err
 	}
 
-	connHealth, err := grpc.Dial(
-		"health"+config.Load().GRPC_PRODUCT_PORT,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	connHealth, err := dial("health", cfg.GRPC_HEALTH_ADDRS)
 	if err != nil {
+		connUser.Close()
 		return nil, err
 	}
 
@@ -69,6 +125,6 @@ func NewServiceManager() (ServiceManager, error) {
 		lifeStyleClient:    health.NewLifeStyleClient(connHealth),
 		medicalRecordClient: health.NewMedicalRecordClient(connHealth),
 		werableClient:      health.NewWearableClient(connHealth),
+		backends:           map[string]*grpc.ClientConn{"user": connUser, "health": connHealth},
 	}, nil
 }
-
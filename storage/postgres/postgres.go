@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"api-gateway/config"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+func ConnectionString(cfg config.Config) string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable",
+		cfg.DB_HOST, cfg.DB_PORT, cfg.DB_USER, cfg.DB_NAME, cfg.DB_PASSWORD)
+}
+
+func ConnectDB(cfg config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", ConnectionString(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}